@@ -1,12 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/zhangzhao-gg/go-rnnoise/rnnoise"
@@ -23,7 +27,7 @@ func main() {
 	switch command {
 	case "denoise":
 		if len(os.Args) < 4 {
-			fmt.Println("用法: go run . denoise <输入文件> <输出文件> [语音概率阈值]")
+			fmt.Println("用法: go run . denoise <输入文件> <输出文件> [语音概率阈值] [输出策略] [-format 原始PCM格式] [-mix 1.0] [-max-attenuation dB]")
 			return
 		}
 		runDenoise(os.Args[2], os.Args[3], os.Args[4:])
@@ -33,6 +37,18 @@ func main() {
 			return
 		}
 		runAnalyze(os.Args[2], os.Args[3:])
+	case "segments":
+		if len(os.Args) < 3 {
+			fmt.Println("用法: go run . segments <输入文件> [-format json|csv] [-export 目录]")
+			return
+		}
+		runSegments(os.Args[2], os.Args[3:])
+	case "pipeline":
+		if len(os.Args) < 5 {
+			fmt.Println("用法: go run . pipeline <描述文件.json|.yaml> <输入文件> <输出文件>")
+			return
+		}
+		runPipeline(os.Args[2], os.Args[3], os.Args[4])
 	case "stream":
 		runStreamExample()
 	case "batch":
@@ -43,10 +59,10 @@ func main() {
 		runBatch(os.Args[2], os.Args[3:])
 	case "test":
 		if len(os.Args) < 5 {
-			fmt.Println("用法: go run . test <音频文件> <批处理大小> <测试次数>")
+			fmt.Println("用法: go run . test <音频文件> <批处理大小> <测试次数> [原始PCM格式]")
 			return
 		}
-		runPerformanceTest(os.Args[2], os.Args[3], os.Args[4])
+		runPerformanceTest(os.Args[2], os.Args[3], os.Args[4], os.Args[5:])
 	default:
 		showUsage()
 	}
@@ -56,45 +72,99 @@ func showUsage() {
 	fmt.Println("Go RNNoise 音频降噪工具")
 	fmt.Println()
 	fmt.Println("用法:")
-	fmt.Println("  go run . denoise <输入文件> <输出文件> [语音概率阈值]")
+	fmt.Println("  go run . denoise <输入文件> <输出文件> [语音概率阈值] [输出策略]")
 	fmt.Println("    - 对单个音频文件进行降噪处理")
 	fmt.Println("    - 语音概率阈值: 0.0-1.0，默认0.0（保留所有帧）")
+	fmt.Println("    - 输出策略: drop（丢弃，默认）、silence（静音）、attenuate（衰减-20dB）、comfort（舒适噪声）")
+	fmt.Println("    - 输入为无文件头的原始PCM时: -format s16le|s16be|s24le|s32le|f32le|f64le|mulaw|alaw [-rate 8000] [-channels 1]")
+	fmt.Println("    - -mix 0.8: 保留20%原始信号；-max-attenuation 12: 相对输入最多衰减12dB")
 	fmt.Println()
 	fmt.Println("  go run . analyze <输入文件> [语音概率阈值]")
 	fmt.Println("    - 分析音频文件的语音/噪声统计信息")
 	fmt.Println()
+	fmt.Println("  go run . segments <输入文件> [-format json|csv] [-export 目录] [-on 0.5] [-off 0.35]")
+	fmt.Println("           [-hangover 150ms] [-min-speech 100ms] [-min-silence 200ms]")
+	fmt.Println("    - 提取语音段并输出为JSON或CSV，-export 将每个语音段导出为单独的WAV文件")
+	fmt.Println()
+	fmt.Println("  go run . pipeline <描述文件.json|.yaml> <输入文件> <输出文件>")
+	fmt.Println("    - 按JSON或YAML描述组装处理流水线（resample、channel_mix、denoise、gate、gain、loudness、limiter、tap）")
+	fmt.Println("    - 名为voice的tap阶段用于统计语音概率")
+	fmt.Println()
 	fmt.Println("  go run . stream")
 	fmt.Println("    - 演示流式音频处理")
 	fmt.Println()
 	fmt.Println("  go run . batch <输入目录> [输出目录]")
 	fmt.Println("    - 批量处理目录中的所有WAV文件")
 	fmt.Println()
-	fmt.Println("  go run . test <音频文件> <批处理大小> <测试次数>")
+	fmt.Println("  go run . test <音频文件> <批处理大小> <测试次数> [原始PCM格式]")
 	fmt.Println("    - 性能测试（模拟Python版本的测试逻辑）")
+	fmt.Println("    - WAV文件按文件头解码，其他文件按8000Hz单声道原始PCM处理（默认s16le）")
 	fmt.Println()
 	fmt.Println("示例:")
 	fmt.Println("  go run . denoise input.wav output.wav 0.3")
+	fmt.Println("  go run . denoise input.wav output.wav 0.3 silence")
+	fmt.Println("  go run . denoise podcast.wav podcast_denoised.wav 0 silence --mix 0.8 --max-attenuation 12")
+	fmt.Println("  go run . denoise call.ulaw call_denoised.ulaw 0.3 silence -format mulaw -rate 8000")
 	fmt.Println("  go run . analyze noisy_audio.wav")
+	fmt.Println("  go run . segments meeting.wav -format csv -export ./clips")
+	fmt.Println("  go run . pipeline podcast.json input.wav output.wav")
 	fmt.Println("  go run . batch ./test_audio ./output")
 	fmt.Println("  go run . test test.wav 1 10")
 }
 
 func runDenoise(inputFile, outputFile string, args []string) {
+	// 位置参数之后是可选的命令行标志
+	positional, flagArgs := splitArgs(args)
+	flags := flag.NewFlagSet("denoise", flag.ExitOnError)
+	rawFormat := flags.String("format", "", "输入为无文件头的原始PCM时的编码: s16le、s16be、s24le、s32le、f32le、f64le、mulaw、alaw")
+	rawRate := flags.Int("rate", 8000, "原始PCM的采样率")
+	rawChannels := flags.Int("channels", 1, "原始PCM的声道数")
+	mix := flags.Float64("mix", 1, "降噪后信号在输出中的比例（0.0-1.0），其余为原始信号")
+	maxAttenuation := flags.Float64("max-attenuation", 0, "相对输入的最大衰减（dB），0表示不限制")
+	_ = flags.Parse(flagArgs)
+
 	// 解析语音概率阈值
 	var voiceProbThreshold float32 = 0.0
-	if len(args) > 0 {
-		if _, err := fmt.Sscanf(args[0], "%f", &voiceProbThreshold); err != nil {
+	if len(positional) > 0 {
+		if _, err := fmt.Sscanf(positional[0], "%f", &voiceProbThreshold); err != nil {
 			log.Printf("无效的语音概率阈值，使用默认值0.0: %v", err)
 			voiceProbThreshold = 0.0
 		}
 	}
+	policyName := "drop"
+	if len(positional) > 1 {
+		policyName = positional[1]
+	}
+	policy, ok := outputPolicies[policyName]
+	if !ok {
+		log.Fatalf("未知的输出策略: %s", policyName)
+	}
 
 	fmt.Printf("开始处理文件: %s\n", inputFile)
 	fmt.Printf("输出文件: %s\n", outputFile)
 	fmt.Printf("语音概率阈值: %.2f\n", voiceProbThreshold)
+	fmt.Printf("输出策略: %s\n", policyName)
+	if *mix < 1 || *maxAttenuation > 0 {
+		fmt.Printf("混合比例: %.2f, 最大衰减: %.1f dB\n", *mix, *maxAttenuation)
+	}
+	opts := []rnnoise.Option{
+		rnnoise.WithOutputPolicy(policy),
+		rnnoise.WithMix(float32(*mix)),
+		rnnoise.WithMaxAttenuation(float32(*maxAttenuation)),
+	}
+
+	if *rawFormat != "" {
+		format, err := rnnoise.ParsePCMFormat(*rawFormat, *rawRate, *rawChannels)
+		if err != nil {
+			log.Fatalf("无效的原始PCM格式: %v", err)
+		}
+		fmt.Printf("原始PCM格式: %s, %dHz, %d声道\n", format, format.SampleRate, format.Channels)
+		runDenoiseRaw(inputFile, outputFile, format, voiceProbThreshold, policy, opts)
+		return
+	}
 
 	// 创建噪声过滤器
-	filter, err := rnnoise.NewNoiseFilter("")
+	filter, err := rnnoise.NewNoiseFilter(opts...)
 
 	if err != nil {
 		log.Fatalf("创建噪声过滤器失败: %v", err)
@@ -118,34 +188,58 @@ func runDenoise(inputFile, outputFile string, args []string) {
 	fmt.Printf("\n处理完成！\n")
 	fmt.Printf("处理时间: %.2f 秒\n", elapsed.Seconds())
 	fmt.Printf("处理帧数: %d\n", result.ProcessedFrames)
-	fmt.Printf("音频时长: %.2f 秒\n", float64(len(result.DenoisedAudio.Samples))/float64(result.DenoisedAudio.SampleRate))
-	fmt.Printf("处理速度: %.1fx 实时\n", (float64(len(result.DenoisedAudio.Samples))/float64(result.DenoisedAudio.SampleRate))/elapsed.Seconds())
+	fmt.Printf("音频时长: %.2f 秒\n", result.InputDuration.Seconds())
+	fmt.Printf("处理速度: %.1fx 实时\n", result.InputDuration.Seconds()/elapsed.Seconds())
+
+	printVoiceStats(result.VoiceProbabilities, voiceProbThreshold, policy)
+}
 
-	// 计算语音帧统计
+// printVoiceStats 显示语音帧统计
+func printVoiceStats(voiceProbs []float32, voiceProbThreshold float32, policy rnnoise.OutputPolicy) {
 	voiceFrames := 0
 	var avgVoiceProb float32
-	for _, prob := range result.VoiceProbabilities {
+	for _, prob := range voiceProbs {
 		avgVoiceProb += prob
 		if prob >= voiceProbThreshold {
 			voiceFrames++
 		}
 	}
-	if len(result.VoiceProbabilities) > 0 {
-		avgVoiceProb /= float32(len(result.VoiceProbabilities))
+	if len(voiceProbs) > 0 {
+		avgVoiceProb /= float32(len(voiceProbs))
 	}
 
-	fmt.Printf("语音帧: %d/%d (%.1f%%)\n", voiceFrames, len(result.VoiceProbabilities),
-		float32(voiceFrames)/float32(len(result.VoiceProbabilities))*100)
+	fmt.Printf("语音帧: %d/%d (%.1f%%)\n", voiceFrames, len(voiceProbs),
+		float32(voiceFrames)/float32(len(voiceProbs))*100)
 	fmt.Printf("平均语音概率: %.3f\n", avgVoiceProb)
 
 	// 如果没有语音帧被保留，给出警告
 	if voiceFrames == 0 && voiceProbThreshold > 0 {
 		fmt.Printf("\n⚠️  警告: 没有帧达到语音概率阈值 %.2f\n", voiceProbThreshold)
 		fmt.Printf("💡 建议: 尝试使用更低的阈值（如0.0-0.3）或检查音频内容\n")
-		fmt.Printf("📊 当前输出文件可能为空或很短\n")
+		if policy == rnnoise.OutputDrop {
+			fmt.Printf("📊 当前输出文件可能为空或很短\n")
+		}
 	}
 }
 
+// splitArgs 将参数分为开头的位置参数和之后的命令行标志
+func splitArgs(args []string) (positional, flags []string) {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			return args[:i], args[i:]
+		}
+	}
+	return args, nil
+}
+
+// outputPolicies 命令行中输出策略的名称
+var outputPolicies = map[string]rnnoise.OutputPolicy{
+	"drop":      rnnoise.OutputDrop,
+	"silence":   rnnoise.OutputSilence,
+	"attenuate": rnnoise.OutputAttenuate,
+	"comfort":   rnnoise.OutputComfortNoise,
+}
+
 func runAnalyze(inputFile string, args []string) {
 	// 解析语音概率阈值
 	var voiceProbThreshold float32 = 0.3
@@ -160,14 +254,14 @@ func runAnalyze(inputFile string, args []string) {
 	fmt.Printf("语音概率阈值: %.2f\n", voiceProbThreshold)
 
 	// 创建噪声过滤器
-	filter, err := rnnoise.NewNoiseFilter("")
+	filter, err := rnnoise.NewNoiseFilter()
 	if err != nil {
 		log.Fatalf("创建噪声过滤器失败: %v", err)
 	}
 	defer filter.Destroy()
 
 	// 读取音频文件
-	processor := rnnoise.NewAudioProcessor(filter.GetRNNoise())
+	processor := rnnoise.NewAudioProcessor(filter.Denoiser())
 	audioData, err := processor.ReadWAV(inputFile)
 	if err != nil {
 		log.Fatalf("读取音频文件失败: %v", err)
@@ -202,7 +296,7 @@ func runStreamExample() {
 	fmt.Println("注意: 这只是一个演示，实际流式处理需要音频输入源")
 
 	// 创建噪声过滤器
-	filter, err := rnnoise.NewNoiseFilter("")
+	filter, err := rnnoise.NewNoiseFilter()
 	if err != nil {
 		log.Fatalf("创建噪声过滤器失败: %v", err)
 	}
@@ -279,7 +373,7 @@ func runBatch(inputDir string, args []string) {
 	fmt.Printf("找到 %d 个WAV文件\n\n", len(wavFiles))
 
 	// 创建噪声过滤器
-	filter, err := rnnoise.NewNoiseFilter("")
+	filter, err := rnnoise.NewNoiseFilter()
 	if err != nil {
 		log.Fatalf("创建噪声过滤器失败: %v", err)
 	}
@@ -313,7 +407,7 @@ func runBatch(inputDir string, args []string) {
 		elapsed := time.Since(startTime)
 
 		// 显示进度
-		audioDuration := float64(len(result.DenoisedAudio.Samples)) / float64(result.DenoisedAudio.SampleRate)
+		audioDuration := result.InputDuration.Seconds()
 		fmt.Printf("  完成: %.2fs (%.1fx 实时)\n", elapsed.Seconds(), audioDuration/elapsed.Seconds())
 
 		successCount++
@@ -325,7 +419,7 @@ func runBatch(inputDir string, args []string) {
 	fmt.Printf("成功处理: %d/%d 文件\n", successCount, len(wavFiles))
 }
 
-func runPerformanceTest(audioPath, batchSizeStr, numRunsStr string) {
+func runPerformanceTest(audioPath, batchSizeStr, numRunsStr string, args []string) {
 	// 解析参数
 	batchSize, err := strconv.Atoi(batchSizeStr)
 	if err != nil {
@@ -337,6 +431,16 @@ func runPerformanceTest(audioPath, batchSizeStr, numRunsStr string) {
 		log.Fatalf("无效的测试次数: %v", err)
 	}
 
+	// 非WAV文件按8000Hz单声道的原始PCM处理，默认s16le
+	rawFormatName := "s16le"
+	if len(args) > 0 {
+		rawFormatName = args[0]
+	}
+	rawFormat, err := rnnoise.ParsePCMFormat(rawFormatName, 8000, 1)
+	if err != nil {
+		log.Fatalf("无效的原始PCM格式: %v", err)
+	}
+
 	fmt.Printf("开始性能测试...\n")
 	fmt.Printf("音频文件: %s\n", audioPath)
 	fmt.Printf("批处理大小: %d\n", batchSize)
@@ -348,7 +452,7 @@ func runPerformanceTest(audioPath, batchSizeStr, numRunsStr string) {
 	for i := 0; i < numRuns; i++ {
 		startTime := time.Now()
 
-		err := performanceTestMain(audioPath, batchSize)
+		err := performanceTestMain(audioPath, batchSize, rawFormat)
 		if err != nil {
 			log.Printf("第 %d 次测试失败: %v", i+1, err)
 			continue
@@ -368,57 +472,102 @@ func runPerformanceTest(audioPath, batchSizeStr, numRunsStr string) {
 }
 
 // performanceTestMain 对应Python的main函数，进行批量音频处理
-func performanceTestMain(audioPath string, batchSize int) error {
+//
+// WAV文件按文件头中的格式解码；其他文件按rawFormat（8000Hz单声道）的原始PCM处理
+func performanceTestMain(audioPath string, batchSize int, rawFormat rnnoise.PCMFormat) error {
 	// 读取音频文件
 	audioData, err := ioutil.ReadFile(audioPath)
 	if err != nil {
 		return fmt.Errorf("无法读取音频文件: %v", err)
 	}
-
-	// 跳过WAV头部（44字节），获取PCM数据
-	if len(audioData) < 44 {
-		return fmt.Errorf("音频文件太小，不是有效的WAV文件")
+	sampleRate, samples, err := decodeTestAudio(audioData, rawFormat)
+	if err != nil {
+		return err
 	}
-	pcmData := audioData[44:]
 
-	// 分割为640字节的块（对应Python的chunk_size = 640）
-	chunkSize := 640
-	var pcmList [][]byte
-	for i := 0; i < len(pcmData); i += chunkSize {
+	// 分割为40ms的块（8000Hz 16位单声道下为640字节，对应Python的chunk_size = 640）
+	chunkSize := sampleRate / 25
+	var chunks [][]float32
+	for i := 0; i < len(samples); i += chunkSize {
 		end := i + chunkSize
-		if end > len(pcmData) {
-			end = len(pcmData)
+		if end > len(samples) {
+			end = len(samples)
 		}
-		pcmList = append(pcmList, pcmData[i:end])
+		chunks = append(chunks, samples[i:end])
 	}
 
-	// 创建噪声过滤器
-	filter, err := rnnoise.NewNoiseFilter("")
+	// 创建流式过滤器，跨块缓存不足一帧的样本和重采样历史
+	// 参数：单声道、语音概率阈值0.5
+	filter, err := rnnoise.NewStreamFilter(sampleRate, rnnoise.WithThreshold(0.5))
 	if err != nil {
 		return fmt.Errorf("创建噪声过滤器失败: %v", err)
 	}
 	defer filter.Destroy()
 
 	// 按批次处理
-	for i := 0; i < len(pcmList); i += batchSize {
+	for i := 0; i < len(chunks); i += batchSize {
 		end := i + batchSize
-		if end > len(pcmList) {
-			end = len(pcmList)
+		if end > len(chunks) {
+			end = len(chunks)
 		}
 
 		// 合并批次数据
-		var batchData []byte
+		var batchData []float32
 		for j := i; j < end; j++ {
-			batchData = append(batchData, pcmList[j]...)
+			batchData = append(batchData, chunks[j]...)
 		}
 
 		// 进行降噪处理（对应Python的filter调用）
-		// 参数：音频字节、采样率8000、单声道、16位、语音概率阈值0.5
-		_, _, err := filter.FilterAudioBytes(batchData, 8000, 1, 16, 0.5)
-		if err != nil {
+		if _, _, err := filter.Process(batchData); err != nil {
 			return fmt.Errorf("音频处理失败: %v", err)
 		}
 	}
 
+	if _, _, err := filter.Flush(); err != nil {
+		return fmt.Errorf("音频处理失败: %v", err)
+	}
+
 	return nil
 }
+
+// decodeTestAudio 解码性能测试的音频并混合为单声道，返回采样率和样本
+func decodeTestAudio(data []byte, rawFormat rnnoise.PCMFormat) (int, []float32, error) {
+	if !bytes.HasPrefix(data, []byte("RIFF")) {
+		return rawFormat.SampleRate, downmixSamples(rawFormat.Decode(data), rawFormat.Channels), nil
+	}
+
+	reader, err := rnnoise.NewWAVReader(bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, fmt.Errorf("无效的WAV文件: %v", err)
+	}
+	format := reader.Format()
+	var samples []float32
+	buf := make([]float32, 4096*format.Channels)
+	for {
+		n, err := reader.ReadSamples(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		samples = append(samples, downmixSamples(buf[:n], format.Channels)...)
+	}
+	return format.SampleRate, samples, nil
+}
+
+// downmixSamples 将交错的多声道样本取平均值混合为单声道
+func downmixSamples(samples []float32, channels int) []float32 {
+	if channels == 1 {
+		return samples
+	}
+	mono := make([]float32, len(samples)/channels)
+	for i := range mono {
+		var sum float32
+		for ch := 0; ch < channels; ch++ {
+			sum += samples[i*channels+ch]
+		}
+		mono[i] = sum / float32(channels)
+	}
+	return mono
+}
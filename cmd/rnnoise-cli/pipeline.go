@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/zhangzhao-gg/go-rnnoise/rnnoise"
+)
+
+// runPipeline 按JSON或YAML描述文件组装流水线处理WAV文件
+func runPipeline(specFile, inputFile, outputFile string) {
+	spec, err := rnnoise.LoadPipelineSpec(specFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// tap阶段把每块的语音概率记录下来，用于输出统计信息
+	var voiceProbs []float32
+	taps := map[string]func(*rnnoise.Block){
+		"voice": func(b *rnnoise.Block) { voiceProbs = append(voiceProbs, b.VoiceProbs...) },
+	}
+	pipeline, err := spec.Build(taps)
+	if err != nil {
+		log.Fatalf("创建流水线失败: %v", err)
+	}
+	defer pipeline.Close()
+
+	processor := rnnoise.NewAudioProcessor(nil)
+	audioData, err := processor.ReadWAV(inputFile)
+	if err != nil {
+		log.Fatalf("读取音频文件失败: %v", err)
+	}
+
+	startTime := time.Now()
+	output, err := pipeline.ProcessAudio(audioData)
+	if err != nil {
+		log.Fatalf("音频处理失败: %v", err)
+	}
+	elapsed := time.Since(startTime)
+	if err := processor.WriteWAV(outputFile, output); err != nil {
+		log.Fatalf("写入音频文件失败: %v", err)
+	}
+
+	duration := time.Duration(len(audioData.Samples)/audioData.Channels) * time.Second / time.Duration(audioData.SampleRate)
+	fmt.Printf("\n处理完成！\n")
+	fmt.Printf("阶段数: %d\n", len(pipeline.Stages()))
+	fmt.Printf("处理时间: %.2f 秒\n", elapsed.Seconds())
+	fmt.Printf("音频时长: %.2f 秒\n", duration.Seconds())
+	fmt.Printf("输出格式: %dHz, %d声道\n", output.SampleRate, output.Channels)
+	if len(voiceProbs) > 0 {
+		var sum float32
+		for _, prob := range voiceProbs {
+			sum += prob
+		}
+		fmt.Printf("平均语音概率: %.3f（%d帧）\n", sum/float32(len(voiceProbs)), len(voiceProbs))
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/zhangzhao-gg/go-rnnoise/rnnoise"
+)
+
+// runDenoiseRaw 流式处理无文件头的原始PCM文件，输出使用相同的格式，opts为过滤器的其余配置
+func runDenoiseRaw(inputFile, outputFile string, format rnnoise.PCMFormat, voiceProbThreshold float32, policy rnnoise.OutputPolicy, opts []rnnoise.Option) {
+	in, err := os.Open(inputFile)
+	if err != nil {
+		log.Fatalf("读取音频文件失败: %v", err)
+	}
+	defer in.Close()
+
+	// 通过指标回调收集每帧的语音概率
+	var voiceProbs []float32
+	reader, err := rnnoise.NewDenoiseReader(in, format, append(opts,
+		rnnoise.WithThreshold(voiceProbThreshold),
+		rnnoise.WithMetrics(rnnoise.Metrics{OnFrame: func(m rnnoise.FrameMetrics) {
+			voiceProbs = append(voiceProbs, m.VoiceProb)
+		}}),
+	)...)
+	if err != nil {
+		log.Fatalf("创建噪声过滤器失败: %v", err)
+	}
+	defer reader.Close()
+
+	out, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatalf("创建输出文件失败: %v", err)
+	}
+	defer out.Close()
+
+	startTime := time.Now()
+	if _, err := io.Copy(out, reader); err != nil {
+		log.Fatalf("音频处理失败: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatalf("写入输出文件失败: %v", err)
+	}
+	elapsed := time.Since(startTime)
+
+	duration := time.Duration(len(voiceProbs)) * 10 * time.Millisecond // 每帧10ms
+
+	fmt.Printf("\n处理完成！\n")
+	fmt.Printf("处理时间: %.2f 秒\n", elapsed.Seconds())
+	fmt.Printf("处理帧数: %d\n", len(voiceProbs))
+	fmt.Printf("音频时长: %.2f 秒\n", duration.Seconds())
+	fmt.Printf("处理速度: %.1fx 实时\n", duration.Seconds()/elapsed.Seconds())
+
+	printVoiceStats(voiceProbs, voiceProbThreshold, policy)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zhangzhao-gg/go-rnnoise/rnnoise"
+)
+
+// segmentOutput 语音段的JSON输出，时间单位为秒
+type segmentOutput struct {
+	Index    int     `json:"index"`
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Duration float64 `json:"duration"`
+	MeanProb float32 `json:"mean_prob"`
+	PeakProb float32 `json:"peak_prob"`
+	File     string  `json:"file,omitempty"`
+}
+
+func runSegments(inputFile string, args []string) {
+	defaults := rnnoise.DefaultVADConfig()
+
+	flags := flag.NewFlagSet("segments", flag.ExitOnError)
+	format := flags.String("format", "json", "输出格式: json 或 csv")
+	exportDir := flags.String("export", "", "将每个语音段导出为单独的WAV文件到该目录")
+	onThreshold := flags.Float64("on", float64(defaults.OnThreshold), "进入语音的概率阈值")
+	offThreshold := flags.Float64("off", float64(defaults.OffThreshold), "离开语音的概率阈值")
+	hangover := flags.Duration("hangover", defaults.Hangover, "低于离开阈值后保持语音的时长")
+	minSpeech := flags.Duration("min-speech", defaults.MinSpeech, "最短语音段")
+	minSilence := flags.Duration("min-silence", defaults.MinSilence, "最短静音间隙")
+	_ = flags.Parse(args)
+
+	if *format != "json" && *format != "csv" {
+		log.Fatalf("未知的输出格式: %s", *format)
+	}
+
+	cfg := defaults
+	cfg.OnThreshold = float32(*onThreshold)
+	cfg.OffThreshold = float32(*offThreshold)
+	cfg.Hangover = *hangover
+	cfg.MinSpeech = *minSpeech
+	cfg.MinSilence = *minSilence
+	vad, err := rnnoise.NewVAD(cfg)
+	if err != nil {
+		log.Fatalf("VAD参数无效: %v", err)
+	}
+
+	filter, err := rnnoise.NewNoiseFilter()
+	if err != nil {
+		log.Fatalf("创建噪声过滤器失败: %v", err)
+	}
+	defer filter.Destroy()
+
+	processor := rnnoise.NewAudioProcessor(filter.Denoiser())
+	audioData, err := processor.ReadWAV(inputFile)
+	if err != nil {
+		log.Fatalf("读取音频文件失败: %v", err)
+	}
+
+	segments, err := filter.DetectSegments(audioData, vad)
+	if err != nil {
+		log.Fatalf("语音段检测失败: %v", err)
+	}
+
+	outputs := make([]segmentOutput, len(segments))
+	for i, seg := range segments {
+		outputs[i] = segmentOutput{
+			Index:    i + 1,
+			Start:    seg.Start.Seconds(),
+			End:      seg.End.Seconds(),
+			Duration: seg.Duration().Seconds(),
+			MeanProb: seg.MeanProb,
+			PeakProb: seg.PeakProb,
+		}
+	}
+
+	if *exportDir != "" {
+		if err := os.MkdirAll(*exportDir, 0755); err != nil {
+			log.Fatalf("创建导出目录失败: %v", err)
+		}
+		base := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+		for i, seg := range segments {
+			file := filepath.Join(*exportDir, fmt.Sprintf("%s_%03d.wav", base, i+1))
+			if err := processor.WriteWAV(file, audioData.Slice(seg.Start, seg.End)); err != nil {
+				log.Fatalf("导出语音段失败: %v", err)
+			}
+			outputs[i].File = file
+		}
+	}
+
+	if *format == "csv" {
+		writeSegmentsCSV(outputs)
+		return
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(outputs); err != nil {
+		log.Fatalf("输出JSON失败: %v", err)
+	}
+}
+
+func writeSegmentsCSV(outputs []segmentOutput) {
+	w := csv.NewWriter(os.Stdout)
+	_ = w.Write([]string{"index", "start", "end", "duration", "mean_prob", "peak_prob", "file"})
+	for _, out := range outputs {
+		_ = w.Write([]string{
+			strconv.Itoa(out.Index),
+			strconv.FormatFloat(out.Start, 'f', 3, 64),
+			strconv.FormatFloat(out.End, 'f', 3, 64),
+			strconv.FormatFloat(out.Duration, 'f', 3, 64),
+			strconv.FormatFloat(float64(out.MeanProb), 'f', 3, 32),
+			strconv.FormatFloat(float64(out.PeakProb), 'f', 3, 32),
+			out.File,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatalf("输出CSV失败: %v", err)
+	}
+}
@@ -14,7 +14,7 @@ func main() {
 	fmt.Println("批量音频处理示例")
 
 	// 创建噪声过滤器
-	filter, err := rnnoise.NewNoiseFilter("")
+	filter, err := rnnoise.NewNoiseFilter()
 	if err != nil {
 		log.Fatal("创建噪声过滤器失败:", err)
 	}
@@ -49,7 +49,7 @@ func main() {
 		elapsed := time.Since(startTime)
 
 		// 显示处理结果
-		audioDuration := float64(len(result.DenoisedAudio.Samples)) / float64(result.DenoisedAudio.SampleRate)
+		audioDuration := result.InputDuration.Seconds()
 		fmt.Printf("  完成: %.2fs (%.1fx 实时)\n", elapsed.Seconds(), audioDuration/elapsed.Seconds())
 
 		// 计算语音帧统计
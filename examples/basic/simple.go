@@ -10,7 +10,7 @@ import (
 // 简单的音频降噪示例
 func main() {
 	// 创建噪声过滤器（自动查找库文件）
-	filter, err := rnnoise.NewNoiseFilter("")
+	filter, err := rnnoise.NewNoiseFilter()
 	if err != nil {
 		log.Fatal("创建噪声过滤器失败:", err)
 	}
@@ -32,7 +32,7 @@ func main() {
 	fmt.Printf("- 输出文件: %s\n", outputFile)
 	fmt.Printf("- 处理帧数: %d\n", result.ProcessedFrames)
 	fmt.Printf("- 音频时长: %.2f 秒\n",
-		float64(len(result.DenoisedAudio.Samples))/float64(result.DenoisedAudio.SampleRate))
+		result.InputDuration.Seconds())
 
 	// 计算语音帧统计
 	voiceFrames := 0
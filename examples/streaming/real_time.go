@@ -13,7 +13,7 @@ func main() {
 	fmt.Println("实时流式音频处理示例")
 
 	// 创建噪声过滤器
-	filter, err := rnnoise.NewNoiseFilter("")
+	filter, err := rnnoise.NewNoiseFilter()
 	if err != nil {
 		log.Fatal("创建噪声过滤器失败:", err)
 	}
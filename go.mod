@@ -2,13 +2,6 @@ module github.com/zhangzhao-gg/go-rnnoise
 
 go 1.19
 
-require (
-	github.com/go-audio/audio v1.0.0
-	github.com/go-audio/wav v1.1.0
-	github.com/sirupsen/logrus v1.9.3
-)
+require github.com/sirupsen/logrus v1.9.3
 
-require (
-	github.com/go-audio/riff v1.0.0 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
-)
+require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
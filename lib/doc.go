@@ -0,0 +1,9 @@
+// Package lib 随项目发布的RNNoise动态库
+//
+// 使用构建标签 rnnoise_embed 在darwin/arm64上编译时（库文件为macOS arm64版本），
+// 动态库会通过go:embed嵌入到可执行文件中，
+// rnnoise包在未指定库路径时自动将其解压到缓存目录并加载，
+// 这样 rnnoise-cli 可以作为单个文件分发（在其他平台上使用该标签会编译失败）：
+//
+//	go build -tags rnnoise_embed ./cmd/rnnoise-cli
+package lib
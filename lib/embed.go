@@ -0,0 +1,16 @@
+//go:build rnnoise_embed && darwin && arm64
+
+package lib
+
+import _ "embed" // go:embed
+
+// Name 嵌入的库文件名
+const Name = "librnnoise_5h_b_500k.so.0.4.1"
+
+// SHA256 嵌入库文件的SHA-256校验和（十六进制），解压后会据此校验
+const SHA256 = "ebcf6485794a9ba7013543c160b9d01944222e43b33fd1c14bb584f5376c96e9"
+
+// Data 嵌入的库文件内容
+//
+//go:embed librnnoise_5h_b_500k.so.0.4.1
+var Data []byte
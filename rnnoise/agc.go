@@ -0,0 +1,136 @@
+package rnnoise
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// agcSilenceLevel 低于该电平（dBFS）的帧不参与语音电平估计
+const agcSilenceLevel = -70.0
+
+// AGCConfig 语音门控自动增益控制的参数
+//
+// 只有语音概率达到SpeechThreshold的帧才更新语音电平的估计，噪声和静音段保持当前增益，
+// 因此增益不会在停顿时被噪声抬高（不会"呼吸"）。
+// 增益为TargetLevel与估计的语音电平之差，限制在±MaxGain以内；
+// 电平估计按Response平滑，电平升高时以4倍速度跟随，避免突然变大的声音过载
+type AGCConfig struct {
+	TargetLevel     float64       // 目标语音电平（dBFS RMS）
+	MaxGain         float64       // 最大增益调整幅度（dB），提升和衰减都不超过该值
+	SpeechThreshold float32       // 语音概率达到该值的帧才参与电平估计
+	Response        time.Duration // 电平估计的时间常数
+}
+
+// DefaultAGCConfig 适合语音录音的默认参数
+func DefaultAGCConfig() AGCConfig {
+	return AGCConfig{
+		TargetLevel:     -20,
+		MaxGain:         20,
+		SpeechThreshold: 0.6,
+		Response:        time.Second,
+	}
+}
+
+// validate 检查参数是否有效
+func (c AGCConfig) validate() error {
+	if c.TargetLevel >= 0 {
+		return fmt.Errorf("AGC目标电平必须小于0dBFS，当前为%v", c.TargetLevel)
+	}
+	if c.MaxGain < 0 {
+		return fmt.Errorf("AGC最大增益不能为负数，当前为%vdB", c.MaxGain)
+	}
+	if c.SpeechThreshold < 0 || c.SpeechThreshold > 1 {
+		return fmt.Errorf("AGC语音概率阈值必须在0到1之间，当前为%v", c.SpeechThreshold)
+	}
+	if c.Response <= 0 {
+		return fmt.Errorf("AGC时间常数必须大于0，当前为%v", c.Response)
+	}
+	return nil
+}
+
+// agc 逐帧更新的自动增益控制状态，多个声道共享同一个增益以保持声像
+type agc struct {
+	cfg   AGCConfig
+	rise  float64 // 电平升高时每帧的平滑系数
+	fall  float64 // 电平降低时每帧的平滑系数
+	level float64 // 估计的语音电平（dBFS）
+	from  float64 // 当前帧开始时的线性增益
+	gain  float64 // 当前帧结束时的线性增益
+}
+
+// newAGC 按过滤器的配置创建自动增益控制，未启用时返回nil
+func (nf *NoiseFilter) newAGC() *agc {
+	if nf.agc == nil {
+		return nil
+	}
+
+	frameDuration := float64(nf.denoiser.FrameSize()) / float64(nf.denoiser.SampleRateHz())
+	response := nf.agc.Response.Seconds()
+	a := &agc{
+		cfg:  *nf.agc,
+		rise: 1 - math.Exp(-frameDuration*4/response),
+		fall: 1 - math.Exp(-frameDuration/response),
+	}
+	a.reset()
+	return a
+}
+
+// reset 开始新的一段音频，增益回到0dB
+func (a *agc) reset() {
+	if a == nil {
+		return
+	}
+	// 电平估计从目标电平开始，增益从0dB逐渐过渡，而不是在第一个语音帧跳变
+	a.level = a.cfg.TargetLevel
+	a.from, a.gain = 1, 1
+}
+
+// update 根据一帧的语音概率和各声道降噪后的帧更新增益
+func (a *agc) update(voiceProb float32, frames ...[]float32) {
+	if a == nil {
+		return
+	}
+	a.from = a.gain
+	if voiceProb < a.cfg.SpeechThreshold {
+		return
+	}
+
+	var energy float64
+	n := 0
+	for _, frame := range frames {
+		for _, sample := range frame {
+			energy += float64(sample) * float64(sample)
+		}
+		n += len(frame)
+	}
+	if n == 0 {
+		return
+	}
+	level := 10 * math.Log10(energy/float64(n))
+	if level < agcSilenceLevel {
+		return
+	}
+
+	// 在dB域平滑，升高和降低的收敛时间只取决于时间常数，与电平差无关
+	coeff := a.fall
+	if level > a.level {
+		coeff = a.rise
+	}
+	a.level += coeff * (level - a.level)
+
+	gainDB := math.Max(-a.cfg.MaxGain, math.Min(a.cfg.MaxGain, a.cfg.TargetLevel-a.level))
+	a.gain = math.Pow(10, gainDB/20)
+}
+
+// apply 对一帧施加增益（原地修改），从上一帧的增益线性过渡
+func (a *agc) apply(frame []float32) {
+	if a == nil {
+		return
+	}
+	n := float64(len(frame))
+	for i := range frame {
+		gain := a.from + (a.gain-a.from)*float64(i+1)/n
+		frame[i] = float32(float64(frame[i]) * gain)
+	}
+}
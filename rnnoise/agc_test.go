@@ -0,0 +1,117 @@
+package rnnoise
+
+import (
+	"math"
+	"testing"
+)
+
+func rmsDB(samples []float32) float64 {
+	var energy float64
+	for _, sample := range samples {
+		energy += float64(sample) * float64(sample)
+	}
+	return 10 * math.Log10(energy/float64(len(samples)))
+}
+
+func TestAGCReachesTargetLevel(t *testing.T) {
+	// -34dBFS峰值（约-37dBFS RMS）的语音提升到-20dBFS RMS
+	filter, err := NewNoiseFilterWithDenoiser(NewFakeDenoiser(0.9),
+		WithOutputPolicy(OutputSilence), WithAGC(DefaultAGCConfig()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	input := tone(48000*6, 48000, 440, -34)
+	result, err := filter.FilterAudio(&AudioData{Samples: input, SampleRate: 48000, Channels: 1, BitDepth: 16}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := result.DenoisedAudio.Samples
+	if level := rmsDB(out[len(out)-48000:]); math.Abs(level+20) > 0.5 {
+		t.Errorf("output level %.1f dBFS, want -20", level)
+	}
+	// 增益逐渐上升，开头没有跳变
+	if level := rmsDB(out[:4800]); level > -35 {
+		t.Errorf("first 100ms level %.1f dBFS, want close to the input level", level)
+	}
+}
+
+func TestAGCHoldsGainDuringNoise(t *testing.T) {
+	// 5秒语音之后是1秒语音概率很低的较响噪声，噪声段保持语音段的增益
+	probs := make([]float32, 600)
+	for i := range probs {
+		probs[i] = 0.9
+		if i >= 500 {
+			probs[i] = 0.1
+		}
+	}
+	filter, err := NewNoiseFilterWithDenoiser(NewFakeDenoiser(probs...),
+		WithOutputPolicy(OutputSilence), WithThreshold(0), WithAGC(DefaultAGCConfig()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	input := append(tone(48000*5, 48000, 440, -34), tone(48000, 48000, 440, -26)...)
+	result, err := filter.FilterAudio(&AudioData{Samples: input, SampleRate: 48000, Channels: 1, BitDepth: 16}, DefaultThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := result.DenoisedAudio.Samples
+	speechGain := rmsDB(out[48000*4:48000*5]) - rmsDB(input[48000*4:48000*5])
+	noiseGain := rmsDB(out[48000*5+4800:]) - rmsDB(input[48000*5+4800:])
+	if math.Abs(noiseGain-speechGain) > 0.5 {
+		t.Errorf("gain changed from %.1f dB during speech to %.1f dB during noise", speechGain, noiseGain)
+	}
+}
+
+func TestStreamFilterAGCMatchesFilterAudio(t *testing.T) {
+	opts := func() []Option {
+		return []Option{WithDenoiser(NewFakeDenoiser(0.9, 0.9, 0.3)), WithOutputPolicy(OutputAttenuate),
+			WithThreshold(0.5), WithAGC(DefaultAGCConfig())}
+	}
+	input := tone(16000*2+123, 16000, 300, -40)
+	input = append(input, tone(16000, 16000, 300, 0)...) // 需要限幅的部分
+
+	filter, err := NewNoiseFilter(opts()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+	want, err := filter.FilterAudio(&AudioData{Samples: input, SampleRate: 16000, Channels: 1, BitDepth: 16}, DefaultThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sf, err := NewStreamFilter(16000, opts()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Destroy()
+	got := processInChunks(input, []int{160, 999, 7}, func(chunk []float32) []float32 {
+		out, _, err := sf.Process(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	})
+	tail, _, err := sf.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, tail...)
+
+	if len(got) != len(input) || len(want.DenoisedAudio.Samples) != len(input) {
+		t.Fatalf("output %d / %d samples, want %d", len(got), len(want.DenoisedAudio.Samples), len(input))
+	}
+	ceiling := math.Pow(10, DefaultTruePeakCeiling/20)
+	for i, sample := range want.DenoisedAudio.Samples {
+		if math.Abs(float64(got[i]-sample)) > 1e-5 {
+			t.Fatalf("sample %d = %f, want %f", i, got[i], sample)
+		}
+		if math.Abs(float64(sample)) > ceiling+1e-6 {
+			t.Fatalf("sample %d = %f exceeds the true-peak ceiling", i, sample)
+		}
+	}
+}
@@ -7,8 +7,6 @@ import (
 	"io"
 	"os"
 
-	"github.com/go-audio/audio"
-	"github.com/go-audio/wav"
 	"github.com/sirupsen/logrus"
 )
 
@@ -17,14 +15,37 @@ import (
 // AudioProcessor 提供了音频文件的读写、格式转换和帧处理功能。
 // 它支持多种音频格式的自动转换，将各种格式统一转换为RNNoise支持的48kHz单声道格式。
 type AudioProcessor struct {
-	rnnoise *RNNoise
+	denoiser  FrameDenoiser
+	resampler Resampler
+	logger    logrus.FieldLogger
 }
 
 // NewAudioProcessor 创建新的音频处理器
-func NewAudioProcessor(rnnoise *RNNoise) *AudioProcessor {
+//
+// 格式转换和分帧会按照降噪后端的采样率和帧大小进行；
+// denoiser 为nil时使用RNNoise的默认格式（48kHz，480样本/帧）
+func NewAudioProcessor(denoiser FrameDenoiser) *AudioProcessor {
 	return &AudioProcessor{
-		rnnoise: rnnoise,
+		denoiser:  denoiser,
+		resampler: PolyphaseResampler{},
+		logger:    logrus.StandardLogger(),
+	}
+}
+
+// sampleRate 降噪后端要求的采样率
+func (ap *AudioProcessor) sampleRate() int {
+	if ap.denoiser == nil {
+		return rnnoiseSampleRate
+	}
+	return ap.denoiser.SampleRateHz()
+}
+
+// frameSize 降噪后端要求的帧大小
+func (ap *AudioProcessor) frameSize() int {
+	if ap.denoiser == nil {
+		return rnnoiseFrameSize
 	}
+	return ap.denoiser.FrameSize()
 }
 
 // AudioData 音频数据结构
@@ -36,119 +57,103 @@ type AudioData struct {
 	SampleRate int       // 采样率
 	Channels   int       // 声道数
 	BitDepth   int       // 位深度
+	Float      bool      // 是否为IEEE浮点样本（BitDepth为32或64），仅影响WriteWAV
 }
 
 // ReadWAV 读取WAV文件
+//
+// 支持8位（无符号）、16位、24位、32位整数PCM，32位、64位IEEE浮点，以及WAVE_FORMAT_EXTENSIBLE格式，
+// 样本按文件的实际位深度归一化到-1.0到1.0
 func (ap *AudioProcessor) ReadWAV(filename string) (*AudioData, error) {
-	file, err := os.Open(filename)
+	reader, err := OpenWAV(filename)
 	if err != nil {
-		return nil, fmt.Errorf("无法打开文件 %s: %v", filename, err)
+		return nil, err
 	}
-	defer file.Close()
+	defer reader.Close()
+	format := reader.Format()
 
-	decoder := wav.NewDecoder(file)
-	if !decoder.IsValidFile() {
-		return nil, fmt.Errorf("无效的WAV文件: %s", filename)
-	}
-	// 获取音频格式信息
-	format := decoder.Format()
-	// 读取所有音频数据
-	buf := &audio.IntBuffer{
-		Data:   make([]int, 0),
-		Format: format,
+	// 按块解码，data块大小已知时预先分配。文件头中的大小不可信，
+	// 预分配不超过文件的实际大小，其余部分由append增长
+	var samples []float32
+	if size := reader.DataSize(); size != wavUnknownSize {
+		if info, err := os.Stat(filename); err == nil {
+			if size > info.Size() {
+				size = info.Size()
+			}
+			samples = make([]float32, 0, size/int64(format.bytesPerSample()))
+		}
 	}
-	// 分块读取音频数据
+	buf := make([]float32, fileBlockFrames*format.Channels)
 	for {
-		chunk := &audio.IntBuffer{
-			Data:   make([]int, 1024),
-			Format: format,
+		n, err := reader.ReadSamples(buf)
+		if err == io.EOF {
+			break
 		}
-
-		n, err := decoder.PCMBuffer(chunk)
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, fmt.Errorf("读取音频数据失败: %v", err)
+			return nil, err
 		}
-
-		// 如果读取到0字节且没有错误，说明已经读完了
-		if n == 0 {
-			break
-		}
-
-		buf.Data = append(buf.Data, chunk.Data[:n]...)
-	}
-
-	// 转换为float32格式
-	samples := make([]float32, len(buf.Data))
-	bitDepth := 16 // 默认16位，因为go-audio没有直接的SampleSize字段
-	maxVal := float32(int32(1) << uint(bitDepth-1))
-	for i, sample := range buf.Data {
-		samples[i] = float32(sample) / maxVal
+		samples = append(samples, buf[:n]...)
 	}
 
 	return &AudioData{
 		Samples:    samples,
-		SampleRate: int(format.SampleRate),
-		Channels:   int(format.NumChannels),
-		BitDepth:   bitDepth,
+		SampleRate: format.SampleRate,
+		Channels:   format.Channels,
+		BitDepth:   format.BitDepth,
+		Float:      format.Float,
 	}, nil
 }
 
 // WriteWAV 写入WAV文件
+//
+// 按audioData的BitDepth和Float写入整数PCM（8/16/24/32位）或IEEE浮点（32/64位），
+// BitDepth为0时写入16位；超过两个声道时使用WAVE_FORMAT_EXTENSIBLE
 func (ap *AudioProcessor) WriteWAV(filename string, audioData *AudioData) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("无法创建文件 %s: %v", filename, err)
-	}
-	defer file.Close()
-
-	// 设置音频格式
-	format := &audio.Format{
-		NumChannels: audioData.Channels,
-		SampleRate:  audioData.SampleRate,
+	format := WAVFormat{
+		SampleRate: audioData.SampleRate,
+		Channels:   audioData.Channels,
+		BitDepth:   audioData.BitDepth,
+		Float:      audioData.Float,
 	}
-
-	encoder := wav.NewEncoder(file, int(format.SampleRate), audioData.BitDepth, int(format.NumChannels), 1)
-	defer encoder.Close()
-
-	// 转换float32样本为int格式
-	maxVal := float32(int32(1) << uint(audioData.BitDepth-1))
-	intSamples := make([]int, len(audioData.Samples))
-	for i, sample := range audioData.Samples {
-		// 限制范围并转换
-		if sample > 1.0 {
-			sample = 1.0
-		} else if sample < -1.0 {
-			sample = -1.0
-		}
-		intSamples[i] = int(sample * maxVal)
+	if format.BitDepth == 0 {
+		format.BitDepth = 16
 	}
 
-	// 创建音频缓冲区
-	buf := &audio.IntBuffer{
-		Data:   intSamples,
-		Format: format,
+	writer, err := CreateWAV(filename, format)
+	if err != nil {
+		return err
 	}
-
-	// 写入音频数据
-	if err := encoder.Write(buf); err != nil {
-		return fmt.Errorf("写入音频数据失败: %v", err)
+	defer writer.Close()
+
+	// 按块编码，只写入完整的采样帧
+	samples := audioData.Samples[:len(audioData.Samples)/format.Channels*format.Channels]
+	block := fileBlockFrames * format.Channels
+	for start := 0; start < len(samples); start += block {
+		end := start + block
+		if end > len(samples) {
+			end = len(samples)
+		}
+		if err := writer.WriteSamples(samples[start:end]); err != nil {
+			return err
+		}
 	}
-
-	return nil
+	return writer.Close()
 }
 
 // ConvertToRNNoiseFormat 将音频转换为RNNoise支持的格式（48kHz, 单声道, 16位）
 // 输入音频格式: 8000Hz, 1声道, 16位深, 160样本
 // 转换后音频格式: 48000Hz, 1声道, 16位深, 960样本
 func (ap *AudioProcessor) ConvertToRNNoiseFormat(audioData *AudioData) (*AudioData, error) {
-	logrus.Debugf("输入音频格式: %dHz, %d声道, %d位深, %d样本",
+	ap.logger.Debugf("输入音频格式: %dHz, %d声道, %d位深, %d样本",
 		audioData.SampleRate, audioData.Channels, audioData.BitDepth, len(audioData.Samples))
 
+	if audioData.SampleRate <= 0 || audioData.Channels <= 0 {
+		return nil, fmt.Errorf("无效的音频格式: %dHz, %d声道", audioData.SampleRate, audioData.Channels)
+	}
+
+	targetRate := ap.sampleRate()
 	result := &AudioData{
-		SampleRate: 48000,
+		SampleRate: targetRate,
 		Channels:   1,
 		BitDepth:   16,
 	}
@@ -175,40 +180,24 @@ func (ap *AudioProcessor) ConvertToRNNoiseFormat(audioData *AudioData) (*AudioDa
 		samples = monoSamples
 	}
 
-	// 2. 重采样到48kHz（简单的线性插值）
-	if audioData.SampleRate != 48000 {
-		ratio := float64(48000) / float64(audioData.SampleRate)
-		newLength := int(float64(len(samples)) * ratio)
-		resampledSamples := make([]float32, newLength)
-
-		for i := 0; i < newLength; i++ {
-			srcIndex := float64(i) / ratio
-			srcIndexInt := int(srcIndex)
-
-			if srcIndexInt >= len(samples)-1 {
-				resampledSamples[i] = samples[len(samples)-1]
-			} else {
-				// 线性插值
-				frac := float32(srcIndex - float64(srcIndexInt))
-				resampledSamples[i] = samples[srcIndexInt]*(1-frac) + samples[srcIndexInt+1]*frac
-			}
-		}
-		samples = resampledSamples
+	// 2. 重采样到48kHz（默认带限多相重采样）
+	if audioData.SampleRate != targetRate {
+		samples = ap.resampler.Resample(samples, audioData.SampleRate, targetRate)
 	}
 
 	result.Samples = samples
-	logrus.Debugf("转换后音频格式: %dHz, %d声道, %d位深, %d样本",
+	ap.logger.Debugf("转换后音频格式: %dHz, %d声道, %d位深, %d样本",
 		result.SampleRate, result.Channels, result.BitDepth, len(result.Samples))
 	return result, nil
 }
 
 // GetFrames 将音频数据分割为10毫秒的帧
 func (ap *AudioProcessor) GetFrames(audioData *AudioData) ([][]float32, error) {
-	if audioData.SampleRate != 48000 {
-		return nil, fmt.Errorf("音频采样率必须为48kHz，当前为%dHz", audioData.SampleRate)
+	if targetRate := ap.sampleRate(); audioData.SampleRate != targetRate {
+		return nil, fmt.Errorf("音频采样率必须为%dHz，当前为%dHz", targetRate, audioData.SampleRate)
 	}
 
-	frameSize := int(48000 * 0.01) // 10ms at 48kHz = 480 samples
+	frameSize := ap.frameSize() // 10ms at 48kHz = 480 samples
 	samples := audioData.Samples
 
 	// 如果样本数不是帧大小的整数倍，用零填充
@@ -232,6 +221,8 @@ func (ap *AudioProcessor) GetFrames(audioData *AudioData) ([][]float32, error) {
 }
 
 // ConvertBytesToFloat32 将字节数组转换为float32样本
+//
+// 只支持小端序16/24/32位有符号整数，其他编码请使用PCMFormat.Decode
 func ConvertBytesToFloat32(data []byte, bitDepth int) []float32 {
 	logrus.Debugf("ConvertBytesToFloat32: 输入%d字节, 位深%d位", len(data), bitDepth)
 	var samples []float32
@@ -272,6 +263,8 @@ func ConvertBytesToFloat32(data []byte, bitDepth int) []float32 {
 }
 
 // ConvertFloat32ToBytes 将float32样本转换为字节数组
+//
+// 只支持小端序16/24/32位有符号整数，其他编码请使用PCMFormat.Encode
 func ConvertFloat32ToBytes(samples []float32, bitDepth int) []byte {
 	var buf bytes.Buffer
 
@@ -0,0 +1,53 @@
+package rnnoise
+
+import "math"
+
+// biquadFilter 二阶IIR滤波器（转置直接II型），状态跨调用保留
+//
+// 系数已按a0归一化：y = b0*x + b1*x[-1] + b2*x[-2] - a1*y[-1] - a2*y[-2]
+type biquadFilter struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+	z1, z2     float64
+}
+
+// process 滤波一个样本
+func (f *biquadFilter) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+// reset 清空滤波器状态
+func (f *biquadFilter) reset() {
+	f.z1, f.z2 = 0, 0
+}
+
+// highPassBiquad 二阶高通滤波器（RBJ Audio EQ Cookbook）
+func highPassBiquad(rate, cutoff, q float64) biquadFilter {
+	w0 := 2 * math.Pi * cutoff / rate
+	cos, alpha := math.Cos(w0), math.Sin(w0)/(2*q)
+	a0 := 1 + alpha
+	return biquadFilter{
+		b0: (1 + cos) / 2 / a0,
+		b1: -(1 + cos) / a0,
+		b2: (1 + cos) / 2 / a0,
+		a1: -2 * cos / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
+
+// notchBiquad 二阶陷波滤波器（RBJ Audio EQ Cookbook），freq处增益为0
+func notchBiquad(rate, freq, q float64) biquadFilter {
+	w0 := 2 * math.Pi * freq / rate
+	cos, alpha := math.Cos(w0), math.Sin(w0)/(2*q)
+	a0 := 1 + alpha
+	return biquadFilter{
+		b0: 1 / a0,
+		b1: -2 * cos / a0,
+		b2: 1 / a0,
+		a1: -2 * cos / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
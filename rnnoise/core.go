@@ -1,99 +1,73 @@
 package rnnoise
 
-/*
-#include <stdlib.h>
-#include <dlfcn.h>
-
-// RNNoise C函数指针类型定义
-typedef struct RNNoiseState RNNoiseState;
-typedef RNNoiseState* (*rnnoise_create_func)(void *model);
-typedef void (*rnnoise_destroy_func)(RNNoiseState *st);
-typedef float (*rnnoise_process_frame_func)(RNNoiseState *st, float *out, const float *in);
-
-// 全局变量存储动态库句柄和函数指针
-static void* rnnoise_handle = NULL;
-static rnnoise_create_func rnnoise_create_ptr = NULL;
-static rnnoise_destroy_func rnnoise_destroy_ptr = NULL;
-static rnnoise_process_frame_func rnnoise_process_frame_ptr = NULL;
-
-// 加载RNNoise库
-int load_rnnoise_library(const char* lib_path) {
-    rnnoise_handle = dlopen(lib_path, RTLD_LAZY);
-    if (!rnnoise_handle) {
-        return 0; // 失败
-    }
-
-    rnnoise_create_ptr = (rnnoise_create_func)dlsym(rnnoise_handle, "rnnoise_create");
-    rnnoise_destroy_ptr = (rnnoise_destroy_func)dlsym(rnnoise_handle, "rnnoise_destroy");
-    rnnoise_process_frame_ptr = (rnnoise_process_frame_func)dlsym(rnnoise_handle, "rnnoise_process_frame");
-
-    if (!rnnoise_create_ptr || !rnnoise_destroy_ptr || !rnnoise_process_frame_ptr) {
-        dlclose(rnnoise_handle);
-        rnnoise_handle = NULL;
-        return 0; // 失败
-    }
-
-    return 1; // 成功
-}
-
-// 释放库
-void unload_rnnoise_library() {
-    if (rnnoise_handle) {
-        dlclose(rnnoise_handle);
-        rnnoise_handle = NULL;
-    }
-}
-
-// 包装函数
-RNNoiseState* rnnoise_create(void *model) {
-    if (rnnoise_create_ptr) {
-        return rnnoise_create_ptr(model);
-    }
-    return NULL;
-}
-
-void rnnoise_destroy(RNNoiseState *st) {
-    if (rnnoise_destroy_ptr) {
-        rnnoise_destroy_ptr(st);
-    }
-}
-
-float rnnoise_process_frame(RNNoiseState *st, float *out, const float *in) {
-    if (rnnoise_process_frame_ptr) {
-        return rnnoise_process_frame_ptr(st, out, in);
-    }
-    return 0.0;
-}
-*/
-import "C"
 import (
 	"fmt"
 	"os"
-	"path/filepath"
-	"runtime"
-	"unsafe"
 )
 
+const (
+	rnnoiseFrameSize  = 480   // 每帧样本数（10ms @ 48kHz）
+	rnnoiseSampleRate = 48000 // RNNoise要求的采样率
+)
+
+// frameEngine RNNoise推理后端
+//
+// 后端处理的样本为16位整数范围的float（-32768到32767），
+// 与RNNoise C库rnnoise_process_frame的约定一致
+type frameEngine interface {
+	// processFrame 处理一帧480个样本，返回语音概率
+	processFrame(out, in []float32) float32
+	// processFrames 处理len(probs)个连续帧，语音概率写入probs
+	processFrames(out, in, probs []float32)
+	// reset 清除后端的内部状态
+	reset() error
+	// close 释放后端持有的资源
+	close()
+}
+
 // RNNoise 结构体，用于封装RNNoise降噪功能
 // RNNoise 是 Mozilla 开发的一个基于深度学习的实时噪声抑制库
 // 它专门用于语音通话和音频处理，能够有效去除背景噪声
+//
+// RNNoise 可以由两种后端驱动：通过cgo调用RNNoise动态库（NewRNNoise），
+// 或者不依赖cgo的纯Go实现（NewGoRNNoise），两者的ProcessFrame行为一致
+//
+// 导出的格式字段仅用于描述RNNoise的输入格式，修改它们不会改变处理行为；
+// 请使用FrameSize和SampleRateHz获取实际的帧格式
+//
+// RNNoise 不是并发安全的：同一实例同时只能被一个goroutine使用，
+// 重叠的调用会返回ErrConcurrentUse而不是破坏降噪状态
 type RNNoise struct {
-	state           *C.RNNoiseState
+	engine          frameEngine
+	guard           useGuard
 	SampleWidth     int // 采样位宽（字节）
 	Channels        int // 声道数
 	SampleRate      int // 采样率
 	FrameDurationMS int // 帧持续时间（毫秒）
+
+	// ProcessFrameInto复用的缓冲区
+	scratchIn  [rnnoiseFrameSize]float32 // 缩放到16位整数范围的输入
+	scratchOut [rnnoiseFrameSize]float32 // 后端输出
+
+	// ProcessFrames复用的缓冲区，按需增长
+	batchIn  []float32
+	batchOut []float32
 }
 
 // NewRNNoise 创建新的RNNoise实例
 //
 // 参数:
 //   - libPath: RNNoise动态库文件路径，如果为空则自动查找
+//   - opts: 可选配置，例如WithModelFile加载自定义模型
 //
 // 返回:
 //   - *RNNoise: RNNoise实例指针
 //   - error: 创建失败时的错误信息
 //
+// 同一路径的动态库在进程内按引用计数共享，销毁某个实例不会影响其他仍在使用该库的实例；
+// 不同路径的库（例如不同模型构建的.so）可以同时加载。
+// 该后端依赖cgo，在CGO_ENABLED=0的构建中会返回错误，此时请使用NewGoRNNoise。
+//
 // 示例:
 //
 //	// 自动查找库文件
@@ -101,8 +75,28 @@ type RNNoise struct {
 //
 //	// 指定库文件路径
 //	rnn, err := NewRNNoise("/path/to/librnnoise.so")
-func NewRNNoise(libPath string) (*RNNoise, error) {
+//
+//	// 使用自己训练的模型
+//	rnn, err := NewRNNoise("", WithModelFile("call_center.rnnn"))
+func NewRNNoise(libPath string, opts ...Option) (*RNNoise, error) {
+	cfg := newConfig(opts)
+	if libPath != "" {
+		cfg.libPath = libPath
+	}
+	return newRNNoiseFromConfig(cfg)
+}
+
+// newRNNoiseFromConfig 根据配置选择后端创建RNNoise实例
+func newRNNoiseFromConfig(cfg *config) (*RNNoise, error) {
+	if cfg.goModel != nil {
+		if cfg.model != nil {
+			return nil, fmt.Errorf("WithGoModel已指定模型，不能同时使用WithModelFile、WithModelReader或WithModelData")
+		}
+		return NewGoRNNoise(cfg.goModel)
+	}
+
 	// 如果没有指定库路径，自动查找
+	libPath := cfg.libPath
 	if libPath == "" {
 		var err error
 		libPath, err = findRNNoiseLib()
@@ -116,55 +110,81 @@ func NewRNNoise(libPath string) (*RNNoise, error) {
 		return nil, fmt.Errorf("RNNoise库文件不存在: %s", libPath)
 	}
 
-	// 动态加载RNNoise库
-	libPathC := C.CString(libPath)
-	defer C.free(unsafe.Pointer(libPathC))
-
-	if C.load_rnnoise_library(libPathC) == 0 {
-		return nil, fmt.Errorf("无法加载RNNoise库: %s", libPath)
+	engine, err := newCgoEngine(libPath, cfg.model)
+	if err != nil {
+		return nil, err
 	}
 
-	// 创建RNNoise状态对象
-	state := C.rnnoise_create(nil)
-	if state == nil {
-		C.unload_rnnoise_library()
-		return nil, fmt.Errorf("无法创建RNNoise状态对象")
-	}
+	return newRNNoise(engine), nil
+}
 
+// newRNNoise 使用指定后端创建RNNoise实例
+func newRNNoise(engine frameEngine) *RNNoise {
 	return &RNNoise{
-		state:           state,
+		engine:          engine,
 		SampleWidth:     2,     // 16位 = 2字节
 		Channels:        1,     // 单声道
 		SampleRate:      48000, // 48kHz
 		FrameDurationMS: 10,    // 10毫秒帧
-	}, nil
+	}
 }
 
 // Destroy 销毁RNNoise实例，释放资源
 //
-// 这个方法会释放RNNoise状态对象和动态库资源
-// 应该在不再使用RNNoise实例时调用此方法
+// 这个方法会释放RNNoise状态对象，并释放对动态库的引用，
+// 当最后一个使用该库的实例销毁时动态库才会被卸载。
+// 应该在不再使用RNNoise实例时调用此方法，重复调用是安全的；
+// 实例正在被其他goroutine使用时不会销毁，需要错误信息时请使用Close
 func (r *RNNoise) Destroy() {
-	if r.state != nil {
-		C.rnnoise_destroy(r.state)
-		r.state = nil
+	_ = r.Close()
+}
+
+// Close 销毁RNNoise实例，实现FrameDenoiser接口
+//
+// 实例正在被其他goroutine使用时返回ErrConcurrentUse
+func (r *RNNoise) Close() error {
+	if err := r.guard.acquire(); err != nil {
+		return err
 	}
-	// 释放动态库（注意：这会影响所有实例，在实际应用中可能需要引用计数）
-	C.unload_rnnoise_library()
+	defer r.guard.release()
+
+	if r.engine == nil {
+		return nil
+	}
+	r.engine.close()
+	r.engine = nil
+	r.batchIn, r.batchOut = nil, nil
+	return nil
+}
+
+// FrameSize 每帧的样本数（480）
+func (r *RNNoise) FrameSize() int {
+	return rnnoiseFrameSize
+}
+
+// SampleRateHz RNNoise要求的输入采样率（48000Hz）
+func (r *RNNoise) SampleRateHz() int {
+	return rnnoiseSampleRate
+}
+
+// Latency RNNoise的固有延迟（480个样本，10ms）
+//
+// 分析窗口覆盖上一帧和当前帧，重叠相加后的输出相对输入延迟一帧
+func (r *RNNoise) Latency() int {
+	return rnnoiseFrameSize
 }
 
 // Reset 重置RNNoise状态，清除神经网络的内部状态
 func (r *RNNoise) Reset() error {
-	if r.state != nil {
-		C.rnnoise_destroy(r.state)
+	if err := r.guard.acquire(); err != nil {
+		return err
 	}
+	defer r.guard.release()
 
-	r.state = C.rnnoise_create(nil)
-	if r.state == nil {
-		return fmt.Errorf("无法重置RNNoise状态对象")
+	if r.engine == nil {
+		return fmt.Errorf("RNNoise实例已销毁")
 	}
-
-	return nil
+	return r.engine.reset()
 }
 
 // ProcessFrame 处理单个音频帧（10毫秒）
@@ -177,86 +197,109 @@ func (r *RNNoise) Reset() error {
 //   - []float32: 降噪后的音频帧（480个样本）
 //   - error: 处理失败时的错误信息
 //
-// 注意: 输入帧必须恰好包含480个样本，对应48kHz采样率下的10毫秒音频
+// 注意: 输入帧必须恰好包含480个样本，对应48kHz采样率下的10毫秒音频。
+// 每次调用都会分配新的输出帧，高频调用时请使用ProcessFrameInto
 func (r *RNNoise) ProcessFrame(frame []float32) (float32, []float32, error) {
-	if len(frame) != 480 {
+	if len(frame) != rnnoiseFrameSize {
 		return 0, nil, fmt.Errorf("帧大小必须为480个样本（10ms @ 48kHz），当前为%d", len(frame))
 	}
 
-	// 将float32样本转换为RNNoise期望的格式（16位整数范围的float）
-	rnnoiseInput := make([]float32, 480)
-	rnnoiseOutput := make([]float32, 480)
+	output := make([]float32, rnnoiseFrameSize)
+	voiceProb, err := r.ProcessFrameInto(output, frame)
+	if err != nil {
+		return 0, nil, err
+	}
+	return voiceProb, output, nil
+}
 
-	for i, sample := range frame {
-		// 将-1.0到1.0范围转换为-32768到32767范围
-		rnnoiseInput[i] = sample * 32768.0
+// ProcessFrameInto 处理单个音频帧，将降噪结果写入dst，不分配内存
+//
+// dst和src都必须恰好包含480个样本，dst可以与src是同一个切片（原地处理）。
+// 内部使用实例自带的缓冲区，同一实例被多个goroutine同时调用时返回ErrConcurrentUse
+//
+// 示例:
+//
+//	frame := make([]float32, 480)
+//	for readFrame(frame) {
+//		prob, err := rnn.ProcessFrameInto(frame, frame)
+//		...
+//	}
+func (r *RNNoise) ProcessFrameInto(dst, src []float32) (float32, error) {
+	if len(src) != rnnoiseFrameSize {
+		return 0, fmt.Errorf("帧大小必须为480个样本（10ms @ 48kHz），当前为%d", len(src))
+	}
+	if len(dst) != rnnoiseFrameSize {
+		return 0, fmt.Errorf("输出缓冲区必须为480个样本，当前为%d", len(dst))
 	}
+	if err := r.guard.acquire(); err != nil {
+		return 0, err
+	}
+	defer r.guard.release()
 
-	// 调用RNNoise处理函数
-	voiceProb := C.rnnoise_process_frame(
-		r.state,
-		(*C.float)(unsafe.Pointer(&rnnoiseOutput[0])),
-		(*C.float)(unsafe.Pointer(&rnnoiseInput[0])),
-	)
-
-	// 将输出转换回-1.0到1.0范围
-	output := make([]float32, 480)
-	for i, sample := range rnnoiseOutput {
-		output[i] = sample / 32768.0
+	if r.engine == nil {
+		return 0, fmt.Errorf("RNNoise实例已销毁")
 	}
 
-	return float32(voiceProb), output, nil
-}
+	// 将-1.0到1.0范围转换为RNNoise期望的16位整数范围（-32768到32767）
+	for i, sample := range src {
+		r.scratchIn[i] = sample * 32768.0
+	}
+
+	voiceProb := r.engine.processFrame(r.scratchOut[:], r.scratchIn[:])
 
-// findRNNoiseLib 自动查找RNNoise库文件
-func findRNNoiseLib() (string, error) {
-	// 根据操作系统确定库文件名
-	var libName string
-	switch runtime.GOOS {
-	case "linux", "darwin":
-		libName = "librnnoise_5h_b_500k.so.0.4.1"
-	case "windows":
-		libName = "librnnoise_5h_b_500k.dll"
-	default:
-		return "", fmt.Errorf("不支持的操作系统: %s", runtime.GOOS)
+	// 输入已经完整复制到scratchIn，此时写入dst不会影响原地处理
+	for i, sample := range r.scratchOut {
+		dst[i] = sample / 32768.0
 	}
 
-	// 优先在lib目录中查找
-	libPath := filepath.Join("lib", libName)
-	if _, err := os.Stat(libPath); err == nil {
-		absPath, err := filepath.Abs(libPath)
-		if err != nil {
-			return "", err
-		}
-		return absPath, nil
+	return voiceProb, nil
+}
+
+// ProcessFrames 批量处理多个连续的音频帧
+//
+// src包含整数个480样本的帧，降噪结果写入同样长度的dst（可以与src相同），
+// 每帧的语音概率依次写入voiceProbs，其长度至少为帧数。
+// cgo后端整批只跨越一次cgo边界，适合离线处理大量帧的场景；
+// 与ProcessFrameInto一样，同一实例不能被多个goroutine同时调用
+func (r *RNNoise) ProcessFrames(dst, src, voiceProbs []float32) error {
+	if len(src)%rnnoiseFrameSize != 0 {
+		return fmt.Errorf("样本数必须为帧大小%d的整数倍，当前为%d", rnnoiseFrameSize, len(src))
+	}
+	if len(dst) != len(src) {
+		return fmt.Errorf("输出缓冲区长度必须与输入相同（%d），当前为%d", len(src), len(dst))
+	}
+	numFrames := len(src) / rnnoiseFrameSize
+	if len(voiceProbs) < numFrames {
+		return fmt.Errorf("语音概率缓冲区至少需要%d个元素，当前为%d", numFrames, len(voiceProbs))
+	}
+	if err := r.guard.acquire(); err != nil {
+		return err
 	}
+	defer r.guard.release()
 
-	// 如果lib目录没有，再在整个项目中搜索
-	var foundPath string
-	err := filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.Name() == libName {
-			foundPath = path
-			return filepath.SkipDir // 找到后停止搜索
-		}
+	if r.engine == nil {
+		return fmt.Errorf("RNNoise实例已销毁")
+	}
+	if numFrames == 0 {
 		return nil
-	})
+	}
 
-	if err != nil {
-		return "", err
+	if cap(r.batchIn) < len(src) {
+		r.batchIn = make([]float32, len(src))
+		r.batchOut = make([]float32, len(src))
 	}
+	in := r.batchIn[:len(src)]
+	out := r.batchOut[:len(src)]
 
-	if foundPath == "" {
-		return "", fmt.Errorf("未找到库文件: %s", libName)
+	for i, sample := range src {
+		in[i] = sample * 32768.0
 	}
 
-	// 返回绝对路径
-	absPath, err := filepath.Abs(foundPath)
-	if err != nil {
-		return "", err
+	r.engine.processFrames(out, in, voiceProbs[:numFrames])
+
+	for i, sample := range out {
+		dst[i] = sample / 32768.0
 	}
 
-	return absPath, nil
+	return nil
 }
@@ -0,0 +1,330 @@
+//go:build cgo
+
+package rnnoise
+
+/*
+#include <stdio.h>
+#include <stdlib.h>
+#include <dlfcn.h>
+
+// RNNoise C函数指针类型定义
+typedef struct RNNoiseState RNNoiseState;
+typedef struct RNNModel RNNModel;
+typedef RNNoiseState* (*rnnoise_create_func)(RNNModel *model);
+typedef void (*rnnoise_destroy_func)(RNNoiseState *st);
+typedef float (*rnnoise_process_frame_func)(RNNoiseState *st, float *out, const float *in);
+
+// 模型加载函数（可选，不同版本的RNNoise导出的符号不同）
+typedef RNNModel* (*rnnoise_model_from_file_func)(FILE *f);
+typedef RNNModel* (*rnnoise_model_from_filename_func)(const char *filename);
+typedef RNNModel* (*rnnoise_model_from_buffer_func)(const void *ptr, int len);
+typedef void (*rnnoise_model_free_func)(RNNModel *model);
+
+// 每个已加载的动态库拥有独立的句柄和函数指针，
+// 不同路径的库可以同时加载而互不覆盖
+typedef struct {
+    void* handle;
+    rnnoise_create_func create;
+    rnnoise_destroy_func destroy;
+    rnnoise_process_frame_func process_frame;
+    rnnoise_model_from_file_func model_from_file;
+    rnnoise_model_from_filename_func model_from_filename;
+    rnnoise_model_from_buffer_func model_from_buffer;
+    rnnoise_model_free_func model_free;
+} rnnoise_library;
+
+// 加载RNNoise库，失败时返回NULL
+static rnnoise_library* load_rnnoise_library(const char* lib_path) {
+    void* handle = dlopen(lib_path, RTLD_LAZY | RTLD_LOCAL);
+    if (!handle) {
+        return NULL;
+    }
+
+    rnnoise_library* lib = (rnnoise_library*)calloc(1, sizeof(rnnoise_library));
+    if (!lib) {
+        dlclose(handle);
+        return NULL;
+    }
+
+    lib->handle = handle;
+    lib->create = (rnnoise_create_func)dlsym(handle, "rnnoise_create");
+    lib->destroy = (rnnoise_destroy_func)dlsym(handle, "rnnoise_destroy");
+    lib->process_frame = (rnnoise_process_frame_func)dlsym(handle, "rnnoise_process_frame");
+
+    if (!lib->create || !lib->destroy || !lib->process_frame) {
+        dlclose(handle);
+        free(lib);
+        return NULL;
+    }
+
+    lib->model_from_file = (rnnoise_model_from_file_func)dlsym(handle, "rnnoise_model_from_file");
+    lib->model_from_filename = (rnnoise_model_from_filename_func)dlsym(handle, "rnnoise_model_from_filename");
+    lib->model_from_buffer = (rnnoise_model_from_buffer_func)dlsym(handle, "rnnoise_model_from_buffer");
+    lib->model_free = (rnnoise_model_free_func)dlsym(handle, "rnnoise_model_free");
+
+    return lib;
+}
+
+// 释放库
+static void unload_rnnoise_library(rnnoise_library* lib) {
+    if (lib) {
+        dlclose(lib->handle);
+        free(lib);
+    }
+}
+
+// 包装函数
+static RNNoiseState* rnnoise_lib_create(rnnoise_library* lib, RNNModel *model) {
+    return lib->create(model);
+}
+
+// 从文件加载模型，优先使用rnnoise_model_from_filename
+static RNNModel* rnnoise_lib_model_from_filename(rnnoise_library* lib, const char* filename) {
+    if (lib->model_from_filename) {
+        return lib->model_from_filename(filename);
+    }
+    if (lib->model_from_file) {
+        FILE* f = fopen(filename, "rb");
+        if (!f) {
+            return NULL;
+        }
+        RNNModel* model = lib->model_from_file(f);
+        fclose(f);
+        return model;
+    }
+    return NULL;
+}
+
+// 从内存加载模型，缓冲区必须在模型释放前保持有效
+static RNNModel* rnnoise_lib_model_from_buffer(rnnoise_library* lib, const void* data, int len) {
+    if (lib->model_from_buffer) {
+        return lib->model_from_buffer(data, len);
+    }
+    if (lib->model_from_file) {
+        FILE* f = fmemopen((void*)data, len, "rb");
+        if (!f) {
+            return NULL;
+        }
+        RNNModel* model = lib->model_from_file(f);
+        fclose(f);
+        return model;
+    }
+    return NULL;
+}
+
+static int rnnoise_lib_supports_models(rnnoise_library* lib) {
+    return lib->model_from_file || lib->model_from_filename || lib->model_from_buffer;
+}
+
+static void rnnoise_lib_model_free(rnnoise_library* lib, RNNModel *model) {
+    if (lib->model_free && model) {
+        lib->model_free(model);
+    }
+}
+
+static void rnnoise_lib_destroy(rnnoise_library* lib, RNNoiseState *st) {
+    lib->destroy(st);
+}
+
+static float rnnoise_lib_process_frame(rnnoise_library* lib, RNNoiseState *st, float *out, const float *in) {
+    return lib->process_frame(st, out, in);
+}
+
+// 批量处理n个连续帧，语音概率写入probs，整批只需要一次cgo调用
+static void rnnoise_lib_process_frames(rnnoise_library* lib, RNNoiseState *st, float *out, const float *in,
+                                       int n, int frame_size, float *probs) {
+    for (int i = 0; i < n; i++) {
+        probs[i] = lib->process_frame(st, out + i * frame_size, in + i * frame_size);
+    }
+}
+*/
+import "C"
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"unsafe"
+)
+
+// library 已加载的RNNoise动态库，按路径引用计数
+//
+// 同一路径的库在进程内只加载一次，由所有使用它的RNNoise实例共享，
+// 直到最后一个实例销毁时才真正卸载
+type library struct {
+	path string
+	lib  *C.rnnoise_library
+	refs int
+}
+
+var (
+	librariesMu sync.Mutex
+	libraries   = make(map[string]*library)
+)
+
+// acquireLibrary 获取指定路径的动态库，必要时加载并增加引用计数
+func acquireLibrary(libPath string) (*library, error) {
+	key, err := libraryKey(libPath)
+	if err != nil {
+		return nil, err
+	}
+
+	librariesMu.Lock()
+	defer librariesMu.Unlock()
+
+	if l, ok := libraries[key]; ok {
+		l.refs++
+		return l, nil
+	}
+
+	libPathC := C.CString(key)
+	defer C.free(unsafe.Pointer(libPathC))
+
+	handle := C.load_rnnoise_library(libPathC)
+	if handle == nil {
+		return nil, fmt.Errorf("无法加载RNNoise库: %s", libPath)
+	}
+
+	l := &library{path: key, lib: handle, refs: 1}
+	libraries[key] = l
+	return l, nil
+}
+
+// release 减少引用计数，最后一个引用释放时卸载动态库
+func (l *library) release() {
+	librariesMu.Lock()
+	defer librariesMu.Unlock()
+
+	l.refs--
+	if l.refs > 0 {
+		return
+	}
+
+	delete(libraries, l.path)
+	C.unload_rnnoise_library(l.lib)
+	l.lib = nil
+}
+
+// libraryKey 将库路径规范化为注册表的键，保证同一文件的不同写法共享一个句柄
+func libraryKey(libPath string) (string, error) {
+	absPath, err := filepath.Abs(libPath)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = resolved
+	}
+	return absPath, nil
+}
+
+// cgoEngine 通过cgo调用RNNoise动态库的后端
+type cgoEngine struct {
+	lib       *library
+	state     *C.RNNoiseState
+	model     *C.RNNModel    // 自定义模型，nil表示使用内置权重
+	modelData unsafe.Pointer // 模型数据的C副本，与模型同生命周期
+}
+
+// newCgoEngine 加载动态库（已加载时只增加引用计数）并创建RNNoise状态对象
+func newCgoEngine(libPath string, model *modelSource) (frameEngine, error) {
+	lib, err := acquireLibrary(libPath)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &cgoEngine{lib: lib}
+	if model != nil {
+		if err := e.loadModel(model); err != nil {
+			e.close()
+			return nil, err
+		}
+	}
+
+	e.state = C.rnnoise_lib_create(lib.lib, e.model)
+	if e.state == nil {
+		e.close()
+		return nil, fmt.Errorf("无法创建RNNoise状态对象")
+	}
+
+	return e, nil
+}
+
+// loadModel 通过动态库的模型加载函数读取自定义模型
+func (e *cgoEngine) loadModel(model *modelSource) error {
+	if C.rnnoise_lib_supports_models(e.lib.lib) == 0 {
+		return fmt.Errorf("RNNoise库不支持加载自定义模型: %s", e.lib.path)
+	}
+
+	path, data, err := model.load()
+	if err != nil {
+		return err
+	}
+
+	if path != "" {
+		pathC := C.CString(path)
+		defer C.free(unsafe.Pointer(pathC))
+		e.model = C.rnnoise_lib_model_from_filename(e.lib.lib, pathC)
+	} else {
+		e.modelData = C.CBytes(data)
+		e.model = C.rnnoise_lib_model_from_buffer(e.lib.lib, e.modelData, C.int(len(data)))
+	}
+
+	if e.model == nil {
+		return fmt.Errorf("无法加载RNNoise模型")
+	}
+	return nil
+}
+
+func (e *cgoEngine) processFrame(out, in []float32) float32 {
+	return float32(C.rnnoise_lib_process_frame(
+		e.lib.lib,
+		e.state,
+		(*C.float)(unsafe.Pointer(&out[0])),
+		(*C.float)(unsafe.Pointer(&in[0])),
+	))
+}
+
+func (e *cgoEngine) processFrames(out, in, probs []float32) {
+	if len(probs) == 0 {
+		return
+	}
+	C.rnnoise_lib_process_frames(
+		e.lib.lib,
+		e.state,
+		(*C.float)(unsafe.Pointer(&out[0])),
+		(*C.float)(unsafe.Pointer(&in[0])),
+		C.int(len(probs)),
+		C.int(rnnoiseFrameSize),
+		(*C.float)(unsafe.Pointer(&probs[0])),
+	)
+}
+
+// reset 创建新的状态对象替换旧的，创建失败时保留旧的状态对象，实例仍然可用
+func (e *cgoEngine) reset() error {
+	state := C.rnnoise_lib_create(e.lib.lib, e.model)
+	if state == nil {
+		return fmt.Errorf("无法重置RNNoise状态对象")
+	}
+
+	if e.state != nil {
+		C.rnnoise_lib_destroy(e.lib.lib, e.state)
+	}
+	e.state = state
+	return nil
+}
+
+// close 依次释放状态对象、模型和动态库引用，模型必须在状态对象之后释放
+func (e *cgoEngine) close() {
+	if e.state != nil {
+		C.rnnoise_lib_destroy(e.lib.lib, e.state)
+		e.state = nil
+	}
+	if e.model != nil {
+		C.rnnoise_lib_model_free(e.lib.lib, e.model)
+		e.model = nil
+	}
+	if e.modelData != nil {
+		C.free(e.modelData)
+		e.modelData = nil
+	}
+	e.lib.release()
+}
@@ -0,0 +1,166 @@
+//go:build cgo
+
+package rnnoise
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLibraryKey(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "librnnoise.so")
+	if err := os.WriteFile(target, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "librnnoise.so.0")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+
+	want, err := libraryKey(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{link, filepath.Join(dir, ".", "sub", "..", "librnnoise.so")} {
+		got, err := libraryKey(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("libraryKey(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+func TestAcquireLibraryInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "librnnoise.so")
+	if err := os.WriteFile(path, []byte("not a shared library"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := acquireLibrary(path); err == nil {
+		t.Fatal("acquireLibrary() expected error for invalid library")
+	}
+
+	key, _ := libraryKey(path)
+	librariesMu.Lock()
+	_, ok := libraries[key]
+	librariesMu.Unlock()
+	if ok {
+		t.Error("failed load must not be registered")
+	}
+}
+
+// registeredLibrary 返回注册表中指定路径的库
+func registeredLibrary(t *testing.T, libPath string) (*library, bool) {
+	t.Helper()
+	key, err := libraryKey(libPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	librariesMu.Lock()
+	defer librariesMu.Unlock()
+	l, ok := libraries[key]
+	return l, ok
+}
+
+func TestLibraryReferenceCounting(t *testing.T) {
+	libPath := requireRNNoiseLib(t)
+	if _, ok := registeredLibrary(t, libPath); ok {
+		t.Fatal("library still registered before the test")
+	}
+
+	a, err := NewRNNoise(libPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Destroy()
+	b, err := NewRNNoise(libPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Destroy()
+
+	// 两个实例共享同一个注册项
+	l, ok := registeredLibrary(t, libPath)
+	if !ok || l.refs != 2 {
+		t.Fatalf("registry entry after opening two instances = %v, want refs 2", l)
+	}
+	if a.engine.(*cgoEngine).lib != l || b.engine.(*cgoEngine).lib != l {
+		t.Error("instances on the same path should share the registered library")
+	}
+
+	// 销毁其中一个不影响另一个
+	a.Destroy()
+	if l.refs != 1 {
+		t.Errorf("refs after destroying one instance = %d, want 1", l.refs)
+	}
+	if _, _, err := b.ProcessFrame(testFrame(0)); err != nil {
+		t.Errorf("remaining instance failed to process a frame: %v", err)
+	}
+
+	b.Destroy()
+	if _, ok := registeredLibrary(t, libPath); ok {
+		t.Error("library still registered after destroying both instances")
+	}
+}
+
+func TestCgoProcessFrameIntoDoesNotAllocate(t *testing.T) {
+	rnn, err := NewRNNoise(requireRNNoiseLib(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rnn.Destroy()
+
+	frame := testFrame(0)
+	if allocs := testing.AllocsPerRun(100, func() {
+		_, _ = rnn.ProcessFrameInto(frame, frame)
+	}); allocs != 0 {
+		t.Errorf("ProcessFrameInto allocates %.1f times per frame, want 0", allocs)
+	}
+}
+
+func TestCgoProcessFramesMatchesProcessFrame(t *testing.T) {
+	libPath := requireRNNoiseLib(t)
+	a, err := NewRNNoise(libPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Destroy()
+	b, err := NewRNNoise(libPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Destroy()
+
+	const numFrames = 6
+	src := make([]float32, 0, numFrames*rnnoiseFrameSize)
+	for n := 0; n < numFrames; n++ {
+		src = append(src, testFrame(n)...)
+	}
+
+	// 批量处理整段，一次cgo调用完成
+	dst := make([]float32, len(src))
+	probs := make([]float32, numFrames)
+	if err := b.ProcessFrames(dst, src, probs); err != nil {
+		t.Fatal(err)
+	}
+
+	for n := 0; n < numFrames; n++ {
+		wantProb, want, err := a.ProcessFrame(src[n*rnnoiseFrameSize : (n+1)*rnnoiseFrameSize])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if probs[n] != wantProb {
+			t.Fatalf("frame %d: voice probability %f, want %f", n, probs[n], wantProb)
+		}
+		for i, v := range want {
+			if dst[n*rnnoiseFrameSize+i] != v {
+				t.Fatalf("frame %d sample %d: %f, want %f", n, i, dst[n*rnnoiseFrameSize+i], v)
+			}
+		}
+	}
+}
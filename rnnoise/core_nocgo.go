@@ -0,0 +1,10 @@
+//go:build !cgo
+
+package rnnoise
+
+import "fmt"
+
+// newCgoEngine 在未启用cgo的构建中不可用
+func newCgoEngine(libPath string, model *modelSource) (frameEngine, error) {
+	return nil, fmt.Errorf("当前构建未启用cgo，无法加载RNNoise库: %s（请使用NewGoRNNoise）", libPath)
+}
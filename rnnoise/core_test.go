@@ -0,0 +1,152 @@
+package rnnoise
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func testFrame(n int) []float32 {
+	frame := make([]float32, rnnoiseFrameSize)
+	for i := range frame {
+		frame[i] = float32(0.3 * math.Sin(2*math.Pi*220*float64(n*rnnoiseFrameSize+i)/rnnoiseSampleRate))
+	}
+	return frame
+}
+
+// requireRNNoiseLib 返回可以加载的RNNoise动态库路径，找不到或无法加载时跳过测试
+func requireRNNoiseLib(t *testing.T) string {
+	t.Helper()
+	path, err := findRNNoiseLib()
+	if err == nil {
+		var rnn *RNNoise
+		if rnn, err = NewRNNoise(path); err == nil {
+			rnn.Destroy()
+			return path
+		}
+	}
+	t.Skipf("RNNoise库不可用: %v", err)
+	return ""
+}
+
+func TestProcessFrameIntoMatchesProcessFrame(t *testing.T) {
+	model := mustSyntheticModel(t)
+	a, err := NewGoRNNoise(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Destroy()
+	b, err := NewGoRNNoise(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Destroy()
+
+	for n := 0; n < 10; n++ {
+		frame := testFrame(n)
+		wantProb, want, err := a.ProcessFrame(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 原地处理
+		gotProb, err := b.ProcessFrameInto(frame, frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotProb != wantProb {
+			t.Fatalf("frame %d: voice probability %f, want %f", n, gotProb, wantProb)
+		}
+		for i := range want {
+			if frame[i] != want[i] {
+				t.Fatalf("frame %d sample %d: %f, want %f", n, i, frame[i], want[i])
+			}
+		}
+	}
+
+	if _, err := b.ProcessFrameInto(make([]float32, 100), testFrame(0)); err == nil {
+		t.Error("expected error for short dst")
+	}
+	if _, err := b.ProcessFrameInto(testFrame(0), make([]float32, 100)); err == nil {
+		t.Error("expected error for short src")
+	}
+	b.Destroy()
+	if _, err := b.ProcessFrameInto(testFrame(0), testFrame(0)); err == nil {
+		t.Error("expected error after Destroy")
+	}
+}
+
+func TestProcessFramesMatchesProcessFrame(t *testing.T) {
+	model := mustSyntheticModel(t)
+	a, err := NewGoRNNoise(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Destroy()
+	b, err := NewGoRNNoise(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Destroy()
+
+	const numFrames = 6
+	src := make([]float32, 0, numFrames*rnnoiseFrameSize)
+	for n := 0; n < numFrames; n++ {
+		src = append(src, testFrame(n)...)
+	}
+
+	dst := make([]float32, len(src))
+	probs := make([]float32, numFrames)
+	if err := b.ProcessFrames(dst, src, probs); err != nil {
+		t.Fatal(err)
+	}
+
+	for n := 0; n < numFrames; n++ {
+		wantProb, want, err := a.ProcessFrame(src[n*rnnoiseFrameSize : (n+1)*rnnoiseFrameSize])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if probs[n] != wantProb {
+			t.Fatalf("frame %d: voice probability %f, want %f", n, probs[n], wantProb)
+		}
+		for i, v := range want {
+			if dst[n*rnnoiseFrameSize+i] != v {
+				t.Fatalf("frame %d sample %d: %f, want %f", n, i, dst[n*rnnoiseFrameSize+i], v)
+			}
+		}
+	}
+
+	if err := b.ProcessFrames(dst[:100], src[:100], probs); err == nil {
+		t.Error("expected error for partial frame")
+	}
+	if err := b.ProcessFrames(dst, src, probs[:1]); err == nil {
+		t.Error("expected error for short voiceProbs")
+	}
+}
+
+func BenchmarkProcessFrameInto(b *testing.B) {
+	model, err := LoadGoModel(strings.NewReader(syntheticModelText(0)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	rnn, err := NewGoRNNoise(model)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer rnn.Destroy()
+
+	frame := testFrame(0)
+	if allocs := testing.AllocsPerRun(100, func() {
+		_, _ = rnn.ProcessFrameInto(frame, frame)
+	}); allocs != 0 {
+		b.Fatalf("ProcessFrameInto allocates %.1f times per frame, want 0", allocs)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := rnn.ProcessFrameInto(frame, frame); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package rnnoise
+
+import (
+	"fmt"
+	"io"
+)
+
+// denoiseIOChunkFrames DenoiseReader每次从源读取的采样帧数（48kHz下约85ms）
+const denoiseIOChunkFrames = 4096
+
+// DenoiseReader 边读边降噪的io.Reader
+//
+// 从源读取PCM数据，经StreamFilter降噪后以相同格式输出，内存占用与音频总长度无关。
+// format可以是PCMFormat支持的任意编码，例如G.711 µ-law。
+// 多声道输入会先混合为单声道降噪，输出时每个声道写入相同的降噪结果。
+// 源数据读完（io.EOF）后会输出缓存的尾部，然后返回io.EOF
+//
+// 示例:
+//
+//	format := PCMFormat{SampleRate: 16000, Channels: 1, BitDepth: 16}
+//	reader, err := NewDenoiseReader(os.Stdin, format)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer reader.Close()
+//	io.Copy(os.Stdout, reader)
+type DenoiseReader struct {
+	src    io.Reader
+	format PCMFormat
+	filter *StreamFilter
+	in     []byte // 从源读取的数据，可能包含不完整的采样帧
+	out    []byte // 已降噪、等待被读取的数据
+	eof    bool
+	err    error
+}
+
+// NewDenoiseReader 创建从r读取format格式PCM数据的降噪Reader
+//
+// opts与NewNoiseFilter相同，例如WithThreshold、WithOutputPolicy；
+// 使用完毕后调用Close释放降噪后端（不会关闭r）
+func NewDenoiseReader(r io.Reader, format PCMFormat, opts ...Option) (*DenoiseReader, error) {
+	if r == nil {
+		return nil, fmt.Errorf("源Reader不能为空")
+	}
+	if err := format.validate(); err != nil {
+		return nil, err
+	}
+
+	filter, err := NewStreamFilter(format.SampleRate, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DenoiseReader{
+		src:    r,
+		format: format,
+		filter: filter,
+		in:     make([]byte, 0, denoiseIOChunkFrames*format.bytesPerFrame()),
+	}, nil
+}
+
+// Read 读取降噪后的PCM数据
+func (dr *DenoiseReader) Read(p []byte) (int, error) {
+	for len(dr.out) == 0 {
+		if dr.err != nil {
+			return 0, dr.err
+		}
+		if dr.eof {
+			return 0, io.EOF
+		}
+		dr.fill()
+	}
+
+	n := copy(p, dr.out)
+	dr.out = dr.out[n:]
+	return n, nil
+}
+
+// fill 从源读取一块数据并降噪，源结束时输出缓存的尾部
+func (dr *DenoiseReader) fill() {
+	n, err := dr.src.Read(dr.in[len(dr.in):cap(dr.in)])
+	dr.in = dr.in[:len(dr.in)+n]
+
+	// 只处理完整的采样帧，剩余字节留到下一次
+	whole := len(dr.in) / dr.format.bytesPerFrame() * dr.format.bytesPerFrame()
+	if whole > 0 {
+		output, _, procErr := dr.filter.Process(dr.format.decode(dr.in[:whole]))
+		if procErr != nil {
+			dr.err = procErr
+			return
+		}
+		dr.out = append(dr.out, dr.format.encode(output)...)
+		dr.in = dr.in[:copy(dr.in, dr.in[whole:])]
+	}
+
+	switch {
+	case err == io.EOF:
+		dr.eof = true
+		tail, _, flushErr := dr.filter.Flush()
+		if flushErr != nil {
+			dr.err = flushErr
+			return
+		}
+		dr.out = append(dr.out, dr.format.encode(tail)...)
+	case err != nil:
+		dr.err = err
+	}
+}
+
+// Close 释放降噪后端，不会关闭源Reader
+func (dr *DenoiseReader) Close() error {
+	dr.filter.Destroy()
+	return nil
+}
+
+// DenoiseWriter 边写边降噪的io.WriteCloser
+//
+// 写入的PCM数据经StreamFilter降噪后以相同格式写入下游Writer，内存占用与音频总长度无关。
+// 多声道输入会先混合为单声道降噪，输出时每个声道写入相同的降噪结果。
+// 写入结束后必须调用Close，以输出缓存的尾部
+//
+// 示例:
+//
+//	format := PCMFormat{SampleRate: 8000, Channels: 1, BitDepth: 16}
+//	writer, err := NewDenoiseWriter(conn, format, WithThreshold(0.3))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	io.Copy(writer, resp.Body)
+//	writer.Close()
+type DenoiseWriter struct {
+	dst    io.Writer
+	format PCMFormat
+	filter *StreamFilter
+	in     []byte // 不完整的采样帧
+	closed bool
+}
+
+// NewDenoiseWriter 创建将降噪后的format格式PCM数据写入w的Writer
+//
+// opts与NewNoiseFilter相同；Close会输出缓存的尾部并释放降噪后端（不会关闭w）
+func NewDenoiseWriter(w io.Writer, format PCMFormat, opts ...Option) (*DenoiseWriter, error) {
+	if w == nil {
+		return nil, fmt.Errorf("目标Writer不能为空")
+	}
+	if err := format.validate(); err != nil {
+		return nil, err
+	}
+
+	filter, err := NewStreamFilter(format.SampleRate, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DenoiseWriter{dst: w, format: format, filter: filter}, nil
+}
+
+// Write 写入PCM数据，凑满一帧的部分会立即降噪并写入下游
+//
+// 返回值n表示p中已被接收的字节数，不完整的采样帧会缓存到下一次Write
+func (dw *DenoiseWriter) Write(p []byte) (int, error) {
+	if dw.closed {
+		return 0, fmt.Errorf("DenoiseWriter已关闭")
+	}
+
+	dw.in = append(dw.in, p...)
+	whole := len(dw.in) / dw.format.bytesPerFrame() * dw.format.bytesPerFrame()
+	if whole == 0 {
+		return len(p), nil
+	}
+
+	output, _, err := dw.filter.Process(dw.format.decode(dw.in[:whole]))
+	dw.in = dw.in[:copy(dw.in, dw.in[whole:])]
+	if err != nil {
+		return 0, err
+	}
+	if _, err := dw.dst.Write(dw.format.encode(output)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close 输出缓存的尾部并释放降噪后端，不会关闭下游Writer；重复调用是安全的
+//
+// 结尾不完整的采样帧会被丢弃
+func (dw *DenoiseWriter) Close() error {
+	if dw.closed {
+		return nil
+	}
+	dw.closed = true
+	defer dw.filter.Destroy()
+
+	tail, _, err := dw.filter.Flush()
+	if err != nil {
+		return err
+	}
+	if _, err := dw.dst.Write(dw.format.encode(tail)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package rnnoise
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func pcm16(samples []float32, channels int) []byte {
+	var buf bytes.Buffer
+	for _, sample := range samples {
+		for ch := 0; ch < channels; ch++ {
+			binary.Write(&buf, binary.LittleEndian, int16(sample*32767))
+		}
+	}
+	return buf.Bytes()
+}
+
+// assertPCM16Close 比较两段16位PCM，首尾各edge字节受重采样滤波器边界影响，不参与比较
+func assertPCM16Close(t *testing.T, got, want []byte, edge int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("output length = %d bytes, want %d", len(got), len(want))
+	}
+	for i := edge; i+1 < len(want)-edge; i += 2 {
+		g := int16(binary.LittleEndian.Uint16(got[i:]))
+		w := int16(binary.LittleEndian.Uint16(want[i:]))
+		if d := int(g) - int(w); d > 2 || d < -2 {
+			t.Fatalf("sample at byte %d = %d, want %d", i, g, w)
+		}
+	}
+}
+
+func TestDenoiseReader(t *testing.T) {
+	format := PCMFormat{SampleRate: 8000, Channels: 2, BitDepth: 16}
+	input := pcm16(sineSamples(8000+77, 8000, 250), 2)
+
+	// OneByteReader迫使Reader处理不完整的采样帧
+	reader, err := NewDenoiseReader(iotest.OneByteReader(bytes.NewReader(input)), format, WithDenoiser(NewFakeDenoiser(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPCM16Close(t, output, input, 64*4)
+}
+
+func TestDenoiseWriter(t *testing.T) {
+	format := PCMFormat{SampleRate: 16000, Channels: 1, BitDepth: 16}
+	input := pcm16(sineSamples(16000+5, 16000, 440), 1)
+
+	var out bytes.Buffer
+	writer, err := NewDenoiseWriter(&out, format, WithDenoiser(NewFakeDenoiser(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 分块大小为奇数，样本会跨越两次Write
+	for pos := 0; pos < len(input); pos += 333 {
+		end := pos + 333
+		if end > len(input) {
+			end = len(input)
+		}
+		if n, err := writer.Write(input[pos:end]); err != nil || n != end-pos {
+			t.Fatalf("Write() = %d, %v", n, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertPCM16Close(t, out.Bytes(), input, 64*2)
+
+	if _, err := writer.Write(input[:2]); err == nil {
+		t.Error("Write() after Close expected error")
+	}
+}
+
+func TestDenoiseReaderInvalidFormat(t *testing.T) {
+	if _, err := NewDenoiseReader(bytes.NewReader(nil), PCMFormat{SampleRate: 8000, Channels: 1, BitDepth: 12}); err == nil {
+		t.Error("expected error for unsupported bit depth")
+	}
+	if _, err := NewDenoiseWriter(io.Discard, PCMFormat{Channels: 1, BitDepth: 16}); err == nil {
+		t.Error("expected error for zero sample rate")
+	}
+}
@@ -0,0 +1,171 @@
+package rnnoise
+
+import (
+	"fmt"
+	"math"
+)
+
+// FrameDenoiser 逐帧降噪器接口
+//
+// NoiseFilter 和 AudioProcessor 通过该接口调用降噪后端，
+// RNNoise（cgo或纯Go后端）、FakeDenoiser 以及自定义实现都可以接入。
+type FrameDenoiser interface {
+	// FrameSize 每帧的样本数
+	FrameSize() int
+	// SampleRateHz 降噪器要求的输入采样率（Hz）
+	SampleRateHz() int
+	// ProcessFrame 处理一帧音频，返回语音概率和降噪后的帧
+	ProcessFrame(frame []float32) (float32, []float32, error)
+	// Reset 清除内部状态
+	Reset() error
+	// Close 释放资源，之后不能再使用
+	Close() error
+}
+
+// BatchDenoiser 支持一次处理多个连续帧的降噪后端
+//
+// NoiseFilter 的离线处理路径（FilterAudio、AnalyzeFrames）会自动使用该接口，
+// 以减少逐帧调用的开销（例如cgo边界跨越）
+type BatchDenoiser interface {
+	FrameDenoiser
+	// ProcessFrames 处理src中整数个连续帧，结果写入dst，每帧的语音概率写入voiceProbs
+	ProcessFrames(dst, src, voiceProbs []float32) error
+}
+
+// LatencyReporter 报告固有算法延迟的降噪后端
+//
+// WithLatencyCompensation 根据该延迟将输出提前，使降噪结果与输入逐样本对齐
+type LatencyReporter interface {
+	// Latency 输出相对输入延迟的样本数（降噪后端采样率）
+	Latency() int
+}
+
+var _ BatchDenoiser = (*RNNoise)(nil)
+var _ BatchDenoiser = (*FakeDenoiser)(nil)
+var _ LatencyReporter = (*RNNoise)(nil)
+var _ LatencyReporter = (*FakeDenoiser)(nil)
+
+// FakeDenoiser 确定性的内存降噪器，供下游单元测试使用
+//
+// FakeDenoiser 不做真正的降噪：输出为输入乘以 Gain，
+// 语音概率依次取自 VoiceProbs（用完后循环），
+// VoiceProbs 为空时根据帧的RMS能量计算（RMS达到0.1时概率为1）。
+// Delay 大于0时输出相对输入延迟Delay个样本，模拟真实后端的算法延迟。
+type FakeDenoiser struct {
+	VoiceProbs []float32 // 依次返回的语音概率
+	Gain       float32   // 输出增益
+	Delay      int       // 输出延迟的样本数，通过Latency报告
+	Frames     int       // 已处理的帧数
+	Batches    int       // ProcessFrames 调用次数
+	Resets     int       // Reset 调用次数
+	Closed     bool      // 是否已关闭
+
+	frameSize  int
+	sampleRate int
+	delayLine  []float32 // 最近Delay个输入样本
+}
+
+// NewFakeDenoiser 创建与RNNoise帧格式（480样本 @ 48kHz）一致的假降噪器
+//
+// 示例:
+//
+//	fake := NewFakeDenoiser(0.9, 0.1)
+//	filter, err := NewNoiseFilterWithDenoiser(fake)
+func NewFakeDenoiser(voiceProbs ...float32) *FakeDenoiser {
+	return &FakeDenoiser{
+		VoiceProbs: voiceProbs,
+		Gain:       1,
+		frameSize:  rnnoiseFrameSize,
+		sampleRate: rnnoiseSampleRate,
+	}
+}
+
+// FrameSize 每帧的样本数
+func (f *FakeDenoiser) FrameSize() int {
+	return f.frameSize
+}
+
+// SampleRateHz 输入采样率
+func (f *FakeDenoiser) SampleRateHz() int {
+	return f.sampleRate
+}
+
+// ProcessFrame 按 Gain 缩放输入帧并返回预设的语音概率
+func (f *FakeDenoiser) ProcessFrame(frame []float32) (float32, []float32, error) {
+	if f.Closed {
+		return 0, nil, fmt.Errorf("FakeDenoiser已关闭")
+	}
+	if len(frame) != f.frameSize {
+		return 0, nil, fmt.Errorf("帧大小必须为%d个样本，当前为%d", f.frameSize, len(frame))
+	}
+
+	output := make([]float32, len(frame))
+	return f.processInto(output, frame), output, nil
+}
+
+// ProcessFrames 逐帧调用与ProcessFrame相同的逻辑，并记录批量调用次数
+func (f *FakeDenoiser) ProcessFrames(dst, src, voiceProbs []float32) error {
+	if f.Closed {
+		return fmt.Errorf("FakeDenoiser已关闭")
+	}
+	if len(src)%f.frameSize != 0 || len(dst) != len(src) || len(voiceProbs) < len(src)/f.frameSize {
+		return fmt.Errorf("批量处理的缓冲区大小不匹配")
+	}
+
+	for i := 0; i < len(src)/f.frameSize; i++ {
+		start := i * f.frameSize
+		voiceProbs[i] = f.processInto(dst[start:start+f.frameSize], src[start:start+f.frameSize])
+	}
+	f.Batches++
+	return nil
+}
+
+// processInto 处理一帧，dst可以与frame相同
+func (f *FakeDenoiser) processInto(dst, frame []float32) float32 {
+	var prob float32
+	if len(f.VoiceProbs) > 0 {
+		prob = f.VoiceProbs[f.Frames%len(f.VoiceProbs)]
+	} else {
+		var energy float64
+		for _, sample := range frame {
+			energy += float64(sample) * float64(sample)
+		}
+		prob = float32(math.Min(1, math.Sqrt(energy/float64(len(frame)))/0.1))
+	}
+
+	if f.Delay > 0 {
+		// 输出为Delay个样本之前的输入
+		if len(f.delayLine) != f.Delay {
+			f.delayLine = make([]float32, f.Delay)
+		}
+		buf := append(append(make([]float32, 0, f.Delay+len(frame)), f.delayLine...), frame...)
+		copy(f.delayLine, buf[len(frame):])
+		frame = buf[:len(frame)]
+	}
+
+	for i, sample := range frame {
+		dst[i] = sample * f.Gain
+	}
+
+	f.Frames++
+	return prob
+}
+
+// Latency 输出延迟的样本数，即Delay
+func (f *FakeDenoiser) Latency() int {
+	return f.Delay
+}
+
+// Reset 清零已处理帧数和延迟线，语音概率序列从头开始
+func (f *FakeDenoiser) Reset() error {
+	f.Frames = 0
+	f.delayLine = nil
+	f.Resets++
+	return nil
+}
+
+// Close 标记为已关闭
+func (f *FakeDenoiser) Close() error {
+	f.Closed = true
+	return nil
+}
@@ -0,0 +1,193 @@
+package rnnoise
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// LibPathEnv 指定RNNoise动态库位置的环境变量，可以是库文件路径或所在目录
+const LibPathEnv = "RNNOISE_LIB_PATH"
+
+// bundledLibName 随项目发布的库文件名
+func bundledLibName() string {
+	if runtime.GOOS == "windows" {
+		return "librnnoise_5h_b_500k.dll"
+	}
+	return "librnnoise_5h_b_500k.so.0.4.1"
+}
+
+// libNames 按优先级排列的库文件名，包括随项目发布的文件名和系统安装的通用文件名
+func libNames() []string {
+	names := []string{bundledLibName()}
+	switch runtime.GOOS {
+	case "darwin":
+		names = append(names, "librnnoise.dylib", "librnnoise.0.dylib", "librnnoise.so", "librnnoise.so.0")
+	case "windows":
+		names = append(names, "rnnoise.dll", "librnnoise.dll", "librnnoise-0.dll")
+	default:
+		names = append(names, "librnnoise.so", "librnnoise.so.0")
+	}
+	return names
+}
+
+// libSearchDirs 按优先级排列的搜索目录（不包括RNNOISE_LIB_PATH）
+//
+// 依次为：当前目录下的lib、可执行文件所在目录及其lib子目录、
+// LD_LIBRARY_PATH（macOS为DYLD_LIBRARY_PATH，Windows为PATH）、
+// ld.so.conf中配置的目录以及系统标准库目录
+func libSearchDirs() []string {
+	dirs := []string{"lib"}
+
+	if exe, err := os.Executable(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		exeDir := filepath.Dir(exe)
+		dirs = append(dirs, exeDir, filepath.Join(exeDir, "lib"), filepath.Join(exeDir, "..", "lib"))
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		dirs = append(dirs, filepath.SplitList(os.Getenv("DYLD_LIBRARY_PATH"))...)
+		dirs = append(dirs, "/opt/homebrew/lib", "/usr/local/lib", "/usr/lib")
+	case "windows":
+		dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+	default:
+		dirs = append(dirs, filepath.SplitList(os.Getenv("LD_LIBRARY_PATH"))...)
+		dirs = append(dirs, ldconfigDirs("/etc/ld.so.conf", 0)...)
+		dirs = append(dirs, "/usr/local/lib", "/usr/local/lib64", "/usr/lib", "/usr/lib64", "/lib", "/lib64")
+		if triplet := multiarchTriplet(); triplet != "" {
+			dirs = append(dirs, filepath.Join("/usr/lib", triplet), filepath.Join("/lib", triplet))
+		}
+	}
+
+	return dedupDirs(dirs)
+}
+
+// ldconfigDirs 解析ld.so.conf，展开include指令
+func ldconfigDirs(confPath string, depth int) []string {
+	if depth > 4 {
+		return nil
+	}
+	file, err := os.Open(confPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var dirs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "include") {
+			pattern := strings.TrimSpace(strings.TrimPrefix(line, "include"))
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(filepath.Dir(confPath), pattern)
+			}
+			matches, _ := filepath.Glob(pattern)
+			for _, match := range matches {
+				dirs = append(dirs, ldconfigDirs(match, depth+1)...)
+			}
+			continue
+		}
+		dirs = append(dirs, line)
+	}
+	return dirs
+}
+
+// multiarchTriplet Debian系发行版的多架构库目录名
+func multiarchTriplet() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x86_64-linux-gnu"
+	case "arm64":
+		return "aarch64-linux-gnu"
+	case "386":
+		return "i386-linux-gnu"
+	case "arm":
+		return "arm-linux-gnueabihf"
+	default:
+		return ""
+	}
+}
+
+func dedupDirs(dirs []string) []string {
+	seen := make(map[string]bool, len(dirs))
+	result := dirs[:0]
+	for _, dir := range dirs {
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		result = append(result, dir)
+	}
+	return result
+}
+
+// findRNNoiseLib 自动查找RNNoise库文件
+//
+// 查找顺序：
+//  1. 环境变量RNNOISE_LIB_PATH（库文件路径或所在目录）
+//  2. 使用 rnnoise_embed 构建标签时嵌入的库（解压到缓存目录）
+//  3. libSearchDirs中的各个目录，每个目录依次尝试libNames中的文件名
+//
+// 找不到时返回的错误会列出所有尝试过的位置
+func findRNNoiseLib() (string, error) {
+	var tried []string
+	names := libNames()
+
+	if envPath := os.Getenv(LibPathEnv); envPath != "" {
+		info, err := os.Stat(envPath)
+		switch {
+		case err != nil:
+			tried = append(tried, envPath)
+		case !info.IsDir():
+			return filepath.Abs(envPath)
+		default:
+			for _, name := range names {
+				candidate := filepath.Join(envPath, name)
+				if isRegularFile(candidate) {
+					return filepath.Abs(candidate)
+				}
+				tried = append(tried, candidate)
+			}
+		}
+	}
+
+	if embeddedLib != nil {
+		path, err := embeddedLib.extract()
+		if err == nil {
+			return path, nil
+		}
+		tried = append(tried, fmt.Sprintf("嵌入的库 (%v)", err))
+	}
+
+	for _, dir := range libSearchDirs() {
+		for _, name := range names {
+			candidate := filepath.Join(dir, name)
+			if isRegularFile(candidate) {
+				return filepath.Abs(candidate)
+			}
+			tried = append(tried, candidate)
+		}
+	}
+
+	return "", fmt.Errorf("未找到库文件 %s，可以通过环境变量%s指定，已尝试以下位置:\n  %s",
+		strings.Join(names, " / "), LibPathEnv, strings.Join(tried, "\n  "))
+}
+
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
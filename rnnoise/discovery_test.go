@@ -0,0 +1,66 @@
+package rnnoise
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindRNNoiseLibFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	libPath := filepath.Join(dir, libNames()[1])
+	if err := os.WriteFile(libPath, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 环境变量指向目录时按文件名查找
+	t.Setenv(LibPathEnv, dir)
+	got, err := findRNNoiseLib()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != libPath {
+		t.Errorf("findRNNoiseLib() = %q, want %q", got, libPath)
+	}
+
+	// 环境变量直接指向库文件
+	t.Setenv(LibPathEnv, libPath)
+	if got, err = findRNNoiseLib(); err != nil || got != libPath {
+		t.Errorf("findRNNoiseLib() = %q, %v, want %q", got, err, libPath)
+	}
+}
+
+func TestFindRNNoiseLibErrorListsLocations(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	t.Setenv(LibPathEnv, missing)
+
+	_, err := findRNNoiseLib()
+	if err == nil {
+		t.Skip("系统中已安装RNNoise库")
+	}
+	if !strings.Contains(err.Error(), missing) || !strings.Contains(err.Error(), filepath.Join("lib", bundledLibName())) {
+		t.Errorf("error should list tried locations, got: %v", err)
+	}
+}
+
+func TestLdconfigDirs(t *testing.T) {
+	dir := t.TempDir()
+	confDir := filepath.Join(dir, "ld.so.conf.d")
+	if err := os.Mkdir(confDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	conf := filepath.Join(dir, "ld.so.conf")
+	if err := os.WriteFile(conf, []byte("# comment\ninclude ld.so.conf.d/*.conf\n/opt/a/lib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(confDir, "x.conf"), []byte("/opt/b/lib # trailing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := ldconfigDirs(conf, 0)
+	want := []string{"/opt/b/lib", "/opt/a/lib"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ldconfigDirs() = %v, want %v", got, want)
+	}
+}
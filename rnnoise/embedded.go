@@ -0,0 +1,89 @@
+package rnnoise
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// embeddedLibrary 嵌入到可执行文件中的动态库
+type embeddedLibrary struct {
+	name   string
+	data   []byte
+	sha256 string // 期望的SHA-256校验和（十六进制）
+
+	once sync.Once
+	path string
+	err  error
+}
+
+// extract 首次调用时解压到缓存目录，之后直接返回解压后的路径
+func (e *embeddedLibrary) extract() (string, error) {
+	e.once.Do(func() {
+		e.path, e.err = extractEmbeddedLibrary(e, embeddedCacheDir())
+	})
+	return e.path, e.err
+}
+
+// embeddedCacheDir 解压目录，优先使用用户缓存目录，不可用时使用临时目录
+func embeddedCacheDir() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "go-rnnoise")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("go-rnnoise-%d", os.Getuid()))
+}
+
+// extractEmbeddedLibrary 将嵌入的库写入dir下以内容哈希命名的文件并校验
+//
+// 文件已存在且校验和一致时直接复用；不一致（被截断或篡改）时重新写入。
+// 写入先落到临时文件再重命名，多个进程同时解压不会读到不完整的文件。
+func extractEmbeddedLibrary(e *embeddedLibrary, dir string) (string, error) {
+	sum := sha256.Sum256(e.data)
+	if hex.EncodeToString(sum[:]) != e.sha256 {
+		return "", fmt.Errorf("嵌入的RNNoise库校验和不匹配，可执行文件可能已损坏")
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("创建RNNoise库缓存目录失败: %v", err)
+	}
+
+	path := filepath.Join(dir, e.sha256[:16]+"-"+e.name)
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, e.data) {
+		return path, nil
+	}
+
+	tmp, err := os.CreateTemp(dir, e.name+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("创建RNNoise库临时文件失败: %v", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(e.data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("写入RNNoise库失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("写入RNNoise库失败: %v", err)
+	}
+	if err := os.Chmod(tmpPath, 0o500); err != nil {
+		return "", fmt.Errorf("设置RNNoise库权限失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", fmt.Errorf("保存RNNoise库失败: %v", err)
+	}
+
+	// 从磁盘读回校验，确认加载的正是嵌入的内容
+	written, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取解压的RNNoise库失败: %v", err)
+	}
+	if sum := sha256.Sum256(written); hex.EncodeToString(sum[:]) != e.sha256 {
+		return "", fmt.Errorf("解压的RNNoise库校验和不匹配: %s", path)
+	}
+	return path, nil
+}
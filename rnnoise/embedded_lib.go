@@ -0,0 +1,12 @@
+//go:build rnnoise_embed && darwin && arm64
+
+package rnnoise
+
+import "github.com/zhangzhao-gg/go-rnnoise/lib"
+
+// embeddedLib 通过 rnnoise_embed 构建标签嵌入的动态库
+var embeddedLib = &embeddedLibrary{
+	name:   lib.Name,
+	data:   lib.Data,
+	sha256: lib.SHA256,
+}
@@ -0,0 +1,23 @@
+//go:build cgo && rnnoise_embed && darwin && arm64
+
+package rnnoise
+
+import "testing"
+
+// TestEmbeddedLibraryLoads 解压嵌入的库后实际加载并处理一帧，确认嵌入的是当前平台可用的库
+func TestEmbeddedLibraryLoads(t *testing.T) {
+	path, err := extractEmbeddedLibrary(embeddedLib, t.TempDir())
+	if err != nil {
+		t.Fatalf("extract failed: %v", err)
+	}
+
+	r, err := NewRNNoise(path)
+	if err != nil {
+		t.Fatalf("加载嵌入的库失败: %v", err)
+	}
+	defer r.Destroy()
+
+	if _, _, err := r.ProcessFrame(make([]float32, r.FrameSize())); err != nil {
+		t.Fatalf("ProcessFrame failed: %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !rnnoise_embed
+
+package rnnoise
+
+// embeddedLib 未使用 rnnoise_embed 构建标签时没有嵌入的动态库
+var embeddedLib *embeddedLibrary
@@ -0,0 +1,58 @@
+package rnnoise
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testEmbeddedLibrary(data []byte) *embeddedLibrary {
+	sum := sha256.Sum256(data)
+	return &embeddedLibrary{name: "librnnoise.so", data: data, sha256: hex.EncodeToString(sum[:])}
+}
+
+func TestExtractEmbeddedLibrary(t *testing.T) {
+	dir := t.TempDir()
+	lib := testEmbeddedLibrary([]byte("fake shared object"))
+
+	path, err := extractEmbeddedLibrary(lib, dir)
+	if err != nil {
+		t.Fatalf("extract failed: %v", err)
+	}
+	if filepath.Dir(path) != dir || filepath.Base(path) != lib.sha256[:16]+"-librnnoise.so" {
+		t.Fatalf("unexpected path %s", path)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "fake shared object" {
+		t.Fatalf("extracted content = %q, %v", data, err)
+	}
+
+	// 被篡改的缓存文件会被重新写入
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("tampered"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	again, err := extractEmbeddedLibrary(lib, dir)
+	if err != nil {
+		t.Fatalf("re-extract failed: %v", err)
+	}
+	if data, _ := os.ReadFile(again); string(data) != "fake shared object" {
+		t.Fatalf("tampered cache not replaced: %q", data)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected only the library in cache dir, got %d entries", len(entries))
+	}
+}
+
+func TestExtractEmbeddedLibraryChecksumMismatch(t *testing.T) {
+	lib := testEmbeddedLibrary([]byte("fake shared object"))
+	lib.data = []byte("corrupted")
+	if _, err := extractEmbeddedLibrary(lib, t.TempDir()); err == nil {
+		t.Fatal("expected checksum error")
+	}
+}
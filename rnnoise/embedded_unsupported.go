@@ -0,0 +1,10 @@
+//go:build rnnoise_embed && !(darwin && arm64)
+
+package rnnoise
+
+// lib/ 中只有macOS arm64版本的动态库，在其他平台上使用 rnnoise_embed 构建标签
+// 会得到无法加载的库，因此直接让编译失败，而不是悄悄退回到不嵌入的构建。
+// 请去掉该标签，并通过 WithLibPath 或 RNNOISE_LIB_PATH 等方式提供当前平台的库。
+var _ = rnnoise_embed_only_supports_darwin_arm64
+
+var embeddedLib *embeddedLibrary
@@ -2,6 +2,11 @@ package rnnoise
 
 import (
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -14,154 +19,575 @@ import (
 // - 原始PCM数据处理
 // - 流式音频处理
 // - 语音概率分析和统计
+//
+// NoiseFilter 持有有状态的降噪后端，同一实例同时只能被一个goroutine使用，
+// 重叠的调用会返回ErrConcurrentUse；并发处理请使用FilterPool
 type NoiseFilter struct {
-	rnnoise   *RNNoise
-	processor *AudioProcessor
+	guard        useGuard
+	denoiser     FrameDenoiser
+	processor    *AudioProcessor
+	logger       logrus.FieldLogger
+	threshold    float32
+	resampler    Resampler
+	outputPolicy OutputPolicy
+	metrics      Metrics
+
+	attenuationGain   float32
+	comfortNoiseLevel float32
+	crossfade         time.Duration
+
+	channelMode      ChannelMode
+	parallelChannels bool
+	newDenoiser      func() (FrameDenoiser, error) // 为额外的声道创建降噪后端，可能为nil
+	channelDenoisers []FrameDenoiser               // 第2个及之后的声道的降噪后端
+
+	latencyCompensation bool
+
+	mix            float32
+	maxAttenuation float32
+	streamMixer    *wetDryMixer // FilterStream的干湿混合状态，首次调用时创建
+
+	agc               *AGCConfig // 自动增益控制的参数，未启用时为nil
+	normalizeLoudness bool
+	loudnessTarget    float64
+	limiter           bool
+	truePeakCeiling   float64
+
+	preProcessors []PreProcessorFactory
+	streamPre     preChain    // FilterStream的预处理状态，首次调用时创建
+	streamDeEmph  *deEmphasis // FilterStream的去加重状态，没有预加重时为nil
 }
 
 // NewNoiseFilter 创建新的噪声过滤器
 //
 // 参数:
-//   - libPath: RNNoise动态库文件路径，如果为空则自动查找
+//   - opts: 配置选项，不传时自动查找RNNoise动态库并使用默认配置
 //
 // 返回:
 //   - *NoiseFilter: 噪声过滤器实例
 //   - error: 创建失败时的错误信息
 //
+// 可用选项:
+//   - WithLibPath / WithModelFile / WithModelReader / WithModelData: 动态库和模型
+//   - WithGoModel: 使用纯Go后端
+//   - WithDenoiser: 使用已创建的降噪后端
+//   - WithLogger / WithThreshold / WithResampler / WithMetrics
+//   - WithOutputPolicy / WithAttenuationGain / WithComfortNoiseLevel / WithCrossfade: 低于阈值的帧的输出方式
+//   - WithChannelMode / WithParallelChannels / WithDenoiserFactory: 多声道处理
+//   - WithLatencyCompensation: 补偿降噪后端的固有延迟
+//   - WithMix / WithMaxAttenuation: 降噪前后信号的混合比例和最大衰减
+//   - WithAGC / WithLoudnessNormalization / WithTruePeakLimiter: 自动增益、响度归一化和真峰值限幅
+//   - WithPreProcessing: 降噪前的直流阻断、高通和陷波等预处理
+//
 // 示例:
 //
-//	filter, err := NewNoiseFilter("")
+//	filter, err := NewNoiseFilter()
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
 //	defer filter.Destroy()
-func NewNoiseFilter(libPath string) (*NoiseFilter, error) {
-	rnnoise, err := NewRNNoise(libPath)
+//
+//	// 指定库路径和默认阈值
+//	filter, err := NewNoiseFilter(
+//	    WithLibPath("/path/to/librnnoise.so"),
+//	    WithThreshold(0.3),
+//	    WithOutputPolicy(OutputSilence),
+//	)
+func NewNoiseFilter(opts ...Option) (*NoiseFilter, error) {
+	cfg := newConfig(opts)
+	if cfg.attenuationGain < 0 || cfg.attenuationGain > 1 {
+		return nil, fmt.Errorf("衰减增益必须在0到1之间，当前为%v", cfg.attenuationGain)
+	}
+	if cfg.comfortNoiseLevel < 0 || cfg.comfortNoiseLevel > 1 {
+		return nil, fmt.Errorf("舒适噪声电平必须在0到1之间，当前为%v", cfg.comfortNoiseLevel)
+	}
+	if cfg.mix < 0 || cfg.mix > 1 {
+		return nil, fmt.Errorf("混合比例必须在0到1之间，当前为%v", cfg.mix)
+	}
+	if cfg.maxAttenuation < 0 {
+		return nil, fmt.Errorf("最大衰减不能为负数，当前为%vdB", cfg.maxAttenuation)
+	}
+	if cfg.agc != nil {
+		if err := cfg.agc.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.normalizeLoudness && (cfg.loudnessTarget >= 0 || cfg.loudnessTarget <= loudnessAbsoluteGate) {
+		return nil, fmt.Errorf("目标响度必须在%v到0 LUFS之间，当前为%v", loudnessAbsoluteGate, cfg.loudnessTarget)
+	}
+	if cfg.truePeakCeiling > 0 {
+		return nil, fmt.Errorf("真峰值上限不能大于0dBTP，当前为%v", cfg.truePeakCeiling)
+	}
+
+	newDenoiser, err := denoiserFactory(cfg)
 	if err != nil {
 		return nil, err
 	}
+	denoiser := cfg.denoiser
+	if denoiser == nil {
+		if denoiser, err = newDenoiser(); err != nil {
+			return nil, err
+		}
+	}
+	if denoiser.FrameSize() <= 0 || denoiser.SampleRateHz() <= 0 {
+		if cfg.denoiser == nil {
+			denoiser.Close()
+		}
+		return nil, fmt.Errorf("降噪后端的帧格式无效: %d样本@%dHz", denoiser.FrameSize(), denoiser.SampleRateHz())
+	}
+
+	processor := NewAudioProcessor(denoiser)
+	processor.resampler = cfg.resampler
+	processor.logger = cfg.logger
 
-	processor := NewAudioProcessor(rnnoise)
+	nf := &NoiseFilter{
+		denoiser:     denoiser,
+		processor:    processor,
+		logger:       cfg.logger,
+		threshold:    cfg.threshold,
+		resampler:    cfg.resampler,
+		outputPolicy: cfg.outputPolicy,
+		metrics:      cfg.metrics,
 
-	return &NoiseFilter{
-		rnnoise:   rnnoise,
-		processor: processor,
+		attenuationGain:   cfg.attenuationGain,
+		comfortNoiseLevel: cfg.comfortNoiseLevel,
+		crossfade:         cfg.crossfade,
+
+		channelMode:      cfg.channelMode,
+		parallelChannels: cfg.parallelChannels,
+		newDenoiser:      newDenoiser,
+
+		latencyCompensation: cfg.latencyCompensation,
+
+		mix:            cfg.mix,
+		maxAttenuation: cfg.maxAttenuation,
+
+		agc:               cfg.agc,
+		normalizeLoudness: cfg.normalizeLoudness,
+		loudnessTarget:    cfg.loudnessTarget,
+		limiter:           cfg.limiterEnabled(),
+		truePeakCeiling:   cfg.truePeakCeiling,
+
+		preProcessors: preProcessorFactories(cfg),
+	}
+
+	// 提前创建一次预处理链，参数错误在构造时报告
+	if _, err := nf.newPreChain(); err != nil {
+		if cfg.denoiser == nil {
+			denoiser.Close()
+		}
+		return nil, err
+	}
+	return nf, nil
+}
+
+// denoiserFactory 按配置返回创建降噪后端的函数，使用WithDenoiser且未指定WithDenoiserFactory时返回nil
+func denoiserFactory(cfg *config) (func() (FrameDenoiser, error), error) {
+	switch {
+	case cfg.denoiserFactory != nil:
+		return cfg.denoiserFactory, nil
+	case cfg.denoiser != nil:
+		return nil, nil
+	}
+
+	// 多声道模式会多次创建实例，io.Reader中的模型只能读取一次
+	if cfg.model != nil && cfg.model.reader != nil && cfg.channelMode != ChannelDownmix {
+		_, data, err := cfg.model.load()
+		if err != nil {
+			return nil, err
+		}
+		cfg.model = &modelSource{data: data}
+	}
+	return func() (FrameDenoiser, error) {
+		rnnoise, err := newRNNoiseFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return rnnoise, nil
 	}, nil
 }
 
+// NewNoiseFilterWithLib 使用指定的动态库路径创建噪声过滤器
+//
+// Deprecated: 请使用 NewNoiseFilter(WithLibPath(libPath))
+func NewNoiseFilterWithLib(libPath string) (*NoiseFilter, error) {
+	return NewNoiseFilter(WithLibPath(libPath))
+}
+
+// NewNoiseFilterWithDenoiser 使用指定的降噪后端创建噪声过滤器
+//
+// 降噪后端可以是RNNoise（cgo或纯Go）、FakeDenoiser或任何实现了FrameDenoiser的类型，
+// 过滤器销毁时会关闭该后端。等价于 NewNoiseFilter(WithDenoiser(denoiser), opts...)
+//
+// 示例:
+//
+//	// 单元测试中使用确定性的假后端
+//	filter, err := NewNoiseFilterWithDenoiser(NewFakeDenoiser(0.9))
+func NewNoiseFilterWithDenoiser(denoiser FrameDenoiser, opts ...Option) (*NoiseFilter, error) {
+	if denoiser == nil {
+		return nil, fmt.Errorf("降噪后端不能为空")
+	}
+
+	return NewNoiseFilter(append(opts, WithDenoiser(denoiser))...)
+}
+
 // Destroy 销毁噪声过滤器，释放资源
 func (nf *NoiseFilter) Destroy() {
-	if nf.rnnoise != nil {
-		nf.rnnoise.Destroy()
+	for _, denoiser := range append([]FrameDenoiser{nf.denoiser}, nf.channelDenoisers...) {
+		if denoiser == nil {
+			continue
+		}
+		if err := denoiser.Close(); err != nil {
+			nf.logger.Warnf("关闭降噪后端失败: %v", err)
+		}
 	}
+	nf.channelDenoisers = nil
 }
 
-// Reset 重置RNNoise状态
+// Threshold 返回默认语音概率阈值
+func (nf *NoiseFilter) Threshold() float32 {
+	return nf.threshold
+}
+
+// resolveThreshold 将DefaultThreshold替换为配置的默认阈值
+func (nf *NoiseFilter) resolveThreshold(voiceProbThreshold float32) float32 {
+	if voiceProbThreshold < 0 {
+		return nf.threshold
+	}
+	return voiceProbThreshold
+}
+
+// Latency 降噪后端的固有延迟，后端未实现LatencyReporter时为0
+//
+// 启用WithLatencyCompensation时FilterAudio、FilterAudioFile和StreamFilter的输出已经补偿了该延迟
+func (nf *NoiseFilter) Latency() time.Duration {
+	reporter, ok := nf.denoiser.(LatencyReporter)
+	if !ok {
+		return 0
+	}
+	return time.Duration(reporter.Latency()) * time.Second / time.Duration(nf.denoiser.SampleRateHz())
+}
+
+// latency 需要补偿的延迟（降噪后端采样率下的样本数），未启用延迟补偿时为0
+func (nf *NoiseFilter) latency() int {
+	if !nf.latencyCompensation {
+		return 0
+	}
+	if reporter, ok := nf.denoiser.(LatencyReporter); ok {
+		return reporter.Latency()
+	}
+	return 0
+}
+
+// keptLength 输出应有的长度：inputLen减去丢弃的dropped个样本（降噪后端采样率rate）换算到输入采样率后的长度
+//
+// 没有丢弃帧时与输入等长，避免重采样的取整使输出比输入少一个样本
+func keptLength(inputLen, dropped, inputRate, rate int) int {
+	n := inputLen - int(math.Round(float64(dropped)*float64(inputRate)/float64(rate)))
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// fitLength 将样本截断或补零到n个
+func fitLength(samples []float32, n int) []float32 {
+	if len(samples) >= n {
+		return samples[:n]
+	}
+	return append(samples, make([]float32, n-len(samples))...)
+}
+
+// observeFrame 记录单帧处理指标
+func (nf *NoiseFilter) observeFrame(m FrameMetrics) {
+	if nf.metrics.OnFrame != nil {
+		nf.metrics.OnFrame(m)
+	}
+}
+
+// Reset 重置降噪后端状态
 func (nf *NoiseFilter) Reset() error {
-	return nf.rnnoise.Reset()
+	if err := nf.guard.acquire(); err != nil {
+		return err
+	}
+	defer nf.guard.release()
+
+	if err := nf.denoiser.Reset(); err != nil {
+		return err
+	}
+	for _, denoiser := range nf.channelDenoisers {
+		if err := denoiser.Reset(); err != nil {
+			return err
+		}
+	}
+	nf.streamMixer.reset()
+	nf.streamPre.reset()
+	nf.streamDeEmph.reset()
+	return nil
 }
 
 // GetRNNoise 获取RNNoise实例（用于创建AudioProcessor）
+//
+// 如果过滤器使用的不是RNNoise后端，返回nil，此时请使用Denoiser
 func (nf *NoiseFilter) GetRNNoise() *RNNoise {
-	return nf.rnnoise
+	rnnoise, _ := nf.denoiser.(*RNNoise)
+	return rnnoise
+}
+
+// Denoiser 获取过滤器使用的降噪后端
+func (nf *NoiseFilter) Denoiser() FrameDenoiser {
+	return nf.denoiser
 }
 
 // FilterResult 过滤结果
 type FilterResult struct {
-	DenoisedAudio      *AudioData // 降噪后的音频
-	VoiceProbabilities []float32  // 每帧的语音概率
-	ProcessedFrames    int        // 处理的帧数
+	DenoisedAudio      *AudioData    // 降噪后的音频
+	VoiceProbabilities []float32     // 每帧的语音概率（多声道模式下为各声道的最大值）
+	ProcessedFrames    int           // 处理的帧数
+	InputDuration      time.Duration // 输入音频的时长
+
+	ChannelVoiceProbabilities [][]float32 // 多声道模式下每个声道每帧的语音概率
 }
 
 // FilterAudio 对音频进行降噪处理
+//
+// 语音概率低于阈值的帧按输出策略处理（默认丢弃，输出只减去被丢弃的帧；静音、衰减、舒适噪声策略保持时间对齐，输出与输入逐样本等长），
+// voiceProbThreshold传入DefaultThreshold时使用过滤器的默认阈值。
+// WithLatencyCompensation时输出补偿降噪后端的固有延迟，与输入逐样本对齐。
+// 多声道音频默认混合为单声道处理；WithChannelMode(ChannelIndependent或ChannelLinked)时
+// 每个声道使用独立的降噪状态，输出保持原声道数；输出的位深和样本格式与输入相同。
+// 整段音频作为一个流经过与StreamFilter相同的流水线（见NewNoiseFilterPipeline），
+// 启用WithLoudnessNormalization时在限幅之前归一化响度
 func (nf *NoiseFilter) FilterAudio(audioData *AudioData, voiceProbThreshold float32) (*FilterResult, error) {
-	// 1. 转换音频格式为RNNoise支持的格式
-	convertedAudio, err := nf.processor.ConvertToRNNoiseFormat(audioData)
-
+	if err := nf.guard.acquire(); err != nil {
+		return nil, err
+	}
+	defer nf.guard.release()
+
+	startTime := time.Now()
+	var stats offlineStats
+	denoise := nf.newDenoiseStage(nf.coreOptions(voiceProbThreshold), stats.add)
+	// 未实现StreamResamplerFactory的转换器按整段转换
+	denoise.opts.whole = true
+	denoisedAudio, err := nf.newPipeline(denoise, nf.outputStages(true)...).ProcessAudio(audioData)
 	if err != nil {
-		return nil, fmt.Errorf("音频格式转换失败: %v", err)
+		return nil, err
 	}
 
-	// 2. 分割为帧
-	frames, err := nf.processor.GetFrames(convertedAudio)
-	if err != nil {
-		return nil, fmt.Errorf("音频分帧失败: %v", err)
+	if nf.metrics.OnAudio != nil {
+		nf.metrics.OnAudio(AudioMetrics{
+			Frames:       len(stats.voiceProbs),
+			KeptFrames:   stats.keptFrames,
+			InputSamples: len(audioData.Samples),
+			Duration:     time.Since(startTime),
+		})
 	}
-	logrus.Debugf("分帧结果: %d帧, 每帧%d样本", len(frames), func() int {
-		if len(frames) > 0 {
-			return len(frames[0])
-		}
-		return 0
-	}())
-
-	// 3. 处理每个帧
-	var denoisedFrames [][]float32
-	var voiceProbabilities []float32
-	for _, frame := range frames {
-		voiceProb, denoisedFrame, err := nf.rnnoise.ProcessFrame(frame)
-		logrus.Debugf("RNNoise当前帧概率为:%v", voiceProb)
-		if err != nil {
-			return nil, fmt.Errorf("帧处理失败: %v", err)
-		}
 
-		voiceProbabilities = append(voiceProbabilities, voiceProb)
+	return &FilterResult{
+		DenoisedAudio:             denoisedAudio,
+		VoiceProbabilities:        stats.voiceProbs,
+		ProcessedFrames:           len(stats.voiceProbs),
+		InputDuration:             audioData.Duration(),
+		ChannelVoiceProbabilities: stats.channelProbs,
+	}, nil
+}
 
-		// 根据语音概率阈值决定是否保留帧
-		if voiceProb >= voiceProbThreshold {
-			denoisedFrames = append(denoisedFrames, denoisedFrame)
-		}
+// offlineStats 离线处理中降噪阶段每次输出的语音概率和统计信息
+type offlineStats struct {
+	voiceProbs   []float32
+	channelProbs [][]float32 // 多声道模式下每个声道的语音概率，单声道处理时为nil
+	keptFrames   int
+}
+
+// add 累计降噪阶段的一次输出
+func (s *offlineStats) add(out *streamOutput) {
+	s.voiceProbs = append(s.voiceProbs, out.voiceProbs...)
+	s.keptFrames += out.keptFrames
+	if len(out.channelProbs) < 2 {
+		return
 	}
-	// 4. 重新组合音频
-	var allSamples []float32
-	for _, frame := range denoisedFrames {
-		allSamples = append(allSamples, frame...)
+	if s.channelProbs == nil {
+		s.channelProbs = make([][]float32, len(out.channelProbs))
 	}
-
-	denoisedAudio := &AudioData{
-		Samples:    allSamples,
-		SampleRate: 48000,
-		Channels:   1,
-		BitDepth:   16,
+	for ch, probs := range out.channelProbs {
+		s.channelProbs[ch] = append(s.channelProbs[ch], probs...)
 	}
+}
 
-	// 5. 如果需要，转换回原始采样率
-	if audioData.SampleRate != 48000 { //肯定不是48000，因为之前是8000。所以需要转换回原始采样率
-		logrus.Debugf("RNN-FilterAudio转换回原始采样率: %d", audioData.SampleRate)
-		denoisedAudio, err = nf.convertSampleRate(denoisedAudio, audioData.SampleRate)
-		if err != nil {
-			return nil, fmt.Errorf("采样率转换失败: %v", err)
+// batchFrames 离线处理时每批的帧数（48kHz下为1秒音频）
+const batchFrames = 100
+
+// frameHandler 接收单帧的处理结果，elapsed为该帧的处理耗时
+type frameHandler func(i int, voiceProb float32, denoised []float32, elapsed time.Duration)
+
+// denoiseFramesWith 使用指定的降噪后端依次处理所有帧，每帧处理完成后调用fn
+//
+// 降噪后端实现了BatchDenoiser时每batchFrames帧调用一次ProcessFrames，
+// 此时传给fn的耗时为整批耗时的平均值
+func denoiseFramesWith(denoiser FrameDenoiser, frames [][]float32, fn frameHandler) error {
+	batch, ok := denoiser.(BatchDenoiser)
+	if !ok {
+		for i, frame := range frames {
+			frameStart := time.Now()
+			voiceProb, denoisedFrame, err := denoiser.ProcessFrame(frame)
+			if err != nil {
+				return err
+			}
+			fn(i, voiceProb, denoisedFrame, time.Since(frameStart))
 		}
+		return nil
 	}
 
-	return &FilterResult{
-		DenoisedAudio:      denoisedAudio,
-		VoiceProbabilities: voiceProbabilities,
-		ProcessedFrames:    len(frames),
-	}, nil
+	frameSize := denoiser.FrameSize()
+	voiceProbs := make([]float32, batchFrames)
+	for first := 0; first < len(frames); first += batchFrames {
+		n := len(frames) - first
+		if n > batchFrames {
+			n = batchFrames
+		}
+
+		// 每批使用新的缓冲区原地处理，fn可以保留传入的帧
+		buf := make([]float32, n*frameSize)
+		for j := 0; j < n; j++ {
+			copy(buf[j*frameSize:(j+1)*frameSize], frames[first+j])
+		}
+
+		batchStart := time.Now()
+		if err := batch.ProcessFrames(buf, buf, voiceProbs[:n]); err != nil {
+			return err
+		}
+		elapsed := time.Since(batchStart) / time.Duration(n)
+
+		for j := 0; j < n; j++ {
+			start := j * frameSize
+			fn(first+j, voiceProbs[j], buf[start:start+frameSize:start+frameSize], elapsed)
+		}
+	}
+	return nil
 }
 
+// fileBlockFrames FilterAudioFile每次读取的采样帧数
+const fileBlockFrames = 8192
+
 // FilterAudioFile 直接处理音频文件
+//
+// 按fileBlockFrames个采样帧分块读取，经与StreamFilter相同的流水线降噪后写入，内存占用与文件大小无关，可以处理数GB的录音。
+// 输出文件保持输入的采样率、位深度和样本格式；声道数与FilterAudio相同（默认混合为单声道，多声道模式下保持原声道数）。
+// 返回结果中的DenoisedAudio只描述输出格式，Samples为nil，降噪后的样本只写入outputFile。
+// WithLoudnessNormalization时分两遍处理：第一遍降噪后写入outputFile所在目录的临时文件并测量响度，
+// 第二遍施加归一化增益、限幅后写入outputFile
 func (nf *NoiseFilter) FilterAudioFile(inputFile, outputFile string, voiceProbThreshold float32) (*FilterResult, error) {
+	if err := nf.guard.acquire(); err != nil {
+		return nil, err
+	}
+	defer nf.guard.release()
+
+	startTime := time.Now()
 
-	// 读取输入文件
-	audioData, err := nf.processor.ReadWAV(inputFile)
+	// 读取输入文件头
+	reader, err := OpenWAV(inputFile)
 	if err != nil {
 		return nil, fmt.Errorf("读取音频文件失败: %v", err)
 	}
+	defer reader.Close()
+	format := reader.Format()
 
-	// 进行降噪处理
-	result, err := nf.FilterAudio(audioData, voiceProbThreshold)
+	outFormat := format
+	if nf.channelMode == ChannelDownmix {
+		outFormat.Channels = 1
+	}
+	writer, err := CreateWAV(outputFile, outFormat)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("写入音频文件失败: %v", err)
+	}
+	defer writer.Close()
+
+	var stats offlineStats
+	denoise := nf.newDenoiseStage(nf.coreOptions(voiceProbThreshold), stats.add)
+	pipeline := nf.newPipeline(denoise, nf.outputStages(false)...)
+
+	// 响度归一化需要整段音频的响度：第一遍写入32位浮点的临时文件并测量响度，第二遍归一化、限幅后写入outputFile
+	sink := writer
+	var meter *LoudnessMeter
+	var temp *os.File
+	if nf.normalizeLoudness {
+		if temp, err = os.CreateTemp(filepath.Dir(outputFile), ".rnnoise-*.wav"); err != nil {
+			return nil, fmt.Errorf("创建临时文件失败: %v", err)
+		}
+		defer os.Remove(temp.Name())
+		defer temp.Close()
+		tempFormat := WAVFormat{SampleRate: outFormat.SampleRate, Channels: outFormat.Channels, BitDepth: 32, Float: true}
+		if sink, err = NewWAVWriter(temp, tempFormat); err != nil {
+			return nil, fmt.Errorf("创建临时文件失败: %v", err)
+		}
+		if meter, err = NewLoudnessMeter(outFormat.SampleRate, outFormat.Channels); err != nil {
+			return nil, err
+		}
+		pipeline = nf.newPipeline(denoise, NewTapStage(func(b *Block) { meter.writeChannels(b.Samples) }))
+	}
+
+	// 分块读取、降噪和写入
+	buf := make([]float32, fileBlockFrames*format.Channels)
+	inputSamples := 0
+	for {
+		n, err := reader.ReadSamples(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取音频文件失败: %v", err)
+		}
+		inputSamples += n
+
+		out, err := pipeline.Process(&Block{Samples: deinterleave(buf[:n], format.Channels), SampleRate: format.SampleRate})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBlock(sink, out); err != nil {
+			return nil, err
+		}
 	}
 
-	// 写入输出文件
-	err = nf.processor.WriteWAV(outputFile, result.DenoisedAudio)
+	tail, err := pipeline.Flush()
 	if err != nil {
+		return nil, err
+	}
+	if err := writeBlock(sink, tail); err != nil {
+		return nil, err
+	}
+	if err := sink.Close(); err != nil {
+		return nil, fmt.Errorf("写入音频文件失败: %v", err)
+	}
+	if meter != nil {
+		gain := normalizationGain(nf.logger, meter.Integrated(), nf.loudnessTarget)
+		if err := nf.normalizeFile(temp.Name(), writer, gain); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
 		return nil, fmt.Errorf("写入音频文件失败: %v", err)
 	}
 
-	return result, nil
+	if nf.metrics.OnAudio != nil {
+		nf.metrics.OnAudio(AudioMetrics{
+			Frames:       len(stats.voiceProbs),
+			KeptFrames:   stats.keptFrames,
+			InputSamples: inputSamples,
+			Duration:     time.Since(startTime),
+		})
+	}
+	return &FilterResult{
+		DenoisedAudio: &AudioData{
+			SampleRate: outFormat.SampleRate,
+			Channels:   outFormat.Channels,
+			BitDepth:   outFormat.BitDepth,
+			Float:      outFormat.Float,
+		},
+		VoiceProbabilities:        stats.voiceProbs,
+		ProcessedFrames:           len(stats.voiceProbs),
+		InputDuration:             time.Duration(inputSamples/format.Channels) * time.Second / time.Duration(format.SampleRate),
+		ChannelVoiceProbabilities: stats.channelProbs,
+	}, nil
 }
 
 // FilterAudioBytes 处理音频字节数据，对原始PCM音频进行RNNoise降噪处理
@@ -170,14 +596,14 @@ func (nf *NoiseFilter) FilterAudioFile(inputFile, outputFile string, voiceProbTh
 // 内部会将音频转换为48kHz单声道进行处理，然后转换回原始格式。
 //
 // 参数:
-//   - audioBytes: 原始PCM音频字节数据（小端序格式）
+//   - audioBytes: 原始PCM音频字节数据（小端序有符号整数，其他编码请使用FilterAudioBytesFormat）
 //   - sampleRate: 音频采样率（Hz），支持8000, 16000, 44100, 48000等
 //   - channels: 声道数（1=单声道, 2=立体声）
 //   - bitDepth: 位深度（支持16, 24, 32位）
 //   - voiceProbThreshold: 语音概率阈值（0.0-1.0），低于此值的帧会被过滤
 //
 // 返回:
-//   - []byte: 降噪后的PCM音频字节数据（与输入格式相同）
+//   - []byte: 降噪后的PCM音频字节数据（位深与输入相同；多声道输入默认输出单声道，多声道模式下保持原声道数）
 //   - []float32: 每帧的语音概率数组（0.0-1.0，每个值对应10ms音频帧）
 //   - error: 处理过程中的错误信息，成功时为nil
 //
@@ -191,16 +617,30 @@ func (nf *NoiseFilter) FilterAudioFile(inputFile, outputFile string, voiceProbTh
 //	}
 //	fmt.Printf("检测到 %d 帧语音\n", len(voiceProbs))
 func (nf *NoiseFilter) FilterAudioBytes(audioBytes []byte, sampleRate, channels, bitDepth int, voiceProbThreshold float32) ([]byte, []float32, error) {
-	// 转换字节为float32样本
-	//输入320字节，16位深，8000hz，单声道
-	//输出为160长度的float32数组,48000hz，单声道，16位深
-	samples := ConvertBytesToFloat32(audioBytes, bitDepth)
+	format := PCMFormat{SampleRate: sampleRate, Channels: channels, BitDepth: bitDepth}
+	return nf.FilterAudioBytesFormat(audioBytes, format, voiceProbThreshold)
+}
+
+// FilterAudioBytesFormat 按format解码原始PCM数据并降噪，输出使用相同的编码
+//
+// 支持小端/大端整数、IEEE浮点和G.711 µ-law/A-law（见PCMFormat和ParsePCMFormat）；
+// 多声道输入默认输出单声道，多声道模式下保持原声道数
+//
+// 示例:
+//
+//	// 8kHz单声道G.711 A-law电话音频
+//	format, _ := ParsePCMFormat("alaw", 8000, 1)
+//	denoisedBytes, voiceProbs, err := filter.FilterAudioBytesFormat(payload, format, 0.3)
+func (nf *NoiseFilter) FilterAudioBytesFormat(audioBytes []byte, format PCMFormat, voiceProbThreshold float32) ([]byte, []float32, error) {
+	if err := format.validate(); err != nil {
+		return nil, nil, err
+	}
 
 	audioData := &AudioData{
-		Samples:    samples,
-		SampleRate: sampleRate,
-		Channels:   channels,
-		BitDepth:   bitDepth,
+		Samples:    format.Decode(audioBytes),
+		SampleRate: format.SampleRate,
+		Channels:   format.Channels,
+		BitDepth:   format.BitDepth,
 	}
 
 	// 进行降噪处理
@@ -210,59 +650,56 @@ func (nf *NoiseFilter) FilterAudioBytes(audioBytes []byte, sampleRate, channels,
 	}
 
 	// 转换回字节格式
-	denoisedBytes := ConvertFloat32ToBytes(result.DenoisedAudio.Samples, bitDepth)
-
-	return denoisedBytes, result.VoiceProbabilities, nil
+	format.Channels = result.DenoisedAudio.Channels
+	return format.Encode(result.DenoisedAudio.Samples), result.VoiceProbabilities, nil
 }
 
 // FilterStream 流式处理音频（每次处理一个10ms的帧）
+//
+// 返回的帧已按WithMix和WithMaxAttenuation混入原始信号，输出策略由调用方根据返回的判定结果处理。
+// 不做自动增益、响度归一化和限幅，需要时请使用StreamFilter
 func (nf *NoiseFilter) FilterStream(frame []float32, voiceProbThreshold float32) ([]float32, float32, bool, error) {
-	if len(frame) != 480 {
-		return nil, 0, false, fmt.Errorf("流式处理要求帧大小为480个样本（10ms @ 48kHz），当前为%d", len(frame))
+	if frameSize := nf.denoiser.FrameSize(); len(frame) != frameSize {
+		return nil, 0, false, fmt.Errorf("流式处理要求帧大小为%d个样本（10ms @ %dHz），当前为%d",
+			frameSize, nf.denoiser.SampleRateHz(), len(frame))
+	}
+	if err := nf.guard.acquire(); err != nil {
+		return nil, 0, false, err
+	}
+	defer nf.guard.release()
+
+	if len(nf.preProcessors) > 0 {
+		if nf.streamPre == nil {
+			chain, err := nf.newPreChain()
+			if err != nil {
+				return nil, 0, false, err
+			}
+			nf.streamPre = chain
+			nf.streamDeEmph = chain.newDeEmphasis()
+		}
+		// 不修改调用方的帧
+		frame = append([]float32(nil), frame...)
+		nf.streamPre.process(frame)
 	}
 
-	voiceProb, denoisedFrame, err := nf.rnnoise.ProcessFrame(frame)
+	frameStart := time.Now()
+	voiceProb, denoisedFrame, err := nf.denoiser.ProcessFrame(frame)
 	if err != nil {
 		return nil, 0, false, err
 	}
+	if nf.streamMixer == nil {
+		nf.streamMixer = nf.newWetDryMixer()
+	}
+	denoisedFrame = nf.streamMixer.apply(denoisedFrame, frame)
+	nf.streamDeEmph.process(denoisedFrame)
 
 	// 判断是否保留该帧
-	keepFrame := voiceProb >= voiceProbThreshold
+	keepFrame := voiceProb >= nf.resolveThreshold(voiceProbThreshold)
+	nf.observeFrame(FrameMetrics{VoiceProb: voiceProb, Kept: keepFrame, Duration: time.Since(frameStart)})
 
 	return denoisedFrame, voiceProb, keepFrame, nil
 }
 
-// convertSampleRate 简单的采样率转换（线性插值）
-func (nf *NoiseFilter) convertSampleRate(audioData *AudioData, targetSampleRate int) (*AudioData, error) {
-	if audioData.SampleRate == targetSampleRate {
-		return audioData, nil
-	}
-
-	ratio := float64(targetSampleRate) / float64(audioData.SampleRate)
-	newLength := int(float64(len(audioData.Samples)) * ratio)
-	resampledSamples := make([]float32, newLength)
-
-	for i := 0; i < newLength; i++ {
-		srcIndex := float64(i) / ratio
-		srcIndexInt := int(srcIndex)
-
-		if srcIndexInt >= len(audioData.Samples)-1 {
-			resampledSamples[i] = audioData.Samples[len(audioData.Samples)-1]
-		} else {
-			// 线性插值
-			frac := float32(srcIndex - float64(srcIndexInt))
-			resampledSamples[i] = audioData.Samples[srcIndexInt]*(1-frac) + audioData.Samples[srcIndexInt+1]*frac
-		}
-	}
-
-	return &AudioData{
-		Samples:    resampledSamples,
-		SampleRate: targetSampleRate,
-		Channels:   audioData.Channels,
-		BitDepth:   audioData.BitDepth,
-	}, nil
-}
-
 // FrameStatistics 获取帧处理统计信息
 type FrameStatistics struct {
 	TotalFrames      int     // 总帧数
@@ -274,31 +711,27 @@ type FrameStatistics struct {
 }
 
 // AnalyzeFrames 分析音频帧的统计信息
+//
+// 多声道音频混合为单声道后经降噪流水线计算每帧的语音概率，不补偿延迟
 func (nf *NoiseFilter) AnalyzeFrames(audioData *AudioData, voiceProbThreshold float32) (*FrameStatistics, error) {
-	// 转换音频格式
-	convertedAudio, err := nf.processor.ConvertToRNNoiseFormat(audioData)
-	if err != nil {
+	if err := nf.guard.acquire(); err != nil {
 		return nil, err
 	}
+	defer nf.guard.release()
 
-	// 分割为帧
-	frames, err := nf.processor.GetFrames(convertedAudio)
+	voiceProbThreshold = nf.resolveThreshold(voiceProbThreshold)
+	voiceProbs, err := nf.analyzeVoiceProbs(audioData)
 	if err != nil {
 		return nil, err
 	}
 
 	stats := &FrameStatistics{
-		TotalFrames:  len(frames),
+		TotalFrames:  len(voiceProbs),
 		MinVoiceProb: 1.0,
 	}
 
 	var totalProb float32
-	for _, frame := range frames {
-		voiceProb, _, err := nf.rnnoise.ProcessFrame(frame)
-		if err != nil {
-			return nil, err
-		}
-
+	for _, voiceProb := range voiceProbs {
 		totalProb += voiceProb
 
 		if voiceProb >= voiceProbThreshold {
@@ -321,3 +754,13 @@ func (nf *NoiseFilter) AnalyzeFrames(audioData *AudioData, voiceProbThreshold fl
 
 	return stats, nil
 }
+
+// analyzeVoiceProbs 计算整段音频每帧的语音概率：多声道先混合为单声道，不补偿延迟
+func (nf *NoiseFilter) analyzeVoiceProbs(audioData *AudioData) ([]float32, error) {
+	var stats offlineStats
+	denoise := nf.newDenoiseStage(coreOptions{threshold: nf.threshold, whole: true}, stats.add)
+	if _, err := NewPipeline(&channelMixStage{channels: 1}, denoise).ProcessAudio(audioData); err != nil {
+		return nil, err
+	}
+	return stats.voiceProbs, nil
+}
@@ -0,0 +1,403 @@
+package rnnoise
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilterAudioWithFakeDenoiser(t *testing.T) {
+	fake := NewFakeDenoiser(0.9, 0.1)
+	filter, err := NewNoiseFilterWithDenoiser(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audioData := &AudioData{
+		Samples:    make([]float32, 480*4),
+		SampleRate: 48000,
+		Channels:   1,
+		BitDepth:   16,
+	}
+
+	result, err := filter.FilterAudio(audioData, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ProcessedFrames != 4 {
+		t.Errorf("ProcessedFrames = %d, want 4", result.ProcessedFrames)
+	}
+	// 语音概率交替为0.9和0.1，阈值0.5时只保留一半的帧
+	if got := len(result.DenoisedAudio.Samples); got != 480*2 {
+		t.Errorf("denoised samples = %d, want %d", got, 480*2)
+	}
+
+	filter.Destroy()
+	if !fake.Closed {
+		t.Error("Destroy() should close the denoiser")
+	}
+}
+
+func TestFilterStreamWithFakeDenoiser(t *testing.T) {
+	fake := NewFakeDenoiser()
+	fake.Gain = 0.5
+	filter, err := NewNoiseFilterWithDenoiser(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	frame := make([]float32, 480)
+	for i := range frame {
+		frame[i] = 0.2
+	}
+
+	out, prob, keep, err := filter.FilterStream(frame, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prob != 1 || !keep {
+		t.Errorf("FilterStream() prob = %f keep = %v, want 1 true", prob, keep)
+	}
+	if out[0] != 0.1 {
+		t.Errorf("FilterStream() sample = %f, want 0.1", out[0])
+	}
+
+	if _, _, _, err := filter.FilterStream(frame[:100], 0.5); err == nil {
+		t.Error("FilterStream() expected error for wrong frame size")
+	}
+
+	if err := filter.Reset(); err != nil || fake.Resets != 1 || fake.Frames != 0 {
+		t.Errorf("Reset() did not reset the denoiser: err=%v resets=%d frames=%d", err, fake.Resets, fake.Frames)
+	}
+}
+
+func TestNewNoiseFilterWithNilDenoiser(t *testing.T) {
+	if _, err := NewNoiseFilterWithDenoiser(nil); err == nil {
+		t.Error("NewNoiseFilterWithDenoiser(nil) expected error")
+	}
+}
+
+func TestNoiseFilterOptions(t *testing.T) {
+	var frameMetrics []FrameMetrics
+	var audioMetrics []AudioMetrics
+	filter, err := NewNoiseFilter(
+		WithDenoiser(NewFakeDenoiser(0.9, 0.1)),
+		WithThreshold(0.5),
+		WithOutputPolicy(OutputSilence),
+		WithMetrics(Metrics{
+			OnFrame: func(m FrameMetrics) { frameMetrics = append(frameMetrics, m) },
+			OnAudio: func(m AudioMetrics) { audioMetrics = append(audioMetrics, m) },
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	audioData := &AudioData{
+		Samples:    make([]float32, 480*4),
+		SampleRate: 48000,
+		Channels:   1,
+		BitDepth:   16,
+	}
+	result, err := filter.FilterAudio(audioData, DefaultThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 静音策略下输出长度与输入一致
+	if got := len(result.DenoisedAudio.Samples); got != 480*4 {
+		t.Errorf("denoised samples = %d, want %d", got, 480*4)
+	}
+	if len(frameMetrics) != 4 || !frameMetrics[0].Kept || frameMetrics[1].Kept {
+		t.Errorf("unexpected frame metrics: %+v", frameMetrics)
+	}
+	if len(audioMetrics) != 1 || audioMetrics[0].KeptFrames != 2 {
+		t.Errorf("unexpected audio metrics: %+v", audioMetrics)
+	}
+}
+
+// frameOnlyDenoiser 隐藏FakeDenoiser的批量处理接口
+type frameOnlyDenoiser struct {
+	FrameDenoiser
+}
+
+func TestFilterAudioUsesBatchProcessing(t *testing.T) {
+	audioData := &AudioData{
+		Samples:    make([]float32, 480*250),
+		SampleRate: 48000,
+		Channels:   1,
+		BitDepth:   16,
+	}
+	for i := range audioData.Samples {
+		audioData.Samples[i] = float32(i%97) / 500
+	}
+
+	fake := NewFakeDenoiser(0.9, 0.2, 0.6)
+	fake.Gain = 0.5
+	filter, err := NewNoiseFilterWithDenoiser(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	result, err := filter.FilterAudio(audioData, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.Batches != 3 || fake.Frames != 250 {
+		t.Errorf("Batches = %d Frames = %d, want 3 batches of 250 frames", fake.Batches, fake.Frames)
+	}
+
+	// 逐帧处理的结果必须与批量处理一致
+	single := NewFakeDenoiser(0.9, 0.2, 0.6)
+	single.Gain = 0.5
+	reference, err := NewNoiseFilterWithDenoiser(frameOnlyDenoiser{single})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reference.Destroy()
+
+	want, err := reference.FilterAudio(audioData, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single.Batches != 0 {
+		t.Fatalf("reference filter should not batch")
+	}
+	if len(result.VoiceProbabilities) != len(want.VoiceProbabilities) ||
+		len(result.DenoisedAudio.Samples) != len(want.DenoisedAudio.Samples) {
+		t.Fatalf("batched output length differs from per-frame output")
+	}
+	for i, v := range want.DenoisedAudio.Samples {
+		if result.DenoisedAudio.Samples[i] != v {
+			t.Fatalf("sample %d = %f, want %f", i, result.DenoisedAudio.Samples[i], v)
+		}
+	}
+
+	fake.Batches = 0
+	stats, err := filter.AnalyzeFrames(audioData, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.Batches != 3 || stats.TotalFrames != 250 {
+		t.Errorf("AnalyzeFrames Batches = %d TotalFrames = %d", fake.Batches, stats.TotalFrames)
+	}
+}
+
+func TestFilterAudioOutputLengthMatchesInput(t *testing.T) {
+	for _, rate := range []int{8000, 16000, 44100, 48000} {
+		for _, policy := range []OutputPolicy{OutputSilence, OutputAttenuate} {
+			filter, err := NewNoiseFilterWithDenoiser(NewFakeDenoiser(0.9, 0.1), WithOutputPolicy(policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 长度不是帧大小的整数倍
+			audioData := &AudioData{Samples: sineSamples(rate/3+17, rate, 200), SampleRate: rate, Channels: 1, BitDepth: 16}
+			result, err := filter.FilterAudio(audioData, 0.5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(result.DenoisedAudio.Samples); got != len(audioData.Samples) {
+				t.Errorf("%d Hz policy %d: output %d samples, want %d", rate, policy, got, len(audioData.Samples))
+			}
+			filter.Destroy()
+		}
+	}
+
+	// OutputDrop保留所有帧时与输入等长：最后一帧补的零不输出，重采样的取整也不会少一个样本
+	for _, rate := range []int{22050, 44100, 48000} {
+		for _, channels := range []int{1, 2} {
+			var created []*FakeDenoiser
+			filter, err := NewNoiseFilter(WithDenoiserFactory(fakeFactory(&created, 1)), WithChannelMode(ChannelIndependent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := rate/3 + 17
+			audioData := &AudioData{Samples: make([]float32, n*channels), SampleRate: rate, Channels: channels, BitDepth: 16}
+			result, err := filter.FilterAudio(audioData, 0.5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(result.DenoisedAudio.Samples); got != n*channels {
+				t.Errorf("%d Hz %d channels OutputDrop: output %d samples, want %d", rate, channels, got, n*channels)
+			}
+			filter.Destroy()
+		}
+	}
+
+	// 丢弃一半的帧时只减去被丢弃的部分
+	filter, err := NewNoiseFilterWithDenoiser(NewFakeDenoiser(0.9, 0.1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+	result, err := filter.FilterAudio(&AudioData{Samples: make([]float32, 22050), SampleRate: 22050, Channels: 1}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(result.DenoisedAudio.Samples); got != 22050/2 {
+		t.Errorf("OutputDrop with half the frames dropped: output %d samples, want %d", got, 22050/2)
+	}
+}
+
+func TestFilterAudioLatencyCompensation(t *testing.T) {
+	input := sineSamples(480*5+100, 48000, 440)
+	run := func(compensate bool, channels int) []float32 {
+		newFake := func() (FrameDenoiser, error) {
+			fake := NewFakeDenoiser(1)
+			fake.Delay = 480
+			return fake, nil
+		}
+		filter, err := NewNoiseFilter(
+			WithDenoiserFactory(newFake),
+			WithChannelMode(ChannelIndependent),
+			WithOutputPolicy(OutputSilence),
+			WithLatencyCompensation(compensate),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer filter.Destroy()
+		if filter.Latency() != 10*time.Millisecond {
+			t.Errorf("Latency() = %v, want 10ms", filter.Latency())
+		}
+
+		audioData := &AudioData{SampleRate: 48000, Channels: channels, BitDepth: 16}
+		for _, sample := range input {
+			for ch := 0; ch < channels; ch++ {
+				audioData.Samples = append(audioData.Samples, sample)
+			}
+		}
+		result, err := filter.FilterAudio(audioData, 0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.DenoisedAudio.Samples) != len(audioData.Samples) {
+			t.Fatalf("output %d samples, want %d", len(result.DenoisedAudio.Samples), len(audioData.Samples))
+		}
+		return deinterleave(result.DenoisedAudio.Samples, channels)[channels-1]
+	}
+
+	for _, channels := range []int{1, 2} {
+		// 不补偿时输出延迟480个样本，补偿后与输入逐样本一致
+		delayed := run(false, channels)
+		for i := range input {
+			want := float32(0)
+			if i >= 480 {
+				want = input[i-480]
+			}
+			if delayed[i] != want {
+				t.Fatalf("%d ch uncompensated sample %d = %f, want %f", channels, i, delayed[i], want)
+			}
+		}
+		aligned := run(true, channels)
+		for i := range input {
+			if aligned[i] != input[i] {
+				t.Fatalf("%d ch compensated sample %d = %f, want %f", channels, i, aligned[i], input[i])
+			}
+		}
+	}
+}
+
+func TestLatencyCompensationWithOutputDrop(t *testing.T) {
+	// 每帧为不同的常数，第1帧和第4帧低于阈值
+	var input []float32
+	for k := 0; k < 4; k++ {
+		for i := 0; i < 480; i++ {
+			input = append(input, float32(k+1)/10)
+		}
+	}
+	opts := func() []Option {
+		fake := NewFakeDenoiser(0.1, 0.9, 0.9, 0.1)
+		fake.Delay = 480
+		return []Option{WithDenoiser(fake), WithOutputPolicy(OutputDrop), WithThreshold(0.5), WithLatencyCompensation(true)}
+	}
+	// 补偿延迟后丢弃的是低于阈值的输入帧本身
+	check := func(name string, got []float32) {
+		t.Helper()
+		want := input[480 : 480*3]
+		if len(got) != len(want) {
+			t.Fatalf("%s: output %d samples, want %d", name, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%s: sample %d = %v, want %v", name, i, got[i], want[i])
+			}
+		}
+	}
+
+	filter, err := NewNoiseFilter(opts()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+	result, err := filter.FilterAudio(&AudioData{Samples: input, SampleRate: 48000, Channels: 1, BitDepth: 16}, DefaultThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("FilterAudio", result.DenoisedAudio.Samples)
+
+	sf, err := NewStreamFilter(48000, opts()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Destroy()
+	out := processInChunks(input, []int{100, 700}, func(chunk []float32) []float32 {
+		out, _, err := sf.Process(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	})
+	tail, _, err := sf.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("StreamFilter", append(out, tail...))
+}
+
+// zeroRateDenoiser 报告无效采样率的降噪后端
+type zeroRateDenoiser struct {
+	FrameDenoiser
+}
+
+func (zeroRateDenoiser) SampleRateHz() int { return 0 }
+
+func TestInvalidSampleRatesReturnErrors(t *testing.T) {
+	filter, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser(0.9)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	for _, rate := range []int{0, -8000} {
+		audioData := &AudioData{Samples: make([]float32, 480), SampleRate: rate, Channels: 1, BitDepth: 16}
+		if _, err := filter.FilterAudio(audioData, DefaultThreshold); err == nil {
+			t.Errorf("FilterAudio(%dHz) expected error", rate)
+		}
+		if _, err := filter.AnalyzeFrames(audioData, DefaultThreshold); err == nil {
+			t.Errorf("AnalyzeFrames(%dHz) expected error", rate)
+		}
+		if _, err := filter.processor.ConvertToRNNoiseFormat(audioData); err == nil {
+			t.Errorf("ConvertToRNNoiseFormat(%dHz) expected error", rate)
+		}
+		if _, _, err := filter.FilterAudioBytes(make([]byte, 960), rate, 1, 16, DefaultThreshold); err == nil {
+			t.Errorf("FilterAudioBytes(%dHz) expected error", rate)
+		}
+		if sf, err := NewStreamFilter(rate, WithDenoiser(NewFakeDenoiser(0.9))); err == nil {
+			sf.Destroy()
+			t.Errorf("NewStreamFilter(%dHz) expected error", rate)
+		}
+		resample, _ := NewResampleStage(16000, nil)
+		if _, err := resample.Process(&Block{Samples: [][]float32{make([]float32, 160)}, SampleRate: rate}); err == nil {
+			t.Errorf("resample stage with a %dHz block expected error", rate)
+		}
+	}
+
+	if nf, err := NewNoiseFilter(WithDenoiser(zeroRateDenoiser{NewFakeDenoiser()})); err == nil {
+		nf.Destroy()
+		t.Error("NewNoiseFilter expected error for a backend reporting 0Hz")
+	}
+}
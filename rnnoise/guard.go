@@ -0,0 +1,32 @@
+package rnnoise
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrConcurrentUse 同一个RNNoise或NoiseFilter实例被多个goroutine同时调用
+//
+// 降噪状态不能共享，并发处理请为每个goroutine创建独立的实例，或使用FilterPool
+var ErrConcurrentUse = errors.New("实例正在被其他goroutine使用，同一实例不能并发调用")
+
+// useGuard 检测对同一实例的并发调用
+//
+// 检测只在调用真正重叠时触发：先到的调用正常执行，
+// 重叠的调用立即返回ErrConcurrentUse而不会访问内部状态
+type useGuard struct {
+	busy atomic.Bool
+}
+
+// acquire 标记实例正在使用，已被占用时返回ErrConcurrentUse
+func (g *useGuard) acquire() error {
+	if !g.busy.CompareAndSwap(false, true) {
+		return ErrConcurrentUse
+	}
+	return nil
+}
+
+// release 释放acquire的占用
+func (g *useGuard) release() {
+	g.busy.Store(false)
+}
@@ -0,0 +1,222 @@
+package rnnoise
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultTruePeakCeiling 真峰值限幅器默认的上限（dBTP）
+const DefaultTruePeakCeiling = -1.0
+
+const (
+	// limiterLookahead 限幅器的前瞻时长，增益在峰值到来之前逐渐降低
+	limiterLookahead = 2 * time.Millisecond
+	// limiterRelease 峰值过后增益恢复的时间常数
+	limiterRelease = 100 * time.Millisecond
+
+	// truePeakPhases 估计真峰值时的过采样倍数（BS.1770-4附录2）
+	truePeakPhases = 4
+	// truePeakTaps 每个插值相位的抽头数
+	truePeakTaps = 12
+)
+
+// truePeakTable 4倍过采样插值滤波器，第p行计算相邻两个样本之间p/4位置的值
+var truePeakTable = newTruePeakTable()
+
+// newTruePeakTable 设计Blackman窗sinc插值滤波器，每个相位的系数归一化为直流增益1
+func newTruePeakTable() [truePeakPhases][truePeakTaps]float64 {
+	var table [truePeakPhases][truePeakTaps]float64
+	half := float64(truePeakTaps / 2)
+	for p := 1; p < truePeakPhases; p++ {
+		var sum float64
+		for j := range table[p] {
+			// 第j个抽头对应样本m+j-(half-1)，插值位置为m+p/4
+			t := float64(p)/truePeakPhases - float64(j) + half - 1
+			w := 0.42 + 0.5*math.Cos(math.Pi*t/half) + 0.08*math.Cos(2*math.Pi*t/half)
+			table[p][j] = w * sinc(t)
+			sum += table[p][j]
+		}
+		for j := range table[p] {
+			table[p][j] /= sum
+		}
+	}
+	return table
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// truePeakLimiter 前瞻式真峰值限幅器，各声道共享同一个增益
+//
+// 每个样本先按4倍过采样估计相邻样本之间的真峰值，求出不超过上限所需的增益；
+// 增益取前瞻窗口内的最小值，峰值过后按limiterRelease恢复，再做前瞻长度的滑动平均，
+// 增益在峰值到来之前平滑下降，峰值处一定不超过所需的增益。
+// 输出相对输入延迟delay个样本，process丢弃开头的delay个样本、flush取回最后的delay个样本，
+// 整段音频的输出与输入逐样本等长
+type truePeakLimiter struct {
+	ceiling   float64
+	lookahead int
+	release   float64
+	delay     int
+
+	history [][]float32 // 每个声道最近truePeakTaps个输入样本，用于估计真峰值
+	line    [][]float32 // 每个声道的延迟线，长度为delay
+	pos     int         // 延迟线的读写位置
+
+	required []float64 // 最近lookahead+2个位置所需的增益
+	reqPos   int
+	released float64   // 按释放时间平滑后的增益
+	smooth   []float64 // 最近lookahead个平滑前的增益，用于滑动平均
+	sumPos   int
+	sum      float64
+
+	skip int // 开头尚待丢弃的输出样本数
+}
+
+// newLimiter 创建处理sampleRate采样率、channels声道音频的真峰值限幅器，未启用时返回nil
+func (nf *NoiseFilter) newLimiter(sampleRate, channels int) *truePeakLimiter {
+	if !nf.limiter {
+		return nil
+	}
+	return newTruePeakLimiter(nf.truePeakCeiling, sampleRate, channels)
+}
+
+// newTruePeakLimiter 创建上限为ceiling dBTP的真峰值限幅器
+func newTruePeakLimiter(ceiling float64, sampleRate, channels int) *truePeakLimiter {
+	lookahead := int(limiterLookahead * time.Duration(sampleRate) / time.Second)
+	if lookahead < 1 {
+		lookahead = 1
+	}
+	l := &truePeakLimiter{
+		ceiling:   math.Pow(10, ceiling/20),
+		lookahead: lookahead,
+		release:   1 - math.Exp(-1/(limiterRelease.Seconds()*float64(sampleRate))),
+		delay:     truePeakTaps/2 + lookahead,
+		history:   make([][]float32, channels),
+		line:      make([][]float32, channels),
+		required:  make([]float64, lookahead+2),
+		smooth:    make([]float64, lookahead),
+	}
+	for ch := range l.line {
+		l.history[ch] = make([]float32, truePeakTaps)
+		l.line[ch] = make([]float32, l.delay)
+	}
+	l.reset()
+	return l
+}
+
+// reset 清空延迟线和增益状态，开始新的一段音频
+func (l *truePeakLimiter) reset() {
+	if l == nil {
+		return
+	}
+	for ch := range l.line {
+		for i := range l.history[ch] {
+			l.history[ch][i] = 0
+		}
+		for i := range l.line[ch] {
+			l.line[ch][i] = 0
+		}
+	}
+	for i := range l.required {
+		l.required[i] = 1
+	}
+	for i := range l.smooth {
+		l.smooth[i] = 1
+	}
+	l.pos, l.reqPos, l.sumPos = 0, 0, 0
+	l.released = 1
+	l.sum = float64(l.lookahead)
+	l.skip = l.delay
+}
+
+// process 处理各声道等长的样本，返回限幅后的输出（比输入少尚待丢弃的延迟），l为nil时原样返回
+func (l *truePeakLimiter) process(channels [][]float32) [][]float32 {
+	if l == nil {
+		return channels
+	}
+	out := make([][]float32, len(channels))
+	for i := range channels[0] {
+		for ch := range channels {
+			l.push(ch, channels[ch][i])
+		}
+		l.step(out)
+	}
+	return out
+}
+
+// flush 输入delay个零，取回延迟线中剩余的输出，l为nil时返回nil
+func (l *truePeakLimiter) flush() [][]float32 {
+	if l == nil {
+		return nil
+	}
+	out := make([][]float32, len(l.line))
+	for i := 0; i < l.delay; i++ {
+		for ch := range l.line {
+			l.push(ch, 0)
+		}
+		l.step(out)
+	}
+	return out
+}
+
+// push 将一个样本加入声道ch的插值历史
+func (l *truePeakLimiter) push(ch int, sample float32) {
+	history := l.history[ch]
+	copy(history, history[1:])
+	history[len(history)-1] = sample
+}
+
+// step 所有声道都加入一个样本后，计算增益并输出延迟线中最早的样本
+func (l *truePeakLimiter) step(out [][]float32) {
+	// 估计插值历史中间两个样本之间的真峰值
+	var peak float64
+	center := truePeakTaps/2 - 1
+	for _, history := range l.history {
+		peak = math.Max(peak, math.Abs(float64(history[center])))
+		peak = math.Max(peak, math.Abs(float64(history[center+1])))
+		for p := 1; p < truePeakPhases; p++ {
+			var y float64
+			for j, coeff := range truePeakTable[p] {
+				y += coeff * float64(history[j])
+			}
+			peak = math.Max(peak, math.Abs(y))
+		}
+	}
+	required := 1.0
+	if peak > l.ceiling {
+		required = l.ceiling / peak
+	}
+
+	// 前瞻窗口内的最小增益，峰值过后按释放时间恢复
+	l.required[l.reqPos] = required
+	l.reqPos = (l.reqPos + 1) % len(l.required)
+	held := 1.0
+	for _, g := range l.required {
+		held = math.Min(held, g)
+	}
+	l.released = math.Min(held, l.released+l.release*(1-l.released))
+
+	// 滑动平均使增益平滑下降
+	l.sum += l.released - l.smooth[l.sumPos]
+	l.smooth[l.sumPos] = l.released
+	l.sumPos = (l.sumPos + 1) % len(l.smooth)
+	gain := float32(l.sum / float64(len(l.smooth)))
+
+	// 延迟线中最早的样本与增益对齐（插值历史的中心延迟truePeakTaps/2个样本，前瞻再延迟lookahead个样本）
+	for ch, line := range l.line {
+		sample := line[l.pos]
+		line[l.pos] = l.history[ch][len(l.history[ch])-1]
+		if l.skip == 0 {
+			out[ch] = append(out[ch], sample*gain)
+		}
+	}
+	l.pos = (l.pos + 1) % l.delay
+	if l.skip > 0 {
+		l.skip--
+	}
+}
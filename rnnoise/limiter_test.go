@@ -0,0 +1,114 @@
+package rnnoise
+
+import (
+	"math"
+	"testing"
+)
+
+// limit 用上限为ceiling dBTP的限幅器处理单声道样本
+func limit(t *testing.T, samples []float32, ceiling float64) []float32 {
+	t.Helper()
+	filter, err := NewNoiseFilterWithDenoiser(NewFakeDenoiser(), WithTruePeakLimiter(ceiling))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	limiter := filter.newLimiter(48000, 1)
+	out := limiter.process([][]float32{samples})
+	appendChannels(out, limiter.flush())
+	return out[0]
+}
+
+func peak(samples []float32) float64 {
+	var p float64
+	for _, sample := range samples {
+		p = math.Max(p, math.Abs(float64(sample)))
+	}
+	return p
+}
+
+func TestTruePeakLimiterPassesQuietSignal(t *testing.T) {
+	input := tone(4801, 48000, 440, -6)
+	out := limit(t, input, -1)
+	if len(out) != len(input) {
+		t.Fatalf("output %d samples, want %d", len(out), len(input))
+	}
+	for i := range input {
+		if math.Abs(float64(out[i]-input[i])) > 1e-6 {
+			t.Fatalf("sample %d = %f, want %f", i, out[i], input[i])
+		}
+	}
+}
+
+func TestTruePeakLimiterLimitsPeaks(t *testing.T) {
+	// 超出满幅6dB的正弦波，限幅后不超过-1dBTP
+	input := tone(48000, 48000, 1000, 6)
+	out := limit(t, input, -1)
+	if len(out) != len(input) {
+		t.Fatalf("output %d samples, want %d", len(out), len(input))
+	}
+	ceiling := math.Pow(10, -1.0/20)
+	if p := peak(out); p > ceiling+1e-6 || p < ceiling*0.9 {
+		t.Errorf("output peak %f, want just below %f", p, ceiling)
+	}
+}
+
+func TestTruePeakLimiterDetectsInterSamplePeaks(t *testing.T) {
+	// fs/4的正弦波相位为45°时样本峰值只有真峰值的0.707倍（-3dB）
+	input := make([]float32, 4800)
+	for i := range input {
+		input[i] = float32(math.Sin(math.Pi/2*float64(i) + math.Pi/4))
+	}
+	if p := peak(input); p > 0.708 {
+		t.Fatalf("input sample peak %f, want 0.707", p)
+	}
+
+	// 样本峰值低于-1dBFS，但真峰值为0dBTP，仍需要限幅
+	out := limit(t, input, -1)
+	if p, want := peak(out[1000:]), 0.7071*math.Pow(10, -1.0/20); p > want+0.01 {
+		t.Errorf("output sample peak %f, want at most %f", p, want)
+	}
+}
+
+func TestFilterAudioTruePeakLimiter(t *testing.T) {
+	for _, channels := range []int{1, 2} {
+		var created []*FakeDenoiser
+		filter, err := NewNoiseFilter(WithDenoiserFactory(fakeFactory(&created, 0.9)), WithChannelMode(ChannelLinked),
+			WithOutputPolicy(OutputSilence), WithTruePeakLimiter(-2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer filter.Destroy()
+
+		mono := tone(8000, 8000, 300, 3)
+		samples := mono
+		if channels == 2 {
+			samples = interleave([][]float32{mono, mono})
+		}
+		result, err := filter.FilterAudio(&AudioData{Samples: samples, SampleRate: 8000, Channels: channels, BitDepth: 16}, 0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.DenoisedAudio.Samples) != len(samples) {
+			t.Fatalf("%d channels: output %d samples, want %d", channels, len(result.DenoisedAudio.Samples), len(samples))
+		}
+		if p := peak(result.DenoisedAudio.Samples); p > math.Pow(10, -2.0/20)+1e-6 {
+			t.Errorf("%d channels: output peak %f exceeds -2dBTP", channels, p)
+		}
+	}
+}
+
+func TestNoiseFilterRejectsInvalidDynamicsOptions(t *testing.T) {
+	if _, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser()), WithTruePeakLimiter(1)); err == nil {
+		t.Error("expected error for a ceiling above 0dBTP")
+	}
+	if _, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser()), WithLoudnessNormalization(3)); err == nil {
+		t.Error("expected error for a positive loudness target")
+	}
+	cfg := DefaultAGCConfig()
+	cfg.SpeechThreshold = 2
+	if _, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser()), WithAGC(cfg)); err == nil {
+		t.Error("expected error for an AGC speech threshold above 1")
+	}
+}
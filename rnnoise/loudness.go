@@ -0,0 +1,189 @@
+package rnnoise
+
+import (
+	"fmt"
+	"math"
+)
+
+// 响度测量的门限（ITU-R BS.1770-4）
+const (
+	loudnessAbsoluteGate = -70.0 // 绝对门限（LUFS）
+	loudnessRelativeGate = -10.0 // 相对门限（LU）
+)
+
+// LoudnessMeter 按ITU-R BS.1770-4 / EBU R128测量积分响度（LUFS）
+//
+// 输入经K计权滤波后按400ms、重叠75%的块计算能量，先按-70 LUFS的绝对门限、
+// 再按比平均响度低10 LU的相对门限选通，选通后的块的平均能量即为积分响度。
+// 每个块只保存一个能量值，测量任意长度的音频内存占用都很小。
+// 各声道的权重均为1.0，与标准中单声道和立体声的定义一致
+//
+// 示例:
+//
+//	meter, err := NewLoudnessMeter(48000, 2)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	meter.Write(samples) // 交织的立体声样本，可以分多次写入
+//	fmt.Printf("积分响度: %.1f LUFS\n", meter.Integrated())
+type LoudnessMeter struct {
+	channels int
+	filters  [][2]biquadFilter // 每个声道的K计权滤波器（高架预滤波 + RLB高通）
+
+	stepSize   int        // 100ms子块的样本数
+	stepPos    int        // 当前子块已累计的样本数
+	stepEnergy float64    // 当前子块各声道能量之和
+	steps      [4]float64 // 最近4个子块的能量，组成一个400ms的块
+	stepCount  int        // 已完成的子块数
+	blocks     []float64  // 每个400ms块的均方能量
+}
+
+// NewLoudnessMeter 创建测量sampleRate采样率、channels声道音频的响度计
+func NewLoudnessMeter(sampleRate, channels int) (*LoudnessMeter, error) {
+	if sampleRate <= 0 || channels <= 0 {
+		return nil, fmt.Errorf("无效的响度测量格式: %d声道, %dHz", channels, sampleRate)
+	}
+	m := &LoudnessMeter{
+		channels: channels,
+		filters:  make([][2]biquadFilter, channels),
+		stepSize: (sampleRate + 5) / 10,
+	}
+	for ch := range m.filters {
+		m.filters[ch] = kWeighting(float64(sampleRate))
+	}
+	return m, nil
+}
+
+// kWeighting BS.1770的K计权滤波器，按采样率计算系数（48kHz时与标准给出的系数一致）
+func kWeighting(rate float64) [2]biquadFilter {
+	// 第一级：模拟头部声学效应的高架滤波器
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / rate)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquadFilter{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// 第二级：RLB高通滤波器
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / rate)
+	a0 = 1 + k/q + k*k
+	highPass := biquadFilter{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return [2]biquadFilter{shelf, highPass}
+}
+
+// Write 写入交织的样本（范围-1.0到1.0），样本数应为声道数的整数倍，末尾不完整的采样帧会被忽略
+func (m *LoudnessMeter) Write(samples []float32) {
+	for i := 0; i+m.channels <= len(samples); i += m.channels {
+		for ch := 0; ch < m.channels; ch++ {
+			m.addSample(ch, samples[i+ch])
+		}
+		m.advance()
+	}
+}
+
+// writeChannels 写入各声道等长的样本
+func (m *LoudnessMeter) writeChannels(channels [][]float32) {
+	for i := range channels[0] {
+		for ch := range channels {
+			m.addSample(ch, channels[ch][i])
+		}
+		m.advance()
+	}
+}
+
+// addSample 对一个样本做K计权并累计能量
+func (m *LoudnessMeter) addSample(ch int, sample float32) {
+	filters := &m.filters[ch]
+	y := filters[1].process(filters[0].process(float64(sample)))
+	m.stepEnergy += y * y
+}
+
+// advance 结束一个采样帧，凑满100ms时完成一个子块
+func (m *LoudnessMeter) advance() {
+	m.stepPos++
+	if m.stepPos < m.stepSize {
+		return
+	}
+
+	m.steps[m.stepCount%len(m.steps)] = m.stepEnergy / float64(m.stepSize)
+	m.stepCount++
+	m.stepPos, m.stepEnergy = 0, 0
+	if m.stepCount >= len(m.steps) {
+		var sum float64
+		for _, energy := range m.steps {
+			sum += energy
+		}
+		m.blocks = append(m.blocks, sum/float64(len(m.steps)))
+	}
+}
+
+// Integrated 目前为止的积分响度（LUFS），没有超过绝对门限的块（例如静音或不足400ms）时返回负无穷
+func (m *LoudnessMeter) Integrated() float64 {
+	absolute := loudnessEnergy(loudnessAbsoluteGate)
+	mean := gatedMean(m.blocks, absolute)
+	if mean == 0 {
+		return math.Inf(-1)
+	}
+
+	relative := math.Max(absolute, mean*math.Pow(10, loudnessRelativeGate/10))
+	return energyLoudness(gatedMean(m.blocks, relative))
+}
+
+// Reset 清空测量结果和滤波器状态
+func (m *LoudnessMeter) Reset() {
+	for ch := range m.filters {
+		m.filters[ch][0].reset()
+		m.filters[ch][1].reset()
+	}
+	m.stepPos, m.stepEnergy, m.stepCount = 0, 0, 0
+	m.blocks = m.blocks[:0]
+}
+
+// MeasureLoudness 测量一段音频的积分响度（LUFS），没有超过绝对门限的内容时返回负无穷
+func MeasureLoudness(audioData *AudioData) (float64, error) {
+	meter, err := NewLoudnessMeter(audioData.SampleRate, audioData.Channels)
+	if err != nil {
+		return 0, err
+	}
+	meter.Write(audioData.Samples)
+	return meter.Integrated(), nil
+}
+
+// gatedMean 能量大于gate的块的平均能量，没有这样的块时返回0
+func gatedMean(blocks []float64, gate float64) float64 {
+	var sum float64
+	n := 0
+	for _, energy := range blocks {
+		if energy > gate {
+			sum += energy
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// energyLoudness 将K计权后的均方能量换算为响度（LUFS）
+func energyLoudness(energy float64) float64 {
+	return -0.691 + 10*math.Log10(energy)
+}
+
+// loudnessEnergy energyLoudness的逆运算
+func loudnessEnergy(loudness float64) float64 {
+	return math.Pow(10, (loudness+0.691)/10)
+}
@@ -0,0 +1,120 @@
+package rnnoise
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tone 峰值为amplitude dBFS的正弦波
+func tone(n, sampleRate int, freq, amplitude float64) []float32 {
+	samples := sineSamples(n, sampleRate, freq)
+	gain := float32(math.Pow(10, amplitude/20) / 0.5)
+	for i := range samples {
+		samples[i] *= gain
+	}
+	return samples
+}
+
+func TestLoudnessMeterReferenceTone(t *testing.T) {
+	// EBU Tech 3341：立体声两个声道都是-23dBFS的1kHz正弦波时为-23 LUFS
+	for _, rate := range []int{44100, 48000} {
+		mono := tone(rate*5, rate, 997, -23)
+		meter, err := NewLoudnessMeter(rate, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meter.Write(interleave([][]float32{mono, mono}))
+		if got := meter.Integrated(); math.Abs(got+23) > 0.1 {
+			t.Errorf("%dHz: integrated loudness %.2f LUFS, want -23", rate, got)
+		}
+	}
+}
+
+func TestLoudnessMeterGating(t *testing.T) {
+	// 静音被绝对门限排除，比平均响度低20 LU的部分被相对门限排除
+	samples := tone(48000*4, 48000, 997, -20)
+	samples = append(samples, make([]float32, 48000*4)...)
+	samples = append(samples, tone(48000*4, 48000, 997, -40)...)
+	loudness, err := MeasureLoudness(&AudioData{Samples: samples, SampleRate: 48000, Channels: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := -20 - 3.01; math.Abs(loudness-want) > 0.2 {
+		t.Errorf("integrated loudness %.2f LUFS, want %.2f", loudness, want)
+	}
+
+	silence, err := MeasureLoudness(&AudioData{Samples: make([]float32, 48000), SampleRate: 48000, Channels: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(silence, -1) {
+		t.Errorf("silence loudness = %v, want -Inf", silence)
+	}
+}
+
+func TestFilterAudioLoudnessNormalization(t *testing.T) {
+	filter, err := NewNoiseFilterWithDenoiser(NewFakeDenoiser(0.9),
+		WithOutputPolicy(OutputSilence), WithLoudnessNormalization(-16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	input := &AudioData{Samples: tone(16000*5, 16000, 440, -30), SampleRate: 16000, Channels: 1, BitDepth: 16}
+	result, err := filter.FilterAudio(input, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.DenoisedAudio.Samples) != len(input.Samples) {
+		t.Fatalf("output %d samples, want %d", len(result.DenoisedAudio.Samples), len(input.Samples))
+	}
+	loudness, err := MeasureLoudness(result.DenoisedAudio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(loudness+16) > 0.2 {
+		t.Errorf("normalized loudness %.2f LUFS, want -16", loudness)
+	}
+}
+
+func TestFilterAudioFileLoudnessNormalization(t *testing.T) {
+	audioData := &AudioData{SampleRate: 48000, Channels: 2, BitDepth: 24}
+	left, right := tone(48000*3, 48000, 300, -35), tone(48000*3, 48000, 500, -40)
+	audioData.Samples = interleave([][]float32{left, right})
+	input := writeTestWAV(t, audioData)
+
+	var created []*FakeDenoiser
+	filter, err := NewNoiseFilter(WithDenoiserFactory(fakeFactory(&created, 0.9)),
+		WithChannelMode(ChannelLinked), WithOutputPolicy(OutputSilence), WithLoudnessNormalization(-18))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "out.wav")
+	if _, err := filter.FilterAudioFile(input, output, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary file left in output directory: %v", entries)
+	}
+
+	out, err := NewAudioProcessor(nil).ReadWAV(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Channels != 2 || out.BitDepth != 24 || len(out.Samples) != len(audioData.Samples) {
+		t.Fatalf("output %d channels %d bit %d samples, want 2 channels 24 bit %d samples",
+			out.Channels, out.BitDepth, len(out.Samples), len(audioData.Samples))
+	}
+	loudness, err := MeasureLoudness(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(loudness+18) > 0.2 {
+		t.Errorf("normalized loudness %.2f LUFS, want -18", loudness)
+	}
+}
@@ -0,0 +1,108 @@
+package rnnoise
+
+import (
+	"math"
+	"time"
+)
+
+// attenuationRelease 最大衰减限制放开时干信号比例的平滑时间常数
+const attenuationRelease = 50 * time.Millisecond
+
+// wetDryMixer 将降噪后的帧（湿信号）与原始帧（干信号）混合
+//
+// 输出为 (1-α)*湿信号 + α*干信号。α至少为1-mix；设置了最大衰减时，
+// 每帧求出使输出能量不低于干信号能量floor²倍的最小α，α上升立即生效、下降按attenuationRelease平滑，
+// 帧内从上一帧的α线性过渡，避免增益跳变。
+// 干信号经过与降噪后端固有延迟相同的延迟线，与湿信号逐样本对齐后再混合
+type wetDryMixer struct {
+	baseAlpha float64   // 1-mix
+	floor     float64   // 最大衰减对应的线性增益，0表示不限制
+	release   float64   // 每帧α向目标下降的比例
+	alpha     float64   // 上一帧结束时的α
+	dryLine   []float32 // 最近latency个干信号样本
+	dry       []float32 // 延迟后的干信号帧
+}
+
+// newWetDryMixer 按过滤器的配置创建混合器，完全使用湿信号且不限制衰减时返回nil
+func (nf *NoiseFilter) newWetDryMixer() *wetDryMixer {
+	if nf.mix >= 1 && nf.maxAttenuation <= 0 {
+		return nil
+	}
+
+	m := &wetDryMixer{baseAlpha: 1 - float64(nf.mix)}
+	if nf.maxAttenuation > 0 {
+		m.floor = math.Pow(10, -float64(nf.maxAttenuation)/20)
+	}
+	frameDuration := float64(nf.denoiser.FrameSize()) / float64(nf.denoiser.SampleRateHz())
+	m.release = 1 - math.Exp(-frameDuration/attenuationRelease.Seconds())
+	if reporter, ok := nf.denoiser.(LatencyReporter); ok {
+		m.dryLine = make([]float32, reporter.Latency())
+	}
+	m.reset()
+	return m
+}
+
+// reset 清空干信号延迟线，开始新的一段音频
+func (m *wetDryMixer) reset() {
+	if m == nil {
+		return
+	}
+	m.alpha = m.baseAlpha
+	for i := range m.dryLine {
+		m.dryLine[i] = 0
+	}
+}
+
+// apply 将干信号frame按配置混合进降噪后的帧wet（原地修改），m为nil时原样返回wet
+func (m *wetDryMixer) apply(wet, frame []float32) []float32 {
+	if m == nil {
+		return wet
+	}
+
+	// 延迟干信号，与降噪后端的输出对齐
+	dry := frame
+	if latency := len(m.dryLine); latency > 0 {
+		m.dry = append(append(m.dry[:0], m.dryLine...), frame...)
+		copy(m.dryLine, m.dry[len(frame):])
+		dry = m.dry[:len(frame)]
+	}
+
+	target := m.targetAlpha(wet, dry)
+	if target < m.alpha {
+		target = m.alpha + m.release*(target-m.alpha)
+	}
+
+	start, n := m.alpha, float64(len(wet))
+	for i := range wet {
+		alpha := start + (target-start)*float64(i+1)/n
+		wet[i] += float32(alpha) * (dry[i] - wet[i])
+	}
+	m.alpha = target
+	return wet
+}
+
+// targetAlpha 当前帧满足最大衰减限制的最小干信号比例
+func (m *wetDryMixer) targetAlpha(wet, dry []float32) float64 {
+	if m.floor == 0 {
+		return m.baseAlpha
+	}
+
+	// 输出 out = wet + α*(dry-wet)，求 |out|² >= floor²*|dry|² 的最小α
+	var a, b, c, dryEnergy float64
+	for i := range wet {
+		w, v := float64(wet[i]), float64(dry[i]-wet[i])
+		a += v * v
+		b += 2 * w * v
+		c += w * w
+		dryEnergy += float64(dry[i]) * float64(dry[i])
+	}
+	c -= m.floor * m.floor * dryEnergy
+
+	alpha := m.baseAlpha
+	if dryEnergy < 1e-12 || a < 1e-18 || a*alpha*alpha+b*alpha+c >= 0 {
+		return alpha
+	}
+	// f(α)在α处为负、在1处非负，取较大的根
+	alpha = (-b + math.Sqrt(math.Max(0, b*b-4*a*c))) / (2 * a)
+	return math.Min(1, math.Max(m.baseAlpha, alpha))
+}
@@ -0,0 +1,153 @@
+package rnnoise
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMixBlendsDrySignal(t *testing.T) {
+	// 降噪后端输出静音，输出中只剩25%的原始信号
+	fake := NewFakeDenoiser(0.9)
+	fake.Gain = 0
+	out := filterConstant(t, 6, nil, WithDenoiser(fake), WithOutputPolicy(OutputSilence), WithMix(0.75))
+	if len(out) != 480*6 {
+		t.Fatalf("output %d samples, want %d", len(out), 480*6)
+	}
+	for i, sample := range out {
+		if math.Abs(float64(sample-0.05)) > 1e-6 {
+			t.Fatalf("sample %d = %f, want 0.05", i, sample)
+		}
+	}
+}
+
+func TestMaxAttenuationLimitsReduction(t *testing.T) {
+	// 降噪后端衰减40dB，最大衰减12dB时输出不低于输入的-12dB
+	fake := NewFakeDenoiser(0.9)
+	fake.Gain = 0.01
+	out := filterConstant(t, 10, nil, WithDenoiser(fake), WithOutputPolicy(OutputSilence), WithMaxAttenuation(12))
+	floor := 0.2 * math.Pow(10, -12.0/20)
+	for i := 480; i < len(out); i++ { // 第一帧从完全降噪过渡到限制值
+		if math.Abs(float64(out[i])-floor) > 1e-5 {
+			t.Fatalf("sample %d = %f, want %f", i, out[i], floor)
+		}
+	}
+	if out[0] >= out[479] {
+		t.Errorf("first frame should ramp up, got %f -> %f", out[0], out[479])
+	}
+}
+
+func TestMaxAttenuationReleasesSmoothly(t *testing.T) {
+	fake := NewFakeDenoiser(0.9)
+	fake.Gain = 0.01
+	sf, err := NewStreamFilter(48000, WithDenoiser(fake), WithOutputPolicy(OutputSilence), WithMaxAttenuation(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Destroy()
+
+	input := make([]float32, 480*5)
+	for i := range input {
+		input[i] = 0.2
+	}
+	if _, _, err := sf.Process(input); err != nil {
+		t.Fatal(err)
+	}
+
+	// 降噪后端只衰减6dB，不再需要限制，混入的原始信号逐渐减少
+	fake.Gain = 0.5
+	out, _, err := sf.Process(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	floor := float32(0.2 * math.Pow(10, -12.0/20))
+	if out[0] < 0.1 || out[0] > 0.1+floor {
+		t.Errorf("first sample after release = %f, want between 0.1 and %f", out[0], 0.1+floor)
+	}
+	for i := 1; i < len(out); i++ {
+		if out[i] > out[i-1] || out[i] < 0.1 {
+			t.Fatalf("sample %d = %f after %f, want a smooth decay towards 0.1", i, out[i], out[i-1])
+		}
+	}
+	if out[len(out)-1]-0.1 > (out[0]-0.1)/2 {
+		t.Errorf("dry signal not released: last sample %f", out[len(out)-1])
+	}
+}
+
+func TestMixAlignsDrySignalWithLatency(t *testing.T) {
+	// 湿信号延迟480个样本，干信号按同样的延迟对齐，混合后不会产生梳状滤波
+	fake := NewFakeDenoiser(0.9)
+	fake.Delay = 480
+	filter, err := NewNoiseFilterWithDenoiser(fake, WithOutputPolicy(OutputSilence), WithMix(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	input := sineSamples(480*8, 48000, 1000)
+	result, err := filter.FilterAudio(&AudioData{Samples: input, SampleRate: 48000, Channels: 1, BitDepth: 16}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := result.DenoisedAudio.Samples
+	for i := 480; i < len(out); i++ {
+		if math.Abs(float64(out[i]-input[i-480])) > 1e-6 {
+			t.Fatalf("sample %d = %f, want %f", i, out[i], input[i-480])
+		}
+	}
+}
+
+func TestStreamFilterMixMatchesFilterAudio(t *testing.T) {
+	opts := func() []Option {
+		fake := NewFakeDenoiser(0.9, 0.2, 0.9)
+		fake.Gain = 0.05
+		return []Option{WithDenoiser(fake), WithOutputPolicy(OutputAttenuate), WithThreshold(0.5),
+			WithMix(0.9), WithMaxAttenuation(18)}
+	}
+	input := sineSamples(480*12, 48000, 300)
+
+	filter, err := NewNoiseFilter(opts()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+	want, err := filter.FilterAudio(&AudioData{Samples: input, SampleRate: 48000, Channels: 1, BitDepth: 16}, DefaultThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sf, err := NewStreamFilter(48000, opts()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Destroy()
+	got := processInChunks(input, []int{333, 480, 1}, func(chunk []float32) []float32 {
+		out, _, err := sf.Process(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	})
+	tail, _, err := sf.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, tail...)
+
+	if len(got) != len(want.DenoisedAudio.Samples) {
+		t.Fatalf("stream output %d samples, want %d", len(got), len(want.DenoisedAudio.Samples))
+	}
+	for i, sample := range want.DenoisedAudio.Samples {
+		if math.Abs(float64(got[i]-sample)) > 1e-6 {
+			t.Fatalf("sample %d = %f, want %f", i, got[i], sample)
+		}
+	}
+}
+
+func TestNoiseFilterRejectsInvalidMixOptions(t *testing.T) {
+	if _, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser()), WithMix(1.5)); err == nil {
+		t.Error("expected error for mix above 1")
+	}
+	if _, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser()), WithMaxAttenuation(-6)); err == nil {
+		t.Error("expected error for negative maximum attenuation")
+	}
+}
@@ -0,0 +1,76 @@
+package rnnoise
+
+import "fmt"
+
+// channelDenoisersFor 返回channels个声道各自的降噪后端，不足时创建
+//
+// 第1个声道使用过滤器的降噪后端，之后的声道的后端在过滤器销毁前一直保留，降噪状态跨调用延续
+func (nf *NoiseFilter) channelDenoisersFor(channels int) ([]FrameDenoiser, error) {
+	for len(nf.channelDenoisers)+1 < channels {
+		if nf.newDenoiser == nil {
+			return nil, fmt.Errorf("多声道处理需要为每个声道创建降噪后端，使用WithDenoiser时请同时指定WithDenoiserFactory")
+		}
+		denoiser, err := nf.newDenoiser()
+		if err != nil {
+			return nil, fmt.Errorf("创建声道降噪后端失败: %v", err)
+		}
+		if denoiser.FrameSize() != nf.denoiser.FrameSize() || denoiser.SampleRateHz() != nf.denoiser.SampleRateHz() {
+			denoiser.Close()
+			return nil, fmt.Errorf("声道降噪后端的帧格式（%d样本@%dHz）与过滤器不一致（%d样本@%dHz）",
+				denoiser.FrameSize(), denoiser.SampleRateHz(), nf.denoiser.FrameSize(), nf.denoiser.SampleRateHz())
+		}
+		nf.channelDenoisers = append(nf.channelDenoisers, denoiser)
+	}
+
+	denoisers := make([]FrameDenoiser, channels)
+	denoisers[0] = nf.denoiser
+	copy(denoisers[1:], nf.channelDenoisers)
+	return denoisers, nil
+}
+
+// deinterleave 将交织的多声道样本拆分为各声道
+func deinterleave(samples []float32, channels int) [][]float32 {
+	frames := len(samples) / channels
+	out := make([][]float32, channels)
+	for ch := range out {
+		out[ch] = make([]float32, frames)
+		for i := range out[ch] {
+			out[ch][i] = samples[i*channels+ch]
+		}
+	}
+	return out
+}
+
+// downmix 将交织的多声道样本取平均值混合为单声道，单声道输入原样返回
+func downmix(samples []float32, channels int) []float32 {
+	if channels == 1 {
+		return samples
+	}
+	out := make([]float32, len(samples)/channels)
+	for i := range out {
+		var sum float32
+		for ch := 0; ch < channels; ch++ {
+			sum += samples[i*channels+ch]
+		}
+		out[i] = sum / float32(channels)
+	}
+	return out
+}
+
+// interleave 将各声道样本交织，长度按最短的声道截断
+func interleave(channels [][]float32) []float32 {
+	frames := len(channels[0])
+	for _, ch := range channels[1:] {
+		if len(ch) < frames {
+			frames = len(ch)
+		}
+	}
+
+	out := make([]float32, frames*len(channels))
+	for ch, samples := range channels {
+		for i := 0; i < frames; i++ {
+			out[i*len(channels)+ch] = samples[i]
+		}
+	}
+	return out
+}
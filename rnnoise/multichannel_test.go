@@ -0,0 +1,129 @@
+package rnnoise
+
+import (
+	"math"
+	"testing"
+)
+
+// fakeFactory 依次为每个声道创建FakeDenoiser，第ch个声道的语音概率为probs[ch]
+func fakeFactory(created *[]*FakeDenoiser, probs ...float32) func() (FrameDenoiser, error) {
+	return func() (FrameDenoiser, error) {
+		fake := NewFakeDenoiser(probs[len(*created)%len(probs)])
+		*created = append(*created, fake)
+		return fake, nil
+	}
+}
+
+// constantChannels 生成frames个48kHz帧的交织音频，第ch个声道恒为levels[ch]
+func constantChannels(frames int, levels ...float32) *AudioData {
+	audioData := &AudioData{SampleRate: 48000, Channels: len(levels), BitDepth: 16}
+	for i := 0; i < frames*480; i++ {
+		audioData.Samples = append(audioData.Samples, levels...)
+	}
+	return audioData
+}
+
+func TestFilterAudioChannelModes(t *testing.T) {
+	cases := []struct {
+		mode ChannelMode
+		want []float32 // 每个声道的输出
+	}{
+		{ChannelIndependent, []float32{0.2, 0}},
+		{ChannelLinked, []float32{0.2, -0.3}},
+	}
+	for _, tc := range cases {
+		var created []*FakeDenoiser
+		filter, err := NewNoiseFilter(
+			WithDenoiserFactory(fakeFactory(&created, 0.9, 0.1)),
+			WithChannelMode(tc.mode),
+			WithThreshold(0.5),
+			WithOutputPolicy(OutputSilence),
+			WithCrossfade(0),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := filter.FilterAudio(constantChannels(3, 0.2, -0.3), DefaultThreshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := result.DenoisedAudio
+		if out.Channels != 2 || len(out.Samples) != 480*3*2 {
+			t.Fatalf("mode %d: output %d channels %d samples, want 2 channels %d samples", tc.mode, out.Channels, len(out.Samples), 480*3*2)
+		}
+		for i, sample := range out.Samples {
+			if want := tc.want[i%2]; math.Abs(float64(sample-want)) > 1e-6 {
+				t.Fatalf("mode %d: sample %d = %f, want %f", tc.mode, i, sample, want)
+			}
+		}
+		if len(created) != 2 || created[0].Frames != 3 || created[1].Frames != 3 {
+			t.Errorf("mode %d: expected one denoiser per channel processing 3 frames each", tc.mode)
+		}
+		if len(result.ChannelVoiceProbabilities) != 2 || result.ChannelVoiceProbabilities[1][0] != 0.1 || result.VoiceProbabilities[0] != 0.9 {
+			t.Errorf("mode %d: unexpected voice probabilities %v / %v", tc.mode, result.VoiceProbabilities, result.ChannelVoiceProbabilities)
+		}
+
+		if err := filter.Reset(); err != nil || created[1].Resets != 1 {
+			t.Errorf("mode %d: Reset() should reset every channel: %v", tc.mode, err)
+		}
+		filter.Destroy()
+		if !created[0].Closed || !created[1].Closed {
+			t.Errorf("mode %d: Destroy() should close every channel", tc.mode)
+		}
+	}
+}
+
+func TestFilterAudioParallelChannels(t *testing.T) {
+	audioData := &AudioData{SampleRate: 16000, Channels: 4, BitDepth: 16}
+	for i, sample := range sineSamples(16000, 16000, 440) {
+		audioData.Samples = append(audioData.Samples, sample, -sample, sample/2, float32(i%7)/10)
+	}
+
+	run := func(parallel bool) *FilterResult {
+		var created []*FakeDenoiser
+		filter, err := NewNoiseFilter(
+			WithDenoiserFactory(fakeFactory(&created, 0.9, 0.1, 0.6, 0.3)),
+			WithChannelMode(ChannelIndependent),
+			WithParallelChannels(parallel),
+			WithThreshold(0.5),
+			WithOutputPolicy(OutputAttenuate),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer filter.Destroy()
+
+		result, err := filter.FilterAudio(audioData, DefaultThreshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	serial, parallel := run(false), run(true)
+	if len(serial.DenoisedAudio.Samples) != len(audioData.Samples) || len(parallel.DenoisedAudio.Samples) != len(audioData.Samples) {
+		t.Fatalf("output lengths %d / %d, want %d", len(serial.DenoisedAudio.Samples), len(parallel.DenoisedAudio.Samples), len(audioData.Samples))
+	}
+	for i := range serial.DenoisedAudio.Samples {
+		if serial.DenoisedAudio.Samples[i] != parallel.DenoisedAudio.Samples[i] {
+			t.Fatalf("sample %d differs between serial and parallel processing", i)
+		}
+	}
+}
+
+func TestFilterAudioMultichannelRequiresFactory(t *testing.T) {
+	filter, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser(0.9)), WithChannelMode(ChannelIndependent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	if _, err := filter.FilterAudio(constantChannels(1, 0.1, 0.1), 0); err == nil {
+		t.Error("expected error without a denoiser factory")
+	}
+	// 单声道输入不需要额外的后端
+	if _, err := filter.FilterAudio(constantChannels(1, 0.1), 0); err != nil {
+		t.Errorf("mono input: %v", err)
+	}
+}
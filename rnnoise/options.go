@@ -0,0 +1,383 @@
+package rnnoise
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Option RNNoise和NoiseFilter的配置选项
+type Option func(*config)
+
+// config 构造时收集的配置
+type config struct {
+	libPath      string
+	model        *modelSource
+	goModel      *GoModel
+	denoiser     FrameDenoiser
+	logger       logrus.FieldLogger
+	threshold    float32
+	resampler    Resampler
+	outputPolicy OutputPolicy
+	metrics      Metrics
+
+	attenuationGain   float32
+	comfortNoiseLevel float32
+	crossfade         time.Duration
+
+	channelMode      ChannelMode
+	parallelChannels bool
+	denoiserFactory  func() (FrameDenoiser, error)
+
+	latencyCompensation bool
+
+	mix            float32
+	maxAttenuation float32
+
+	agc               *AGCConfig
+	normalizeLoudness bool
+	loudnessTarget    float64
+	limiter           bool
+	truePeakCeiling   float64
+
+	preProcessors []PreProcessorFactory
+	preEmphasis   float64
+}
+
+// DefaultThreshold 作为阈值参数传入时，使用WithThreshold配置的默认阈值
+const DefaultThreshold float32 = -1
+
+// OutputPolicy 语音概率低于阈值的帧的输出策略
+type OutputPolicy int
+
+const (
+	// OutputDrop 丢弃低于阈值的帧（输出会变短）
+	OutputDrop OutputPolicy = iota
+	// OutputSilence 用静音替换低于阈值的帧，保持时间对齐
+	OutputSilence
+	// OutputAttenuate 按WithAttenuationGain设置的增益衰减低于阈值的帧，保持时间对齐
+	OutputAttenuate
+	// OutputComfortNoise 用WithComfortNoiseLevel电平的舒适噪声替换低于阈值的帧，保持时间对齐
+	OutputComfortNoise
+)
+
+// ChannelMode 多声道音频的处理方式
+type ChannelMode int
+
+const (
+	// ChannelDownmix 混合为单声道后处理，输出单声道（默认）
+	ChannelDownmix ChannelMode = iota
+	// ChannelIndependent 每个声道使用独立的降噪状态并各自按阈值判定，输出保持原声道数
+	ChannelIndependent
+	// ChannelLinked 每个声道使用独立的降噪状态，所有声道共享同一个判定（取各声道语音概率的最大值），
+	// 低于阈值时所有声道同时被替换，声像不会漂移
+	ChannelLinked
+)
+
+// FrameMetrics 单帧处理指标
+type FrameMetrics struct {
+	Index     int           // 帧序号（从0开始）
+	VoiceProb float32       // 语音概率
+	Kept      bool          // 是否达到阈值
+	Duration  time.Duration // 降噪后端处理耗时
+}
+
+// AudioMetrics 一段音频的处理指标
+type AudioMetrics struct {
+	Frames       int           // 处理的帧数
+	KeptFrames   int           // 达到阈值的帧数
+	InputSamples int           // 输入样本数
+	Duration     time.Duration // 总耗时
+}
+
+// Metrics 处理指标回调，未设置的回调会被忽略
+type Metrics struct {
+	OnFrame func(FrameMetrics) // 每处理一帧调用一次
+	OnAudio func(AudioMetrics) // 每段音频处理完成后调用一次
+}
+
+// modelSource 自定义模型的来源，三者只会设置其一
+type modelSource struct {
+	path   string
+	reader io.Reader
+	data   []byte
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		logger:            logrus.StandardLogger(),
+		resampler:         PolyphaseResampler{},
+		attenuationGain:   DefaultAttenuationGain,
+		comfortNoiseLevel: DefaultComfortNoiseLevel,
+		crossfade:         DefaultCrossfade,
+		mix:               1,
+		truePeakCeiling:   DefaultTruePeakCeiling,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+	return cfg
+}
+
+// limiterEnabled 是否需要真峰值限幅（WithTruePeakLimiter、WithAGC或WithLoudnessNormalization）
+func (c *config) limiterEnabled() bool {
+	return c.limiter || c.agc != nil || c.normalizeLoudness
+}
+
+// WithLibPath 指定RNNoise动态库路径，不指定时自动查找
+func WithLibPath(path string) Option {
+	return func(c *config) {
+		c.libPath = path
+	}
+}
+
+// WithGoModel 使用纯Go后端和指定的模型，不依赖cgo和动态库
+//
+// 模型已由model指定，与WithModelFile、WithModelReader、WithModelData同时使用时创建实例会返回错误
+func WithGoModel(model *GoModel) Option {
+	return func(c *config) {
+		c.goModel = model
+	}
+}
+
+// WithDenoiser 使用已创建的降噪后端，忽略库路径和模型相关的选项
+func WithDenoiser(denoiser FrameDenoiser) Option {
+	return func(c *config) {
+		c.denoiser = denoiser
+	}
+}
+
+// WithLogger 指定日志记录器，默认使用logrus标准日志
+func WithLogger(logger logrus.FieldLogger) Option {
+	return func(c *config) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithThreshold 设置默认语音概率阈值（0.0-1.0），默认0.0
+//
+// 调用过滤方法时传入DefaultThreshold即使用该值
+func WithThreshold(threshold float32) Option {
+	return func(c *config) {
+		c.threshold = threshold
+	}
+}
+
+// WithResampler 指定采样率转换器，默认使用PolyphaseResampler
+func WithResampler(resampler Resampler) Option {
+	return func(c *config) {
+		if resampler != nil {
+			c.resampler = resampler
+		}
+	}
+}
+
+// WithOutputPolicy 设置低于阈值的帧的输出策略，默认OutputDrop
+//
+// 除OutputDrop外的策略都保持输出与输入时间对齐，保留与替换之间按WithCrossfade交叉淡化
+func WithOutputPolicy(policy OutputPolicy) Option {
+	return func(c *config) {
+		c.outputPolicy = policy
+	}
+}
+
+// WithAttenuationGain 设置OutputAttenuate的线性增益（0.0-1.0），默认DefaultAttenuationGain
+func WithAttenuationGain(gain float32) Option {
+	return func(c *config) {
+		c.attenuationGain = gain
+	}
+}
+
+// WithComfortNoiseLevel 设置OutputComfortNoise的噪声RMS电平（线性，1.0为满幅），默认DefaultComfortNoiseLevel
+func WithComfortNoiseLevel(level float32) Option {
+	return func(c *config) {
+		c.comfortNoiseLevel = level
+	}
+}
+
+// WithCrossfade 设置保留与替换之间切换时的交叉淡化时长，默认DefaultCrossfade，0表示不淡化
+func WithCrossfade(d time.Duration) Option {
+	return func(c *config) {
+		if d >= 0 {
+			c.crossfade = d
+		}
+	}
+}
+
+// WithChannelMode 设置多声道音频的处理方式，默认ChannelDownmix
+//
+// ChannelIndependent和ChannelLinked为第2个及之后的声道额外创建降噪后端，
+// 使用WithDenoiser时需要同时指定WithDenoiserFactory
+func WithChannelMode(mode ChannelMode) Option {
+	return func(c *config) {
+		c.channelMode = mode
+	}
+}
+
+// WithParallelChannels 多声道模式下每个声道在单独的goroutine中降噪
+func WithParallelChannels(parallel bool) Option {
+	return func(c *config) {
+		c.parallelChannels = parallel
+	}
+}
+
+// WithDenoiserFactory 指定创建降噪后端的函数，多声道模式下为每个声道创建一个实例
+//
+// 未使用WithDenoiser时第1个声道的降噪后端也由factory创建
+func WithDenoiserFactory(factory func() (FrameDenoiser, error)) Option {
+	return func(c *config) {
+		c.denoiserFactory = factory
+	}
+}
+
+// WithLatencyCompensation 补偿降噪后端的固有延迟，使降噪后的音频与原始音频逐样本对齐
+//
+// 启用后输出整体提前LatencyReporter报告的样本数（RNNoise为10ms），末尾补零处理以取回最后的输出；
+// 每帧的阈值判定作用于该帧输入对应的输出样本，OutputDrop策略下丢弃的正是低于阈值的输入帧。
+// 降噪后端未实现LatencyReporter时不做补偿
+func WithLatencyCompensation(enabled bool) Option {
+	return func(c *config) {
+		c.latencyCompensation = enabled
+	}
+}
+
+// WithMix 设置降噪后信号（湿信号）在输出中的比例（0.0-1.0），默认1.0即完全使用降噪后的信号
+//
+// 其余部分为原始信号（干信号），例如0.8保留20%的原始声音，适合带背景音乐的播客等不希望完全去除背景的场景。
+// 干信号按降噪后端的固有延迟对齐后再混合，不会产生梳状滤波
+func WithMix(mix float32) Option {
+	return func(c *config) {
+		c.mix = mix
+	}
+}
+
+// WithMaxAttenuation 设置相对输入的最大衰减（dB），默认0表示不限制
+//
+// 每帧的输出能量不会低于输入能量减去该值，例如12表示最多衰减12dB；
+// 超出限制时混入更多原始信号，限制收紧立即生效、放开时平滑过渡。可以与WithMix同时使用
+func WithMaxAttenuation(dB float32) Option {
+	return func(c *config) {
+		c.maxAttenuation = dB
+	}
+}
+
+// WithPreProcessing 设置降噪前的预处理链，各阶段按顺序作用于降噪后端采样率下的样本
+//
+// 离线和流式处理都在ProcessFrame之前执行，每个声道使用单独的实例，滤波器状态跨帧保留。
+// 混合原始信号（WithMix）时干信号同样经过预处理
+//
+// 示例:
+//
+//	WithPreProcessing(DCBlocker(), HighPass(80, 0.7071), Notch(50, 10))
+func WithPreProcessing(stages ...PreProcessorFactory) Option {
+	return func(c *config) {
+		c.preProcessors = stages
+	}
+}
+
+// WithPreEmphasis 在预处理链的最后加入系数为coeff的预加重（见PreEmphasis），0表示不使用
+//
+// 预加重只作用于送入降噪后端的信号，降噪和干湿混合之后会去加重，输出不保留高频提升。
+//
+// 与WithPreProcessing可以同时使用，预加重总是在其他预处理阶段之后执行，例如:
+//
+//	WithPreProcessing(DCBlocker(), HighPass(80, 0.7071)), WithPreEmphasis(DefaultPreEmphasis)
+func WithPreEmphasis(coeff float64) Option {
+	return func(c *config) {
+		c.preEmphasis = coeff
+	}
+}
+
+// WithAGC 启用语音门控的自动增益控制（见AGCConfig），同时启用真峰值限幅器
+//
+// 增益只在语音帧上调整，作用于降噪和混合之后、输出策略之前，多声道共享同一个增益
+func WithAGC(cfg AGCConfig) Option {
+	return func(c *config) {
+		c.agc = &cfg
+	}
+}
+
+// WithLoudnessNormalization 将离线处理的输出归一化到targetLUFS积分响度（ITU-R BS.1770 / EBU R128），同时启用真峰值限幅器
+//
+// EBU R128推荐-23 LUFS，播客常用-16 LUFS。只适用于FilterAudio、FilterAudioFile和FilterAudioBytes：
+// 流式处理无法预知整段音频的响度，不做归一化
+func WithLoudnessNormalization(targetLUFS float64) Option {
+	return func(c *config) {
+		c.normalizeLoudness = true
+		c.loudnessTarget = targetLUFS
+	}
+}
+
+// WithTruePeakLimiter 启用真峰值限幅器，输出的真峰值（4倍过采样估计）不超过ceiling（dBTP）
+//
+// 代替写入时对超出±1.0的样本直接削波。WithAGC和WithLoudnessNormalization会自动启用限幅器，
+// 上限默认为DefaultTruePeakCeiling，可以用该选项修改
+func WithTruePeakLimiter(ceiling float64) Option {
+	return func(c *config) {
+		c.limiter = true
+		c.truePeakCeiling = ceiling
+	}
+}
+
+// WithMetrics 设置处理指标回调
+func WithMetrics(metrics Metrics) Option {
+	return func(c *config) {
+		c.metrics = metrics
+	}
+}
+
+// WithModelFile 从文件加载自定义模型，替代RNNoise内置权重
+//
+// 模型通过动态库的 rnnoise_model_from_filename / rnnoise_model_from_file 加载
+func WithModelFile(path string) Option {
+	return func(c *config) {
+		c.model = &modelSource{path: path}
+	}
+}
+
+// WithModelReader 从io.Reader读取自定义模型，读取在创建实例时进行
+func WithModelReader(r io.Reader) Option {
+	return func(c *config) {
+		c.model = &modelSource{reader: r}
+	}
+}
+
+// WithModelData 使用内存中的模型数据（例如通过go:embed嵌入的权重）
+//
+// 模型数据会被复制，调用方之后可以修改或释放data
+func WithModelData(data []byte) Option {
+	return func(c *config) {
+		c.model = &modelSource{data: data}
+	}
+}
+
+// load 读取模型数据；从文件加载时返回路径，由动态库直接读取
+func (m *modelSource) load() (path string, data []byte, err error) {
+	switch {
+	case m.path != "":
+		if _, err := os.Stat(m.path); err != nil {
+			return "", nil, fmt.Errorf("RNNoise模型文件不可用: %v", err)
+		}
+		return m.path, nil, nil
+	case m.reader != nil:
+		data, err := io.ReadAll(m.reader)
+		if err != nil {
+			return "", nil, fmt.Errorf("读取RNNoise模型失败: %v", err)
+		}
+		if len(data) == 0 {
+			return "", nil, fmt.Errorf("RNNoise模型数据为空")
+		}
+		return "", data, nil
+	case len(m.data) > 0:
+		return "", m.data, nil
+	default:
+		return "", nil, fmt.Errorf("RNNoise模型数据为空")
+	}
+}
@@ -0,0 +1,64 @@
+package rnnoise
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/iotest"
+)
+
+func TestModelSourceLoad(t *testing.T) {
+	weights := []byte("model weights")
+
+	cfg := newConfig([]Option{WithModelReader(bytes.NewReader(weights))})
+	path, data, err := cfg.model.load()
+	if err != nil || path != "" || !bytes.Equal(data, weights) {
+		t.Errorf("WithModelReader load() = %q, %q, %v", path, data, err)
+	}
+
+	cfg = newConfig([]Option{WithModelData(weights)})
+	if _, data, err = cfg.model.load(); err != nil || !bytes.Equal(data, weights) {
+		t.Errorf("WithModelData load() = %q, %v", data, err)
+	}
+
+	cfg = newConfig([]Option{WithModelReader(iotest.ErrReader(errors.New("boom")))})
+	if _, _, err = cfg.model.load(); err == nil {
+		t.Error("load() expected error from failing reader")
+	}
+
+	cfg = newConfig([]Option{WithModelFile("does/not/exist.rnnn")})
+	if _, _, err = cfg.model.load(); err == nil {
+		t.Error("load() expected error for missing model file")
+	}
+
+	cfg = newConfig([]Option{WithModelData(nil)})
+	if _, _, err = cfg.model.load(); err == nil {
+		t.Error("load() expected error for empty model data")
+	}
+}
+
+func TestGoModelRejectsModelOptions(t *testing.T) {
+	model := mustSyntheticModel(t)
+	for name, opt := range map[string]Option{
+		"file":   WithModelFile("call_center.rnnn"),
+		"reader": WithModelReader(bytes.NewReader([]byte("model weights"))),
+		"data":   WithModelData([]byte("model weights")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if nf, err := NewNoiseFilter(WithGoModel(model), opt); err == nil {
+				nf.Destroy()
+				t.Error("NewNoiseFilter expected error for WithGoModel with a model option")
+			}
+			if rnn, err := NewRNNoise("", WithGoModel(model), opt); err == nil {
+				rnn.Destroy()
+				t.Error("NewRNNoise expected error for WithGoModel with a model option")
+			}
+		})
+	}
+
+	nf, err := NewNoiseFilter(WithGoModel(model))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nf.Destroy()
+}
@@ -0,0 +1,117 @@
+package rnnoise
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DefaultAttenuationGain OutputAttenuate默认的线性增益（-20dB）
+	DefaultAttenuationGain float32 = 0.1
+	// DefaultComfortNoiseLevel OutputComfortNoise默认的舒适噪声RMS电平（-60dBFS）
+	DefaultComfortNoiseLevel float32 = 0.001
+	// DefaultCrossfade 保留与替换之间切换时默认的交叉淡化时长
+	DefaultCrossfade = 5 * time.Millisecond
+
+	sqrt3 = 1.7320508
+)
+
+// frameShaper 按输出策略输出降噪后的帧
+//
+// 每个样本的输出为 g*降噪样本 + (1-g)*替换样本，保留的帧g为1，低于阈值的帧g为0，
+// 状态切换时g在fadeLen个样本内线性过渡，过渡可以跨越帧边界。
+// g和舒适噪声的随机数状态跨帧保留，同一段音频应使用同一个frameShaper
+type frameShaper struct {
+	policy     OutputPolicy
+	gain       float32 // OutputAttenuate的替换增益
+	noiseLevel float32 // OutputComfortNoise的噪声RMS
+	fadeLen    int     // 交叉淡化的样本数，至少为1
+	pos        int     // g = pos/fadeLen
+	started    bool
+	seed       uint32
+}
+
+// newFrameShaper 按过滤器的输出策略创建frameShaper，用于降噪后端采样率下的帧
+func (nf *NoiseFilter) newFrameShaper() *frameShaper {
+	s := &frameShaper{
+		policy:     nf.outputPolicy,
+		gain:       nf.attenuationGain,
+		noiseLevel: nf.comfortNoiseLevel,
+		fadeLen:    1,
+	}
+	sampleRate := time.Duration(nf.denoiser.SampleRateHz())
+	if fadeLen := int(nf.crossfade * sampleRate / time.Second); fadeLen > 1 {
+		s.fadeLen = fadeLen
+	}
+	s.reset()
+	return s
+}
+
+// reset 开始新的一段音频，第一帧不做淡化
+func (s *frameShaper) reset() {
+	s.started = false
+	s.seed = 0x9e3779b9
+}
+
+// apply 将一帧（可以是不完整的帧）按策略追加到dst
+func (s *frameShaper) apply(dst, frame []float32, keep bool) []float32 {
+	target := 0
+	if keep {
+		target = s.fadeLen
+	}
+	if !s.started {
+		s.pos, s.started = target, true
+	}
+
+	if s.policy == OutputDrop {
+		// 丢弃策略本身会造成不连续，不做淡化
+		if keep {
+			dst = append(dst, frame...)
+		}
+		return dst
+	}
+
+	// 整帧都不需要过渡时走快速路径
+	if s.pos == target {
+		if keep {
+			return append(dst, frame...)
+		}
+		if s.policy == OutputSilence {
+			return append(dst, make([]float32, len(frame))...)
+		}
+	}
+
+	for _, sample := range frame {
+		switch {
+		case s.pos < target:
+			s.pos++
+		case s.pos > target:
+			s.pos--
+		}
+		mix := float32(s.pos) / float32(s.fadeLen)
+		dst = append(dst, mix*sample+(1-mix)*s.replacement(sample))
+	}
+	return dst
+}
+
+// replacement 低于阈值时替代sample输出的样本
+func (s *frameShaper) replacement(sample float32) float32 {
+	switch s.policy {
+	case OutputAttenuate:
+		return s.gain * sample
+	case OutputComfortNoise:
+		return s.noise()
+	default:
+		return 0
+	}
+}
+
+// noise 生成RMS为noiseLevel的均匀分布白噪声（xorshift32，结果可复现）
+func (s *frameShaper) noise() float32 {
+	s.seed ^= s.seed << 13
+	s.seed ^= s.seed >> 17
+	s.seed ^= s.seed << 5
+	uniform := float32(s.seed)/float32(math.MaxUint32)*2 - 1
+	// 均匀分布[-a, a]的RMS为a/sqrt(3)
+	return uniform * s.noiseLevel * sqrt3
+}
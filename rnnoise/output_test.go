@@ -0,0 +1,139 @@
+package rnnoise
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// filterConstant 用语音概率依次为probs的FakeDenoiser处理frames帧恒定为0.2的48kHz音频
+func filterConstant(t *testing.T, frames int, probs []float32, opts ...Option) []float32 {
+	t.Helper()
+	opts = append([]Option{WithDenoiser(NewFakeDenoiser(probs...)), WithThreshold(0.5)}, opts...)
+	filter, err := NewNoiseFilter(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	samples := make([]float32, 480*frames)
+	for i := range samples {
+		samples[i] = 0.2
+	}
+	result, err := filter.FilterAudio(&AudioData{Samples: samples, SampleRate: 48000, Channels: 1, BitDepth: 16}, DefaultThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.DenoisedAudio.Samples
+}
+
+func TestOutputPolicies(t *testing.T) {
+	probs := []float32{0.9, 0.1, 0.1, 0.9}
+
+	if got := filterConstant(t, 4, probs, WithOutputPolicy(OutputDrop)); len(got) != 480*2 {
+		t.Errorf("OutputDrop output = %d samples, want %d", len(got), 480*2)
+	}
+
+	cases := []struct {
+		name   string
+		opts   []Option
+		reject float32 // 低于阈值的帧的输出
+	}{
+		{"silence", []Option{WithOutputPolicy(OutputSilence)}, 0},
+		{"attenuate", []Option{WithOutputPolicy(OutputAttenuate), WithAttenuationGain(0.25)}, 0.05},
+	}
+	for _, tc := range cases {
+		got := filterConstant(t, 4, probs, append(tc.opts, WithCrossfade(0))...)
+		if len(got) != 480*4 {
+			t.Fatalf("%s: output = %d samples, want %d", tc.name, len(got), 480*4)
+		}
+		for i, sample := range got {
+			want := float32(0.2)
+			if i >= 480 && i < 480*3 {
+				want = tc.reject
+			}
+			if math.Abs(float64(sample-want)) > 1e-6 {
+				t.Fatalf("%s: sample %d = %f, want %f", tc.name, i, sample, want)
+			}
+		}
+	}
+}
+
+func TestOutputComfortNoise(t *testing.T) {
+	got := filterConstant(t, 4, []float32{0.1}, WithOutputPolicy(OutputComfortNoise), WithComfortNoiseLevel(0.01))
+	if len(got) != 480*4 {
+		t.Fatalf("output = %d samples, want %d", len(got), 480*4)
+	}
+
+	var sum float64
+	for _, sample := range got {
+		sum += float64(sample) * float64(sample)
+	}
+	if rms := math.Sqrt(sum / float64(len(got))); math.Abs(rms-0.01) > 0.001 {
+		t.Errorf("comfort noise RMS = %f, want 0.01", rms)
+	}
+}
+
+func TestOutputCrossfade(t *testing.T) {
+	// 默认5ms（240个样本）交叉淡化：第二帧开始淡出，第四帧开始淡入
+	got := filterConstant(t, 4, []float32{0.9, 0.1, 0.1, 0.9}, WithOutputPolicy(OutputSilence))
+
+	const fadeLen = 240
+	maxStep := 0.2/fadeLen + 1e-6
+	for i := 1; i < len(got); i++ {
+		if step := math.Abs(float64(got[i] - got[i-1])); step > maxStep {
+			t.Fatalf("step of %f between samples %d and %d, want <= %f", step, i-1, i, maxStep)
+		}
+	}
+	if got[480+fadeLen-1] != 0 || got[480*3-1] != 0 {
+		t.Errorf("fade out did not reach silence: %f, %f", got[480+fadeLen-1], got[480*3-1])
+	}
+	if got[480*3+fadeLen-1] != 0.2 {
+		t.Errorf("fade in did not reach full level: %f", got[480*3+fadeLen-1])
+	}
+}
+
+func TestStreamFilterCrossfadeAcrossChunks(t *testing.T) {
+	run := func(sizes []int) []float32 {
+		sf, err := NewStreamFilter(48000, WithDenoiser(NewFakeDenoiser(0.9, 0.1, 0.9)), WithThreshold(0.5),
+			WithOutputPolicy(OutputComfortNoise), WithCrossfade(20*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sf.Destroy()
+
+		out := processInChunks(sineSamples(480*6, 48000, 440), sizes, func(chunk []float32) []float32 {
+			out, _, err := sf.Process(chunk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return out
+		})
+		tail, _, err := sf.Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(out, tail...)
+	}
+
+	// 20ms的淡化跨越两帧，分块方式不影响结果
+	whole := run([]int{480 * 6})
+	chunked := run([]int{100, 480, 7})
+	if len(whole) != 480*6 || len(chunked) != len(whole) {
+		t.Fatalf("output = %d / %d samples, want %d", len(whole), len(chunked), 480*6)
+	}
+	for i := range whole {
+		if chunked[i] != whole[i] {
+			t.Fatalf("sample %d differs between chunked (%f) and whole (%f) processing", i, chunked[i], whole[i])
+		}
+	}
+}
+
+func TestNoiseFilterRejectsInvalidOutputOptions(t *testing.T) {
+	if _, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser()), WithAttenuationGain(2)); err == nil {
+		t.Error("expected error for attenuation gain above 1")
+	}
+	if _, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser()), WithComfortNoiseLevel(-0.1)); err == nil {
+		t.Error("expected error for negative comfort noise level")
+	}
+}
@@ -0,0 +1,300 @@
+package rnnoise
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// PCMEncoding 原始PCM数据中样本的编码方式
+type PCMEncoding int
+
+const (
+	// PCMInt 有符号整数，BitDepth为16、24或32
+	PCMInt PCMEncoding = iota
+	// PCMFloat IEEE浮点，BitDepth为32或64，范围-1.0到1.0
+	PCMFloat
+	// PCMMuLaw G.711 µ-law，每个样本8位
+	PCMMuLaw
+	// PCMALaw G.711 A-law，每个样本8位
+	PCMALaw
+)
+
+// PCMFormat 原始PCM数据的格式
+//
+// 多声道数据按帧交错存储（L R L R ...）。零值的Encoding和BigEndian表示小端序有符号整数，
+// 与之前只支持s16le/s24le/s32le时的用法兼容；G.711编码不需要设置BitDepth
+type PCMFormat struct {
+	SampleRate int         // 采样率（Hz）
+	Channels   int         // 声道数
+	BitDepth   int         // 位深度（整数为16、24或32，浮点为32或64）
+	Encoding   PCMEncoding // 样本编码
+	BigEndian  bool        // 多字节样本按大端序存储
+}
+
+// pcmFormatNames ParsePCMFormat支持的格式名称（与sox/ffmpeg的命名一致）
+var pcmFormatNames = map[string]PCMFormat{
+	"s16le": {BitDepth: 16},
+	"s16be": {BitDepth: 16, BigEndian: true},
+	"s24le": {BitDepth: 24},
+	"s24be": {BitDepth: 24, BigEndian: true},
+	"s32le": {BitDepth: 32},
+	"s32be": {BitDepth: 32, BigEndian: true},
+	"f32le": {BitDepth: 32, Encoding: PCMFloat},
+	"f32be": {BitDepth: 32, Encoding: PCMFloat, BigEndian: true},
+	"f64le": {BitDepth: 64, Encoding: PCMFloat},
+	"f64be": {BitDepth: 64, Encoding: PCMFloat, BigEndian: true},
+	"mulaw": {BitDepth: 8, Encoding: PCMMuLaw},
+	"ulaw":  {BitDepth: 8, Encoding: PCMMuLaw},
+	"alaw":  {BitDepth: 8, Encoding: PCMALaw},
+}
+
+// ParsePCMFormat 按名称创建PCM格式，例如"s16le"、"s16be"、"f32le"、"mulaw"、"alaw"
+//
+// 示例:
+//
+//	// 8kHz单声道G.711 µ-law电话音频
+//	format, err := ParsePCMFormat("mulaw", 8000, 1)
+func ParsePCMFormat(name string, sampleRate, channels int) (PCMFormat, error) {
+	format, ok := pcmFormatNames[strings.ToLower(name)]
+	if !ok {
+		return PCMFormat{}, fmt.Errorf("未知的PCM格式: %s", name)
+	}
+	format.SampleRate = sampleRate
+	format.Channels = channels
+	return format, format.validate()
+}
+
+// String 返回格式名称，例如"s16le"、"f32be"、"mulaw"
+func (f PCMFormat) String() string {
+	order := "le"
+	if f.BigEndian {
+		order = "be"
+	}
+	switch f.Encoding {
+	case PCMMuLaw:
+		return "mulaw"
+	case PCMALaw:
+		return "alaw"
+	case PCMFloat:
+		return fmt.Sprintf("f%d%s", f.BitDepth, order)
+	default:
+		return fmt.Sprintf("s%d%s", f.BitDepth, order)
+	}
+}
+
+// validate 检查格式是否受支持
+func (f PCMFormat) validate() error {
+	if f.SampleRate <= 0 {
+		return fmt.Errorf("采样率必须大于0，当前为%d", f.SampleRate)
+	}
+	if f.Channels <= 0 {
+		return fmt.Errorf("声道数必须大于0，当前为%d", f.Channels)
+	}
+	switch f.Encoding {
+	case PCMInt:
+		switch f.BitDepth {
+		case 16, 24, 32:
+			return nil
+		}
+	case PCMFloat:
+		switch f.BitDepth {
+		case 32, 64:
+			return nil
+		}
+	case PCMMuLaw, PCMALaw:
+		return nil
+	default:
+		return fmt.Errorf("不支持的PCM编码: %d", f.Encoding)
+	}
+	return fmt.Errorf("不支持的位深度: %d", f.BitDepth)
+}
+
+// bytesPerSample 单个样本的字节数
+func (f PCMFormat) bytesPerSample() int {
+	if f.Encoding == PCMMuLaw || f.Encoding == PCMALaw {
+		return 1
+	}
+	return f.BitDepth / 8
+}
+
+// bytesPerFrame 一个采样帧（所有声道各一个样本）的字节数
+func (f PCMFormat) bytesPerFrame() int {
+	return f.bytesPerSample() * f.Channels
+}
+
+// byteOrder 多字节样本的字节序
+func (f PCMFormat) byteOrder() binary.ByteOrder {
+	if f.BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// Decode 将字节解码为交错的样本（范围-1.0到1.0），末尾不完整的样本会被忽略
+func (f PCMFormat) Decode(data []byte) []float32 {
+	width := f.bytesPerSample()
+	samples := make([]float32, len(data)/width)
+	order := f.byteOrder()
+	for i := range samples {
+		b := data[i*width : (i+1)*width]
+		switch {
+		case f.Encoding == PCMMuLaw:
+			samples[i] = float32(muLawToLinear(b[0])) / 32768
+		case f.Encoding == PCMALaw:
+			samples[i] = float32(aLawToLinear(b[0])) / 32768
+		case f.Encoding == PCMFloat && width == 4:
+			samples[i] = math.Float32frombits(order.Uint32(b))
+		case f.Encoding == PCMFloat:
+			samples[i] = float32(math.Float64frombits(order.Uint64(b)))
+		case width == 2:
+			samples[i] = float32(int16(order.Uint16(b))) / 32768
+		case width == 3:
+			if f.BigEndian {
+				b = []byte{b[2], b[1], b[0]}
+			}
+			samples[i] = float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / 8388608
+		default:
+			samples[i] = float32(float64(int32(order.Uint32(b))) / 2147483648)
+		}
+	}
+	return samples
+}
+
+// Encode 将交错的样本（范围-1.0到1.0）编码为字节，整数和G.711编码会限幅并四舍五入
+func (f PCMFormat) Encode(samples []float32) []byte {
+	width := f.bytesPerSample()
+	data := make([]byte, len(samples)*width)
+	order := f.byteOrder()
+	for i, sample := range samples {
+		b := data[i*width : (i+1)*width]
+		switch {
+		case f.Encoding == PCMMuLaw:
+			b[0] = linearToMuLaw(int16(quantize(sample, 16)))
+		case f.Encoding == PCMALaw:
+			b[0] = linearToALaw(int16(quantize(sample, 16)))
+		case f.Encoding == PCMFloat && width == 4:
+			order.PutUint32(b, math.Float32bits(sample))
+		case f.Encoding == PCMFloat:
+			order.PutUint64(b, math.Float64bits(float64(sample)))
+		case width == 2:
+			order.PutUint16(b, uint16(quantize(sample, 16)))
+		case width == 3:
+			v := quantize(sample, 24)
+			b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+			if f.BigEndian {
+				b[0], b[2] = b[2], b[0]
+			}
+		default:
+			order.PutUint32(b, uint32(quantize(sample, 32)))
+		}
+	}
+	return data
+}
+
+// decode 将整数个采样帧的字节解码并混合为单声道样本
+func (f PCMFormat) decode(data []byte) []float32 {
+	return downmix(f.Decode(data), f.Channels)
+}
+
+// encode 将单声道样本编码为该格式，多声道时每个声道输出相同的样本
+func (f PCMFormat) encode(mono []float32) []byte {
+	if f.Channels == 1 {
+		return f.Encode(mono)
+	}
+
+	samples := make([]float32, len(mono)*f.Channels)
+	for i, sample := range mono {
+		for ch := 0; ch < f.Channels; ch++ {
+			samples[i*f.Channels+ch] = sample
+		}
+	}
+	return f.Encode(samples)
+}
+
+// G.711 µ-law的偏置和限幅值（ITU-T G.711）
+const (
+	muLawBias = 0x84
+	muLawClip = 32635
+)
+
+// muLawToLinear 将G.711 µ-law码字解码为16位线性样本
+func muLawToLinear(u byte) int16 {
+	u = ^u
+	exponent := (u >> 4) & 0x07
+	mantissa := int(u & 0x0F)
+	sample := ((mantissa << 3) + muLawBias) << exponent
+	sample -= muLawBias
+	if u&0x80 != 0 {
+		return int16(-sample)
+	}
+	return int16(sample)
+}
+
+// linearToMuLaw 将16位线性样本编码为G.711 µ-law码字
+func linearToMuLaw(sample int16) byte {
+	s := int(sample)
+	var sign byte
+	if s < 0 {
+		sign = 0x80
+		s = -s
+	}
+	if s > muLawClip {
+		s = muLawClip
+	}
+	s += muLawBias
+
+	exponent := byte(7)
+	for mask := 0x4000; s&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := byte(s>>(exponent+3)) & 0x0F
+	return ^(sign | exponent<<4 | mantissa)
+}
+
+// aLawToLinear 将G.711 A-law码字解码为16位线性样本
+func aLawToLinear(a byte) int16 {
+	a ^= 0x55
+	t := int(a&0x0F) << 4
+	switch segment := (a & 0x70) >> 4; segment {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= segment - 1
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+// linearToALaw 将16位线性样本编码为G.711 A-law码字
+func linearToALaw(sample int16) byte {
+	s := int(sample) >> 3 // A-law使用13位精度
+	mask := byte(0xD5)
+	if s < 0 {
+		mask = 0x55
+		s = -s - 1
+	}
+
+	// 第segment段的上界为0x1F, 0x3F, ..., 0xFFF
+	segment := 0
+	for segment < 8 && s > 0x20<<segment-1 {
+		segment++
+	}
+	if segment >= 8 {
+		return 0x7F ^ mask
+	}
+
+	code := byte(segment << 4)
+	if segment < 2 {
+		code |= byte(s>>1) & 0x0F
+	} else {
+		code |= byte(s>>segment) & 0x0F
+	}
+	return code ^ mask
+}
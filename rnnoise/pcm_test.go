@@ -0,0 +1,159 @@
+package rnnoise
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestPCMFormatRoundTrip(t *testing.T) {
+	for _, name := range []string{"s16le", "s16be", "s24le", "s24be", "s32le", "s32be", "f32le", "f32be", "f64le", "f64be"} {
+		format, err := ParsePCMFormat(name, 8000, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format.String() != name {
+			t.Errorf("String() = %q, want %q", format.String(), name)
+		}
+
+		in := []float32{0, 0.5, -0.5, -1, 0.25, -0.125}
+		data := format.Encode(in)
+		if len(data) != len(in)*format.BitDepth/8 {
+			t.Fatalf("%s: encoded %d bytes", name, len(data))
+		}
+		out := format.Decode(data)
+		for i := range in {
+			if out[i] != in[i] {
+				t.Errorf("%s: sample %d = %g, want %g", name, i, out[i], in[i])
+			}
+		}
+	}
+}
+
+func TestPCMFormatByteOrder(t *testing.T) {
+	le, _ := ParsePCMFormat("s16le", 8000, 1)
+	be, _ := ParsePCMFormat("s16be", 8000, 1)
+	if got := le.Encode([]float32{0.5}); !bytes.Equal(got, []byte{0x00, 0x40}) {
+		t.Errorf("s16le 0.5 = % x", got)
+	}
+	if got := be.Encode([]float32{0.5}); !bytes.Equal(got, []byte{0x40, 0x00}) {
+		t.Errorf("s16be 0.5 = % x", got)
+	}
+	s24be, _ := ParsePCMFormat("s24be", 8000, 1)
+	if got := s24be.Decode([]byte{0xC0, 0x00, 0x00}); got[0] != -0.5 {
+		t.Errorf("s24be decode = %g, want -0.5", got[0])
+	}
+	f32be, _ := ParsePCMFormat("f32be", 8000, 1)
+	if got := f32be.Encode([]float32{1}); !bytes.Equal(got, []byte{0x3F, 0x80, 0x00, 0x00}) {
+		t.Errorf("f32be 1.0 = % x", got)
+	}
+}
+
+func TestG711(t *testing.T) {
+	for _, name := range []string{"mulaw", "alaw"} {
+		format, err := ParsePCMFormat(name, 8000, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 每个码字解码后再编码应得到原码字（µ-law的0x7F与0xFF都表示0）
+		codes := make([]byte, 256)
+		for i := range codes {
+			codes[i] = byte(i)
+		}
+		decoded := format.Decode(codes)
+		encoded := format.Encode(decoded)
+		for i, code := range codes {
+			if encoded[i] != code && !(name == "mulaw" && code == 0x7F) {
+				t.Errorf("%s: code %#02x -> %g -> %#02x", name, code, decoded[i], encoded[i])
+			}
+		}
+
+		// 量化误差相对于幅度应在对数压扩的精度之内
+		for _, v := range []float32{0.001, -0.01, 0.1, -0.3, 0.7, -0.95} {
+			got := format.Decode(format.Encode([]float32{v}))[0]
+			if math.Abs(float64(got-v)) > math.Abs(float64(v))*0.07+0.0005 {
+				t.Errorf("%s: %g -> %g", name, v, got)
+			}
+		}
+		// 超出范围的样本被限幅
+		if got := format.Decode(format.Encode([]float32{2}))[0]; got < 0.9 || got > 1 {
+			t.Errorf("%s: clipped 2.0 -> %g", name, got)
+		}
+	}
+
+	// ITU-T G.711的参考值
+	if code := linearToMuLaw(0); code != 0xFF {
+		t.Errorf("µ-law(0) = %#02x, want 0xff", code)
+	}
+	if code := linearToALaw(0); code != 0xD5 {
+		t.Errorf("A-law(0) = %#02x, want 0xd5", code)
+	}
+	if v := muLawToLinear(0x00); v != -32124 {
+		t.Errorf("µ-law 0x00 = %d, want -32124", v)
+	}
+	if v := aLawToLinear(0xAA); v != 32256 {
+		t.Errorf("A-law 0xaa = %d, want 32256", v)
+	}
+}
+
+func TestParsePCMFormatErrors(t *testing.T) {
+	if _, err := ParsePCMFormat("s12le", 8000, 1); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if _, err := ParsePCMFormat("mulaw", 0, 1); err == nil {
+		t.Error("expected error for zero sample rate")
+	}
+	if err := (PCMFormat{SampleRate: 8000, Channels: 1, BitDepth: 16, Encoding: PCMFloat}).validate(); err == nil {
+		t.Error("expected error for 16-bit float")
+	}
+}
+
+func TestFilterAudioBytesFormat(t *testing.T) {
+	filter, err := NewNoiseFilterWithDenoiser(NewFakeDenoiser(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	format, _ := ParsePCMFormat("alaw", 48000, 1)
+	input := format.Encode(sineSamples(480*4, 48000, 300))
+	output, voiceProbs, err := filter.FilterAudioBytesFormat(input, format, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(voiceProbs) != 4 || !bytes.Equal(output, input) {
+		t.Errorf("A-law passthrough changed the payload (%d probs)", len(voiceProbs))
+	}
+
+	if _, _, err := filter.FilterAudioBytes(input, 48000, 1, 12, 0.5); err == nil {
+		t.Error("expected error for unsupported bit depth")
+	}
+}
+
+func TestDenoiseReaderMuLaw(t *testing.T) {
+	format, _ := ParsePCMFormat("mulaw", 8000, 1)
+	input := format.Encode(sineSamples(8000, 8000, 250))
+
+	reader, err := NewDenoiseReader(bytes.NewReader(input), format, WithDenoiser(NewFakeDenoiser(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output) != len(input) {
+		t.Fatalf("output %d bytes, want %d", len(output), len(input))
+	}
+
+	// 跳过重采样滤波器的边界，比较解码后的波形
+	in, out := format.Decode(input), format.Decode(output)
+	for i := 64; i < len(in)-64; i++ {
+		if math.Abs(float64(out[i]-in[i])) > 0.05 {
+			t.Fatalf("sample %d = %f, want %f", i, out[i], in[i])
+		}
+	}
+}
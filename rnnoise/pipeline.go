@@ -0,0 +1,220 @@
+package rnnoise
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Block 流水线中传递的一块音频
+//
+// 样本按声道分开存放（平面格式），各声道等长；Timestamp为第一个样本在流中的时间，
+// 改变长度或带有延迟的阶段按输出样本数推算输出块的时间戳
+type Block struct {
+	Samples    [][]float32   // 每个声道的样本（范围-1.0到1.0）
+	SampleRate int           // 采样率
+	Timestamp  time.Duration // 第一个样本在流中的时间
+	VoiceProbs []float32     // 降噪阶段处理的每一帧的语音概率，其他阶段原样传递
+}
+
+// Channels 声道数
+func (b *Block) Channels() int {
+	return len(b.Samples)
+}
+
+// Len 每个声道的样本数
+func (b *Block) Len() int {
+	if len(b.Samples) == 0 {
+		return 0
+	}
+	return len(b.Samples[0])
+}
+
+// Duration 块的时长
+func (b *Block) Duration() time.Duration {
+	if b.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(b.Len()) * time.Second / time.Duration(b.SampleRate)
+}
+
+// Stage 流水线中的一个处理阶段
+//
+// 阶段可以缓存样本（例如凑满一帧、重采样或前瞻），Process只返回目前可以输出的部分，
+// 流结束时由Flush取回剩余部分。阶段可以原地修改输入块；实现io.Closer的阶段在Pipeline.Close时关闭。
+// 阶段不是并发安全的
+type Stage interface {
+	// Process 处理一块音频，返回目前可以输出的部分，没有输出时返回nil
+	Process(block *Block) (*Block, error)
+	// Flush 输出缓存的剩余部分（没有时返回nil），之后开始新的一段流
+	Flush() (*Block, error)
+	// Reset 丢弃缓存的样本和状态，开始新的一段流
+	Reset() error
+}
+
+// Pipeline 按顺序执行的处理阶段
+//
+// Pipeline本身也实现了Stage，可以嵌套组合。示例:
+//
+//	denoise, err := NewDenoiseStage(WithOutputPolicy(OutputSilence))
+//	if err != nil {
+//		return err
+//	}
+//	tap := NewTapStage(func(b *Block) { log.Println(b.Timestamp, b.VoiceProbs) })
+//	p := NewPipeline(denoise, tap, NewGainStage(6))
+//	defer p.Close()
+//	out, err := p.ProcessAudio(audioData)
+type Pipeline struct {
+	stages []Stage
+}
+
+// NewPipeline 创建依次执行stages的流水线
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Stages 流水线的各个阶段
+func (p *Pipeline) Stages() []Stage {
+	return p.stages
+}
+
+// Process 依次经过所有阶段处理一块音频，返回目前可以输出的部分，没有输出时返回nil
+func (p *Pipeline) Process(block *Block) (*Block, error) {
+	for _, stage := range p.stages {
+		if block == nil {
+			return nil, nil
+		}
+		var err error
+		if block, err = stage.Process(block); err != nil {
+			return nil, err
+		}
+	}
+	return block, nil
+}
+
+// Flush 依次结束各个阶段：前面阶段取回的剩余部分先经过后面的阶段处理，再结束后面的阶段
+func (p *Pipeline) Flush() (*Block, error) {
+	var carry *Block
+	for _, stage := range p.stages {
+		if carry != nil {
+			var err error
+			if carry, err = stage.Process(carry); err != nil {
+				return nil, err
+			}
+		}
+		tail, err := stage.Flush()
+		if err != nil {
+			return nil, err
+		}
+		carry = joinBlocks(carry, tail)
+	}
+	return carry, nil
+}
+
+// Reset 重置所有阶段
+func (p *Pipeline) Reset() error {
+	for _, stage := range p.stages {
+		if err := stage.Reset(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭实现了io.Closer的阶段（例如释放降噪后端），返回遇到的第一个错误
+func (p *Pipeline) Close() error {
+	var first error
+	for _, stage := range p.stages {
+		if closer, ok := stage.(io.Closer); ok {
+			if err := closer.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// ProcessAudio 将整段音频作为一个流处理并结束，输出为交织格式，位深与输入相同
+//
+// 输入不会被修改；所有样本都被丢弃时返回采样率和声道数与输入相同的空音频
+func (p *Pipeline) ProcessAudio(audioData *AudioData) (*AudioData, error) {
+	if audioData.SampleRate <= 0 || audioData.Channels <= 0 {
+		return nil, fmt.Errorf("无效的音频格式: %dHz, %d声道", audioData.SampleRate, audioData.Channels)
+	}
+
+	var samples [][]float32
+	if audioData.Channels == 1 {
+		samples = [][]float32{append([]float32(nil), audioData.Samples...)}
+	} else {
+		samples = deinterleave(audioData.Samples, audioData.Channels)
+	}
+	out, err := p.Process(&Block{Samples: samples, SampleRate: audioData.SampleRate})
+	if err != nil {
+		return nil, err
+	}
+	tail, err := p.Flush()
+	if err != nil {
+		return nil, err
+	}
+	out = joinBlocks(out, tail)
+
+	result := &AudioData{
+		SampleRate: audioData.SampleRate,
+		Channels:   audioData.Channels,
+		BitDepth:   audioData.BitDepth,
+		Float:      audioData.Float,
+	}
+	if out == nil || out.Channels() == 0 {
+		return result, nil
+	}
+	result.SampleRate = out.SampleRate
+	result.Channels = out.Channels()
+	if result.Channels == 1 {
+		result.Samples = out.Samples[0]
+	} else {
+		result.Samples = interleave(out.Samples)
+	}
+	return result, nil
+}
+
+// joinBlocks 将b接在a之后，任一为nil时返回另一个
+func joinBlocks(a, b *Block) *Block {
+	if a == nil || a.Channels() == 0 {
+		return b
+	}
+	if b == nil || b.Channels() == 0 {
+		return a
+	}
+	for ch := range a.Samples {
+		a.Samples[ch] = append(a.Samples[ch], b.Samples[ch]...)
+	}
+	a.VoiceProbs = append(a.VoiceProbs, b.VoiceProbs...)
+	return a
+}
+
+// timeline 按已输出的样本数推算输出块的时间戳
+type timeline struct {
+	origin  time.Duration // 流中第一个输入块的时间戳
+	started bool
+	samples int64 // 已输出的样本数
+}
+
+// start 记录流中第一个输入块的时间戳
+func (t *timeline) start(block *Block) {
+	if !t.started {
+		t.origin = block.Timestamp
+		t.started = true
+	}
+}
+
+// next 返回接下来n个输出样本的起始时间
+func (t *timeline) next(n, sampleRate int) time.Duration {
+	ts := t.origin + time.Duration(t.samples)*time.Second/time.Duration(sampleRate)
+	t.samples += int64(n)
+	return ts
+}
+
+// reset 开始新的一段流
+func (t *timeline) reset() {
+	*t = timeline{}
+}
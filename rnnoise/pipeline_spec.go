@@ -0,0 +1,229 @@
+package rnnoise
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PipelineSpec 流水线的声明式描述
+//
+// 描述可以是JSON或YAML格式，分别用ParsePipelineSpec和ParsePipelineSpecYAML解码，
+// LoadPipelineSpec按文件扩展名选择。JSON示例:
+//
+//	{
+//	  "stages": [
+//	    {"type": "denoise", "threshold": 0.5, "output_policy": "silence", "mix": 0.9},
+//	    {"type": "gate", "threshold_db": -50, "hold_ms": 200},
+//	    {"type": "gain", "gain_db": 6},
+//	    {"type": "limiter", "ceiling_db": -1},
+//	    {"type": "resample", "sample_rate": 16000}
+//	  ]
+//	}
+//
+// 等价的YAML:
+//
+//	stages:
+//	  - type: denoise
+//	    threshold: 0.5
+//	    output_policy: silence
+//	    mix: 0.9
+//	  - {type: gate, threshold_db: -50, hold_ms: 200}
+//	  - {type: gain, gain_db: 6}
+//	  - {type: limiter, ceiling_db: -1}
+//	  - {type: resample, sample_rate: 16000}
+//
+// YAML只支持描述所需的子集：块映射和序列、单行的流式集合、带引号或不带引号的标量以及注释，
+// 不支持锚点、别名、标签和多行标量
+type PipelineSpec struct {
+	Stages []StageSpec `json:"stages" yaml:"stages"`
+}
+
+// StageSpec 一个阶段的描述，Type决定使用哪些字段，其他字段被忽略
+type StageSpec struct {
+	// Type 阶段类型: resample、channel_mix、denoise、gate、gain、loudness、limiter、tap
+	Type string `json:"type" yaml:"type"`
+
+	// resample: 目标采样率和转换器（polyphase（默认）或linear）
+	SampleRate int    `json:"sample_rate,omitempty" yaml:"sample_rate,omitempty"`
+	Resampler  string `json:"resampler,omitempty" yaml:"resampler,omitempty"`
+
+	// channel_mix: 目标声道数
+	Channels int `json:"channels,omitempty" yaml:"channels,omitempty"`
+
+	// denoise: 对应WithThreshold、WithOutputPolicy（drop、silence、attenuate、comfort）、
+	// WithChannelMode（downmix、independent、linked）、WithMix和WithMaxAttenuation
+	Threshold      *float32 `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	OutputPolicy   string   `json:"output_policy,omitempty" yaml:"output_policy,omitempty"`
+	ChannelMode    string   `json:"channel_mode,omitempty" yaml:"channel_mode,omitempty"`
+	Mix            *float32 `json:"mix,omitempty" yaml:"mix,omitempty"`
+	MaxAttenuation float32  `json:"max_attenuation,omitempty" yaml:"max_attenuation,omitempty"`
+
+	// gate: 阈值（dBFS）和保持时间（毫秒）
+	ThresholdDB float64 `json:"threshold_db,omitempty" yaml:"threshold_db,omitempty"`
+	HoldMS      float64 `json:"hold_ms,omitempty" yaml:"hold_ms,omitempty"`
+
+	// gain: 增益（dB）
+	GainDB float64 `json:"gain_db,omitempty" yaml:"gain_db,omitempty"`
+
+	// loudness: 目标积分响度（LUFS），整段流在Flush时一次输出
+	TargetLUFS float64 `json:"target_lufs,omitempty" yaml:"target_lufs,omitempty"`
+
+	// limiter: 真峰值上限（dBTP），默认DefaultTruePeakCeiling
+	CeilingDB *float64 `json:"ceiling_db,omitempty" yaml:"ceiling_db,omitempty"`
+
+	// tap: Build时taps中对应的函数名
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+// pipelineOutputPolicies 描述中输出策略的名称，与rnnoise-cli一致
+var pipelineOutputPolicies = map[string]OutputPolicy{
+	"drop":      OutputDrop,
+	"silence":   OutputSilence,
+	"attenuate": OutputAttenuate,
+	"comfort":   OutputComfortNoise,
+}
+
+// pipelineChannelModes 描述中多声道处理方式的名称
+var pipelineChannelModes = map[string]ChannelMode{
+	"downmix":     ChannelDownmix,
+	"independent": ChannelIndependent,
+	"linked":      ChannelLinked,
+}
+
+// ParsePipelineSpec 解码JSON格式的流水线描述，未知的字段视为错误
+func ParsePipelineSpec(data []byte) (*PipelineSpec, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var spec PipelineSpec
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("解析流水线描述失败: %v", err)
+	}
+	return &spec, nil
+}
+
+// ParsePipelineSpecYAML 解码YAML格式的流水线描述，与ParsePipelineSpec一样未知的字段视为错误
+func ParsePipelineSpecYAML(data []byte) (*PipelineSpec, error) {
+	value, err := decodeYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析流水线描述失败: %v", err)
+	}
+	// 转换为JSON后按相同的规则解码，两种格式的字段名和校验保持一致
+	data, err = json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("解析流水线描述失败: %v", err)
+	}
+	return ParsePipelineSpec(data)
+}
+
+// LoadPipelineSpec 读取并解码流水线描述文件，扩展名为.yaml或.yml时按YAML解码，否则按JSON解码
+func LoadPipelineSpec(filename string) (*PipelineSpec, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("读取流水线描述失败: %v", err)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return ParsePipelineSpecYAML(data)
+	}
+	return ParsePipelineSpec(data)
+}
+
+// Build 按描述创建流水线
+//
+// taps为tap阶段按名称使用的函数；opts在每个denoise阶段的描述之前应用，
+// 用于指定降噪后端等无法在描述中表达的选项
+func (s *PipelineSpec) Build(taps map[string]func(*Block), opts ...Option) (*Pipeline, error) {
+	if len(s.Stages) == 0 {
+		return nil, fmt.Errorf("流水线描述中没有阶段")
+	}
+
+	stages := make([]Stage, 0, len(s.Stages))
+	for i, spec := range s.Stages {
+		stage, err := spec.build(taps, opts)
+		if err != nil {
+			NewPipeline(stages...).Close()
+			return nil, fmt.Errorf("第%d个阶段（%s）: %v", i+1, spec.Type, err)
+		}
+		stages = append(stages, stage)
+	}
+	return NewPipeline(stages...), nil
+}
+
+// build 创建一个阶段
+func (s StageSpec) build(taps map[string]func(*Block), opts []Option) (Stage, error) {
+	switch s.Type {
+	case "resample":
+		var resampler Resampler
+		switch s.Resampler {
+		case "", "polyphase":
+			resampler = PolyphaseResampler{}
+		case "linear":
+			resampler = LinearResampler{}
+		default:
+			return nil, fmt.Errorf("未知的转换器: %s", s.Resampler)
+		}
+		return NewResampleStage(s.SampleRate, resampler)
+	case "channel_mix":
+		return NewChannelMixStage(s.Channels)
+	case "denoise":
+		stageOpts, err := s.denoiseOptions()
+		if err != nil {
+			return nil, err
+		}
+		return NewDenoiseStage(append(append([]Option(nil), opts...), stageOpts...)...)
+	case "gate":
+		return NewGateStage(s.ThresholdDB, time.Duration(s.HoldMS*float64(time.Millisecond)))
+	case "gain":
+		return NewGainStage(s.GainDB), nil
+	case "loudness":
+		return NewLoudnessStage(s.TargetLUFS)
+	case "limiter":
+		ceiling := DefaultTruePeakCeiling
+		if s.CeilingDB != nil {
+			ceiling = *s.CeilingDB
+		}
+		return NewLimiterStage(ceiling)
+	case "tap":
+		fn, ok := taps[s.Name]
+		if !ok {
+			return nil, fmt.Errorf("未提供名为%q的tap函数", s.Name)
+		}
+		return NewTapStage(fn), nil
+	default:
+		return nil, fmt.Errorf("未知的阶段类型: %q", s.Type)
+	}
+}
+
+// denoiseOptions 将denoise阶段的描述转换为过滤器选项
+func (s StageSpec) denoiseOptions() ([]Option, error) {
+	var opts []Option
+	if s.Threshold != nil {
+		opts = append(opts, WithThreshold(*s.Threshold))
+	}
+	if s.OutputPolicy != "" {
+		policy, ok := pipelineOutputPolicies[s.OutputPolicy]
+		if !ok {
+			return nil, fmt.Errorf("未知的输出策略: %s", s.OutputPolicy)
+		}
+		opts = append(opts, WithOutputPolicy(policy))
+	}
+	if s.ChannelMode != "" {
+		mode, ok := pipelineChannelModes[s.ChannelMode]
+		if !ok {
+			return nil, fmt.Errorf("未知的声道处理方式: %s", s.ChannelMode)
+		}
+		opts = append(opts, WithChannelMode(mode))
+	}
+	if s.Mix != nil {
+		opts = append(opts, WithMix(*s.Mix))
+	}
+	if s.MaxAttenuation != 0 {
+		opts = append(opts, WithMaxAttenuation(s.MaxAttenuation))
+	}
+	return opts, nil
+}
@@ -0,0 +1,414 @@
+package rnnoise
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNoiseFilterPipelineMatchesFilterAudio(t *testing.T) {
+	input := tone(16000*2+77, 16000, 300, -30)
+	input = append(input, tone(8000, 16000, 300, 0)...) // 需要限幅的部分
+	for _, channels := range []int{1, 2} {
+		opts := func(created *[]*FakeDenoiser) []Option {
+			return []Option{WithDenoiserFactory(fakeFactory(created, 0.9, 0.3)), WithChannelMode(ChannelLinked),
+				WithOutputPolicy(OutputAttenuate), WithThreshold(0.5), WithAGC(DefaultAGCConfig())}
+		}
+		audioData := &AudioData{Samples: input, SampleRate: 16000, Channels: 1, BitDepth: 16}
+		if channels == 2 {
+			audioData = &AudioData{Samples: interleave([][]float32{input, input}), SampleRate: 16000, Channels: 2, BitDepth: 16}
+		}
+
+		var created []*FakeDenoiser
+		filter, err := NewNoiseFilter(opts(&created)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer filter.Destroy()
+		want, err := filter.FilterAudio(audioData, DefaultThreshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var pipelineCreated []*FakeDenoiser
+		p, err := NewNoiseFilterPipeline(opts(&pipelineCreated)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		got, err := p.ProcessAudio(audioData)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Channels != channels || len(got.Samples) != len(want.DenoisedAudio.Samples) {
+			t.Fatalf("%d channels: pipeline output %d channels %d samples, want %d samples",
+				channels, got.Channels, len(got.Samples), len(want.DenoisedAudio.Samples))
+		}
+		for i, sample := range want.DenoisedAudio.Samples {
+			if math.Abs(float64(got.Samples[i]-sample)) > 1e-5 {
+				t.Fatalf("%d channels: sample %d = %f, want %f", channels, i, got.Samples[i], sample)
+			}
+		}
+	}
+}
+
+// batchOnlyResampler 隐藏LinearResampler的流式接口
+type batchOnlyResampler struct {
+	resampler Resampler
+}
+
+func (r batchOnlyResampler) Resample(samples []float32, fromRate, toRate int) []float32 {
+	return r.resampler.Resample(samples, fromRate, toRate)
+}
+
+func TestFilterAudioRunsOnPipeline(t *testing.T) {
+	input := tone(16000*3+77, 16000, 300, -30)
+	audioData := &AudioData{Samples: input, SampleRate: 16000, Channels: 1, BitDepth: 16}
+	filterAudio := func(opts ...Option) []float32 {
+		t.Helper()
+		filter, err := NewNoiseFilter(append([]Option{WithDenoiser(NewFakeDenoiser(0.9, 0.3)),
+			WithOutputPolicy(OutputSilence), WithThreshold(0.5)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer filter.Destroy()
+		result, err := filter.FilterAudio(audioData, DefaultThreshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.DenoisedAudio.Samples
+	}
+	same := func(name string, got, want []float32) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: %d samples, want %d", name, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%s: sample %d = %v, want %v", name, i, got[i], want[i])
+			}
+		}
+	}
+
+	// 响度归一化是降噪之后、限幅之前的一个阶段
+	denoise, err := NewDenoiseStage(WithDenoiser(NewFakeDenoiser(0.9, 0.3)), WithOutputPolicy(OutputSilence), WithThreshold(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loudness, err := NewLoudnessStage(-20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, _ := NewLimiterStage(DefaultTruePeakCeiling)
+	p := NewPipeline(denoise, loudness, limiter)
+	defer p.Close()
+	want, err := p.ProcessAudio(audioData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same("loudness", filterAudio(WithLoudnessNormalization(-20)), want.Samples)
+
+	// 未实现StreamResamplerFactory的转换器按整段转换，结果与对应的流式转换器一致
+	same("resampler", filterAudio(WithResampler(batchOnlyResampler{LinearResampler{}})), filterAudio(WithResampler(LinearResampler{})))
+
+	if _, err := NewLoudnessStage(1); err == nil {
+		t.Error("NewLoudnessStage expected error for a positive target")
+	}
+}
+
+func TestStreamFilterRunsOnPipeline(t *testing.T) {
+	sf, err := NewStreamFilter(16000, WithDenoiser(NewFakeDenoiser(0.9)), WithOutputPolicy(OutputSilence),
+		WithTruePeakLimiter(-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Destroy()
+
+	// 降噪阶段内不限幅，限幅由其后的LimiterStage完成
+	stages := sf.pipeline.Stages()
+	if _, ok := stages[len(stages)-1].(*limiterStage); !ok || sf.denoise.opts.limit {
+		t.Fatalf("pipeline stages %T, denoise limiter %v", stages, sf.denoise.opts.limit)
+	}
+
+	input := tone(16000/2+33, 16000, 300, 0)
+	run := func() []float32 {
+		out := processInChunks(input, []int{160, 999}, func(chunk []float32) []float32 {
+			out, _, err := sf.Process(chunk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return out
+		})
+		tail, _, err := sf.Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(out, tail...)
+	}
+
+	first := run()
+	if len(first) != len(input) {
+		t.Fatalf("output %d samples, want %d", len(first), len(input))
+	}
+	// Flush之后开始新的一段流，结果与第一段相同
+	second := run()
+	for i := range first {
+		if second[i] != first[i] {
+			t.Fatalf("sample %d after Flush = %f, want %f", i, second[i], first[i])
+		}
+	}
+}
+
+func TestPipelineTimestamps(t *testing.T) {
+	resample, err := NewResampleStage(16000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, err := NewLimiterStage(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []*Block
+	p := NewPipeline(resample, limiter, NewTapStage(func(b *Block) { seen = append(seen, b) }))
+
+	// 从流中5秒处开始，每块10ms（48kHz）
+	start := 5 * time.Second
+	input := sineSamples(48000, 48000, 440)
+	var out []*Block
+	for i := 0; i < len(input); i += 480 {
+		block := &Block{Samples: [][]float32{input[i : i+480]}, SampleRate: 48000, Timestamp: start + time.Duration(i)*time.Second/48000}
+		got, err := p.Process(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != nil {
+			out = append(out, got)
+		}
+	}
+	tail, err := p.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = append(out, tail)
+
+	// 输出块首尾相接，时间戳按输出样本数推算
+	total := 0
+	for i, block := range out {
+		if block.SampleRate != 16000 {
+			t.Fatalf("block %d sample rate %d, want 16000", i, block.SampleRate)
+		}
+		if want := start + time.Duration(total)*time.Second/16000; block.Timestamp != want {
+			t.Fatalf("block %d timestamp %v, want %v", i, block.Timestamp, want)
+		}
+		total += block.Len()
+	}
+	if total != 16000 {
+		t.Errorf("output %d samples, want 16000", total)
+	}
+	if len(seen) != len(out) {
+		t.Errorf("tap saw %d blocks, want %d", len(seen), len(out))
+	}
+}
+
+func TestChannelMixStage(t *testing.T) {
+	mono, err := NewChannelMixStage(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := mono.Process(&Block{Samples: [][]float32{{0.2, 0.4}, {0.4, 0}}, SampleRate: 8000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Channels() != 1 || math.Abs(float64(out.Samples[0][0]-0.3)) > 1e-6 || math.Abs(float64(out.Samples[0][1]-0.2)) > 1e-6 {
+		t.Errorf("downmix = %v, want [[0.3 0.2]]", out.Samples)
+	}
+
+	stereo, err := NewChannelMixStage(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = stereo.Process(&Block{Samples: [][]float32{{0.5}}, SampleRate: 8000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Channels() != 2 || out.Samples[1][0] != 0.5 {
+		t.Errorf("upmix = %v, want [[0.5] [0.5]]", out.Samples)
+	}
+	if _, err := stereo.Process(&Block{Samples: make([][]float32, 3), SampleRate: 8000}); err == nil {
+		t.Error("expected error for mixing 3 channels to 2")
+	}
+}
+
+func TestGateStage(t *testing.T) {
+	gate, err := NewGateStage(-40, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1秒-20dBFS的语音之后是1秒-60dBFS的噪声
+	samples := append(tone(48000, 48000, 440, -20), tone(48000, 48000, 440, -60)...)
+	out, err := gate.Process(&Block{Samples: [][]float32{samples}, SampleRate: 48000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level := rmsDB(out.Samples[0][4800:48000]); math.Abs(level-rmsDB(tone(48000, 48000, 440, -20))) > 0.1 {
+		t.Errorf("open gate level %.1f dBFS, want unchanged", level)
+	}
+	if level := rmsDB(out.Samples[0][48000+9600:]); level > -120 {
+		t.Errorf("closed gate level %.1f dBFS, want silence", level)
+	}
+}
+
+func TestPipelineSpec(t *testing.T) {
+	spec, err := ParsePipelineSpec([]byte(`{
+		"stages": [
+			{"type": "channel_mix", "channels": 1},
+			{"type": "denoise", "threshold": 0.5, "output_policy": "silence"},
+			{"type": "tap", "name": "meter"},
+			{"type": "gain", "gain_db": -6},
+			{"type": "limiter"},
+			{"type": "resample", "sample_rate": 8000, "resampler": "linear"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var voiceProbs []float32
+	taps := map[string]func(*Block){"meter": func(b *Block) { voiceProbs = append(voiceProbs, b.VoiceProbs...) }}
+	// 前半秒是语音，后半秒是噪声
+	probs := make([]float32, 100)
+	for i := range probs {
+		probs[i] = 0.9
+		if i >= 50 {
+			probs[i] = 0.1
+		}
+	}
+	p, err := spec.Build(taps, WithDenoiser(NewFakeDenoiser(probs...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if len(p.Stages()) != 6 {
+		t.Fatalf("%d stages, want 6", len(p.Stages()))
+	}
+
+	left := sineSamples(16000, 16000, 300)
+	input := &AudioData{Samples: interleave([][]float32{left, left}), SampleRate: 16000, Channels: 2, BitDepth: 16}
+	out, err := p.ProcessAudio(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Channels != 1 || out.SampleRate != 8000 || len(out.Samples) != 8000 {
+		t.Fatalf("output %d channels %dHz %d samples, want 1 channel 8000Hz 8000 samples",
+			out.Channels, out.SampleRate, len(out.Samples))
+	}
+	if len(voiceProbs) != 100 {
+		t.Errorf("tap saw %d voice probabilities, want 100", len(voiceProbs))
+	}
+	// 语音衰减6dB，低于阈值的帧被静音
+	if got := peak(out.Samples[800:3200]); math.Abs(got-0.25) > 0.01 {
+		t.Errorf("speech peak %f, want 0.25", got)
+	}
+	if got := peak(out.Samples[4800:]); got != 0 {
+		t.Errorf("silenced peak %f, want 0", got)
+	}
+}
+
+func TestPipelineSpecErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"unknown field", `{"stages": [{"type": "gain", "gain": 3}]}`, "gain"},
+		{"unknown type", `{"stages": [{"type": "reverb"}]}`, "reverb"},
+		{"missing tap", `{"stages": [{"type": "tap", "name": "missing"}]}`, "missing"},
+		{"invalid policy", `{"stages": [{"type": "denoise", "output_policy": "mute"}]}`, "mute"},
+		{"invalid rate", `{"stages": [{"type": "resample"}]}`, "采样率"},
+		{"invalid loudness", `{"stages": [{"type": "loudness"}]}`, "目标响度"},
+		{"empty", `{"stages": []}`, "没有阶段"},
+	}
+	for _, tt := range tests {
+		spec, err := ParsePipelineSpec([]byte(tt.json))
+		if err == nil {
+			_, err = spec.Build(nil, WithDenoiser(NewFakeDenoiser()))
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want mention of %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPipelineSpecYAML(t *testing.T) {
+	want, err := ParsePipelineSpec([]byte(`{
+		"stages": [
+			{"type": "channel_mix", "channels": 1},
+			{"type": "denoise", "threshold": 0.5, "output_policy": "silence", "mix": 0.9},
+			{"type": "tap", "name": "meter"},
+			{"type": "gain", "gain_db": -6},
+			{"type": "limiter", "ceiling_db": -1},
+			{"type": "resample", "sample_rate": 8000, "resampler": "linear"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	yaml := `# 与JSON描述等价
+stages:
+- type: channel_mix
+  channels: 1
+- type: denoise
+  threshold: 0.5
+  output_policy: 'silence'
+  mix: 0.9
+- {type: tap, name: "meter"}
+- type: gain
+  gain_db: -6
+- type: limiter
+  ceiling_db: -1   # dBTP
+- type: resample
+  sample_rate: 8000
+  resampler: linear
+`
+	spec, err := ParsePipelineSpecYAML([]byte(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(spec, want) {
+		t.Errorf("YAML spec %+v, want %+v", spec, want)
+	}
+
+	filename := filepath.Join(t.TempDir(), "pipeline.yml")
+	if err := os.WriteFile(filename, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if spec, err = LoadPipelineSpec(filename); err != nil || !reflect.DeepEqual(spec, want) {
+		t.Errorf("LoadPipelineSpec(%q) = %+v, %v, want %+v", filename, spec, err, want)
+	}
+}
+
+func TestPipelineSpecYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"unknown field", "stages:\n  - type: gain\n    gain: 3\n", "gain"},
+		{"duplicate key", "stages:\n  - type: gain\n    type: gate\n", "重复"},
+		{"tab indent", "stages:\n\t- type: gain\n", "制表符"},
+		{"anchor", "stages:\n  - &gain {type: gain}\n", "锚点"},
+		{"block scalar", "stages:\n  - type: |\n      gain\n", "多行"},
+		{"bad indent", "stages:\n  - type: gain\n   gain_db: 3\n", "第3行"},
+		{"wrong type", "stages:\n  - type: gain\n    gain_db: loud\n", "gain_db"},
+	}
+	for _, tt := range tests {
+		_, err := ParsePipelineSpecYAML([]byte(tt.yaml))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want mention of %q", tt.name, err, tt.want)
+		}
+	}
+}
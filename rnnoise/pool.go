@@ -0,0 +1,191 @@
+package rnnoise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrPoolClosed 过滤器池已关闭
+	ErrPoolClosed = errors.New("过滤器池已关闭")
+	// ErrPoolExhausted 过滤器池中没有空闲的过滤器，且已达到容量上限
+	ErrPoolExhausted = errors.New("过滤器池已满，没有空闲的过滤器")
+)
+
+// FilterPool 并发安全的NoiseFilter池
+//
+// 每个过滤器持有独立的降噪状态，同一时刻只会被借给一个goroutine。
+// 过滤器在首次需要时才创建，总数不超过池的容量；
+// 归还时会重置降噪状态，下一个使用者不会受到上一段音频的影响。
+//
+// 示例:
+//
+//	pool, err := NewFilterPool(runtime.NumCPU(), WithThreshold(0.3))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer pool.Close()
+//
+//	filter, err := pool.Acquire(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	defer pool.Release(filter)
+//	result, err := filter.FilterAudio(audioData, DefaultThreshold)
+type FilterPool struct {
+	newFilter func() (*NoiseFilter, error)
+	slots     chan struct{}     // 已借出的过滤器，容量即池的大小
+	idle      chan *NoiseFilter // 空闲的过滤器
+	done      chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewFilterPool 创建容量为size的过滤器池，每个过滤器使用opts创建
+//
+// 选项与NewNoiseFilter相同，但不能使用WithDenoiser（单个降噪后端不能在过滤器之间共享）；
+// 需要自定义后端时请使用NewFilterPoolFunc。WithModelReader中的模型在创建池时读取一次，由所有过滤器共用
+func NewFilterPool(size int, opts ...Option) (*FilterPool, error) {
+	cfg := newConfig(opts)
+	if cfg.denoiser != nil {
+		return nil, fmt.Errorf("过滤器池不能使用WithDenoiser，请使用NewFilterPoolFunc")
+	}
+
+	// 池中会创建多个过滤器，io.Reader中的模型只能读取一次
+	if cfg.model != nil && cfg.model.reader != nil && cfg.denoiserFactory == nil {
+		_, data, err := cfg.model.load()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(append([]Option(nil), opts...), WithModelData(data))
+	}
+
+	return NewFilterPoolFunc(size, func() (*NoiseFilter, error) {
+		return NewNoiseFilter(opts...)
+	})
+}
+
+// NewFilterPoolFunc 创建容量为size的过滤器池，过滤器由newFilter创建
+//
+// newFilter每次调用都必须返回持有独立降噪后端的新过滤器
+func NewFilterPoolFunc(size int, newFilter func() (*NoiseFilter, error)) (*FilterPool, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("过滤器池容量必须大于0，当前为%d", size)
+	}
+	if newFilter == nil {
+		return nil, fmt.Errorf("过滤器创建函数不能为空")
+	}
+
+	return &FilterPool{
+		newFilter: newFilter,
+		slots:     make(chan struct{}, size),
+		idle:      make(chan *NoiseFilter, size),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// Size 池的容量
+func (p *FilterPool) Size() int {
+	return cap(p.slots)
+}
+
+// Acquire 借出一个过滤器，没有空闲过滤器且已达到容量上限时阻塞等待
+//
+// ctx取消时返回ctx.Err()，池关闭时返回ErrPoolClosed。
+// 使用完毕后必须通过Release归还
+func (p *FilterPool) Acquire(ctx context.Context) (*NoiseFilter, error) {
+	select {
+	case p.slots <- struct{}{}:
+		return p.take()
+	case <-p.done:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// TryAcquire 借出一个过滤器，不阻塞；已达到容量上限时返回ErrPoolExhausted
+func (p *FilterPool) TryAcquire() (*NoiseFilter, error) {
+	select {
+	case p.slots <- struct{}{}:
+		return p.take()
+	default:
+		return nil, ErrPoolExhausted
+	}
+}
+
+// take 在占用一个名额后取出空闲的过滤器，没有空闲的则新建
+func (p *FilterPool) take() (*NoiseFilter, error) {
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		<-p.slots
+		return nil, ErrPoolClosed
+	}
+
+	select {
+	case filter := <-p.idle:
+		return filter, nil
+	default:
+	}
+
+	filter, err := p.newFilter()
+	if err != nil {
+		<-p.slots
+		return nil, fmt.Errorf("创建过滤器失败: %v", err)
+	}
+	return filter, nil
+}
+
+// Release 归还Acquire或TryAcquire借出的过滤器
+//
+// 过滤器的降噪状态会被重置；重置失败或池已关闭时过滤器会被销毁。
+// 归还之后调用方不能再使用该过滤器
+func (p *FilterPool) Release(filter *NoiseFilter) {
+	if filter == nil {
+		return
+	}
+	defer func() { <-p.slots }()
+
+	if err := filter.Reset(); err != nil {
+		filter.logger.Warnf("重置过滤器失败，将其销毁: %v", err)
+		filter.Destroy()
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		filter.Destroy()
+		return
+	}
+	// 空闲的过滤器不会超过容量，写入不会阻塞
+	p.idle <- filter
+}
+
+// Close 关闭过滤器池，销毁所有空闲的过滤器
+//
+// 正在被借用的过滤器会在归还时销毁，等待中的Acquire返回ErrPoolClosed。
+// 重复调用是安全的
+func (p *FilterPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+
+	for {
+		select {
+		case filter := <-p.idle:
+			filter.Destroy()
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package rnnoise
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// blockingEngine 在processFrame中阻塞直到release关闭，用于构造确定的并发调用
+type blockingEngine struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newBlockingEngine() *blockingEngine {
+	return &blockingEngine{entered: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+// signalEntered 通知测试已进入处理函数，只有第一次通知会被接收
+func signalEntered(entered chan struct{}) {
+	select {
+	case entered <- struct{}{}:
+	default:
+	}
+}
+
+func (e *blockingEngine) processFrame(out, in []float32) float32 {
+	signalEntered(e.entered)
+	<-e.release
+	copy(out, in)
+	return 1
+}
+
+func (e *blockingEngine) processFrames(out, in, probs []float32) {
+	for i := range probs {
+		probs[i] = e.processFrame(out[i*rnnoiseFrameSize:(i+1)*rnnoiseFrameSize], in[i*rnnoiseFrameSize:(i+1)*rnnoiseFrameSize])
+	}
+}
+
+func (e *blockingEngine) reset() error { return nil }
+func (e *blockingEngine) close()       {}
+
+func TestRNNoiseDetectsConcurrentUse(t *testing.T) {
+	engine := newBlockingEngine()
+	rnn := newRNNoise(engine)
+
+	done := make(chan error)
+	go func() {
+		_, _, err := rnn.ProcessFrame(make([]float32, rnnoiseFrameSize))
+		done <- err
+	}()
+	<-engine.entered
+
+	frame := make([]float32, rnnoiseFrameSize)
+	if _, _, err := rnn.ProcessFrame(frame); !errors.Is(err, ErrConcurrentUse) {
+		t.Errorf("ProcessFrame() during another call = %v, want ErrConcurrentUse", err)
+	}
+	if _, err := rnn.ProcessFrameInto(frame, frame); !errors.Is(err, ErrConcurrentUse) {
+		t.Errorf("ProcessFrameInto() during another call = %v, want ErrConcurrentUse", err)
+	}
+	if err := rnn.Reset(); !errors.Is(err, ErrConcurrentUse) {
+		t.Errorf("Reset() during another call = %v, want ErrConcurrentUse", err)
+	}
+	if err := rnn.Close(); !errors.Is(err, ErrConcurrentUse) {
+		t.Errorf("Close() during another call = %v, want ErrConcurrentUse", err)
+	}
+
+	close(engine.release)
+	if err := <-done; err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	if _, _, err := rnn.ProcessFrame(frame); err != nil {
+		t.Errorf("ProcessFrame() after the other call finished: %v", err)
+	}
+	if err := rnn.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+}
+
+// blockingDenoiser 在ProcessFrame中阻塞的降噪后端
+type blockingDenoiser struct {
+	*FakeDenoiser
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (d *blockingDenoiser) ProcessFrame(frame []float32) (float32, []float32, error) {
+	signalEntered(d.entered)
+	<-d.release
+	return d.FakeDenoiser.ProcessFrame(frame)
+}
+
+func TestNoiseFilterDetectsConcurrentUse(t *testing.T) {
+	denoiser := &blockingDenoiser{
+		FakeDenoiser: NewFakeDenoiser(1),
+		entered:      make(chan struct{}, 1),
+		release:      make(chan struct{}),
+	}
+	filter, err := NewNoiseFilterWithDenoiser(denoiser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	frame := make([]float32, rnnoiseFrameSize)
+	done := make(chan error)
+	go func() {
+		_, _, _, err := filter.FilterStream(frame, 0.5)
+		done <- err
+	}()
+	<-denoiser.entered
+
+	if _, _, _, err := filter.FilterStream(frame, 0.5); !errors.Is(err, ErrConcurrentUse) {
+		t.Errorf("FilterStream() during another call = %v, want ErrConcurrentUse", err)
+	}
+	audio := &AudioData{Samples: frame, SampleRate: 48000, Channels: 1, BitDepth: 16}
+	if _, err := filter.FilterAudio(audio, 0.5); !errors.Is(err, ErrConcurrentUse) {
+		t.Errorf("FilterAudio() during another call = %v, want ErrConcurrentUse", err)
+	}
+	if _, err := filter.AnalyzeFrames(audio, 0.5); !errors.Is(err, ErrConcurrentUse) {
+		t.Errorf("AnalyzeFrames() during another call = %v, want ErrConcurrentUse", err)
+	}
+
+	close(denoiser.release)
+	if err := <-done; err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+}
+
+func TestFilterPoolConcurrentUse(t *testing.T) {
+	const size = 3
+	var created, inUse, maxInUse int32
+	pool, err := NewFilterPoolFunc(size, func() (*NoiseFilter, error) {
+		atomic.AddInt32(&created, 1)
+		return NewNoiseFilterWithDenoiser(NewFakeDenoiser())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	frame := make([]float32, rnnoiseFrameSize)
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				filter, err := pool.Acquire(context.Background())
+				if err != nil {
+					errs <- err
+					return
+				}
+				n := atomic.AddInt32(&inUse, 1)
+				for {
+					peak := atomic.LoadInt32(&maxInUse)
+					if n <= peak || atomic.CompareAndSwapInt32(&maxInUse, peak, n) {
+						break
+					}
+				}
+				if _, _, _, err := filter.FilterStream(frame, 0); err != nil {
+					errs <- err
+				}
+				atomic.AddInt32(&inUse, -1)
+				pool.Release(filter)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if created > size || maxInUse > size {
+		t.Errorf("created %d filters, %d in use at once; pool size is %d", created, maxInUse, size)
+	}
+}
+
+func TestFilterPoolTryAcquireAndClose(t *testing.T) {
+	pool, err := NewFilterPoolFunc(1, func() (*NoiseFilter, error) {
+		return NewNoiseFilterWithDenoiser(NewFakeDenoiser())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := pool.TryAcquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.TryAcquire(); !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("TryAcquire() on exhausted pool = %v, want ErrPoolExhausted", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() on exhausted pool = %v, want DeadlineExceeded", err)
+	}
+
+	// 归还时重置降噪状态，再次借出的是同一个过滤器
+	fake := filter.Denoiser().(*FakeDenoiser)
+	pool.Release(filter)
+	if fake.Resets != 1 {
+		t.Errorf("Release() should reset the filter, Resets = %d", fake.Resets)
+	}
+	again, err := pool.TryAcquire()
+	if err != nil || again != filter {
+		t.Fatalf("TryAcquire() after Release = %p, %v; want the idle filter", again, err)
+	}
+
+	waiting := make(chan error)
+	go func() {
+		_, err := pool.Acquire(context.Background())
+		waiting <- err
+	}()
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-waiting; !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("waiting Acquire() after Close = %v, want ErrPoolClosed", err)
+	}
+
+	pool.Release(again)
+	if !fake.Closed {
+		t.Error("filter released after Close should be destroyed")
+	}
+	if _, err := pool.TryAcquire(); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("TryAcquire() after Close = %v, want ErrPoolClosed", err)
+	}
+}
+
+func TestNewFilterPoolRejectsSharedDenoiser(t *testing.T) {
+	if _, err := NewFilterPool(2, WithDenoiser(NewFakeDenoiser())); err == nil {
+		t.Error("expected error when sharing one denoiser across the pool")
+	}
+	if _, err := NewFilterPool(0, WithGoModel(mustSyntheticModel(t))); err == nil {
+		t.Error("expected error for zero-sized pool")
+	}
+}
+
+func TestFilterPoolGoBackend(t *testing.T) {
+	pool, err := NewFilterPool(2, WithGoModel(mustSyntheticModel(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			filter, err := pool.Acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer pool.Release(filter)
+			for n := 0; n < 5; n++ {
+				if _, _, _, err := filter.FilterStream(testFrame(g*5+n), 0); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestNewFilterPoolReadsModelOnce(t *testing.T) {
+	weights, err := os.ReadFile(filepath.Join(goFixtureDir, "model.rnnn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 模型在创建池时读取一次，读取失败直接返回错误
+	r := bytes.NewReader(weights)
+	pool, err := NewFilterPool(2, WithModelReader(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	if r.Len() != 0 {
+		t.Errorf("model reader has %d unread bytes after NewFilterPool", r.Len())
+	}
+	if _, err := NewFilterPool(2, WithModelReader(iotest.ErrReader(errors.New("boom")))); err == nil {
+		t.Error("expected error for failing model reader")
+	}
+
+	// 有动态库时，池中的每个过滤器都能使用同一份模型
+	libPath := requireRNNoiseLib(t)
+	if rnn, err := NewRNNoise(libPath, WithModelData(weights)); err != nil {
+		t.Skipf("RNNoise库不支持该模型: %v", err)
+	} else {
+		rnn.Destroy()
+	}
+	pool, err = NewFilterPool(2, WithLibPath(libPath), WithModelReader(bytes.NewReader(weights)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	var filters []*NoiseFilter
+	for i := 0; i < pool.Size(); i++ {
+		filter, err := pool.TryAcquire()
+		if err != nil {
+			t.Fatalf("filter %d: %v", i, err)
+		}
+		filters = append(filters, filter)
+	}
+	for _, filter := range filters {
+		if _, _, _, err := filter.FilterStream(testFrame(0), 0); err != nil {
+			t.Error(err)
+		}
+		pool.Release(filter)
+	}
+}
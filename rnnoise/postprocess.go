@@ -0,0 +1,89 @@
+package rnnoise
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/sirupsen/logrus"
+)
+
+// normalizationGain 将积分响度调整到目标响度target所需的线性增益，音频没有可测量的响度时为1
+func normalizationGain(logger logrus.FieldLogger, loudness, target float64) float64 {
+	if math.IsInf(loudness, -1) {
+		return 1
+	}
+	gain := math.Pow(10, (target-loudness)/20)
+	logger.Debugf("积分响度%.1f LUFS，归一化增益%.1fdB", loudness, target-loudness)
+	return gain
+}
+
+// normalizeFile 按gain缩放临时文件中的样本，经限幅阶段后写入writer，内存占用与文件大小无关
+func (nf *NoiseFilter) normalizeFile(tempFile string, writer *WAVWriter, gain float64) error {
+	reader, err := OpenWAV(tempFile)
+	if err != nil {
+		return fmt.Errorf("读取临时文件失败: %v", err)
+	}
+	defer reader.Close()
+
+	format := reader.Format()
+	p := NewPipeline(nf.outputStages(false)...)
+	buf := make([]float32, fileBlockFrames*format.Channels)
+	for {
+		n, err := reader.ReadSamples(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取临时文件失败: %v", err)
+		}
+		channels := deinterleave(buf[:n], format.Channels)
+		applyGain(channels, gain)
+		out, err := p.Process(&Block{Samples: channels, SampleRate: format.SampleRate})
+		if err != nil {
+			return err
+		}
+		if err := writeBlock(writer, out); err != nil {
+			return err
+		}
+	}
+	tail, err := p.Flush()
+	if err != nil {
+		return err
+	}
+	return writeBlock(writer, tail)
+}
+
+// writeBlock 将块中的样本交织后写入writer，块为nil或为空时什么也不做
+func writeBlock(writer *WAVWriter, block *Block) error {
+	if block == nil || block.Len() == 0 {
+		return nil
+	}
+	samples := block.Samples[0]
+	if block.Channels() > 1 {
+		samples = interleave(block.Samples)
+	}
+	if err := writer.WriteSamples(samples); err != nil {
+		return fmt.Errorf("写入音频文件失败: %v", err)
+	}
+	return nil
+}
+
+// applyGain 将各声道的样本乘以gain（原地修改）
+func applyGain(channels [][]float32, gain float64) {
+	if gain == 1 {
+		return
+	}
+	for _, samples := range channels {
+		for i := range samples {
+			samples[i] = float32(float64(samples[i]) * gain)
+		}
+	}
+}
+
+// appendChannels 将tail中各声道的样本追加到channels对应的声道，tail可以为nil
+func appendChannels(channels, tail [][]float32) {
+	for ch := range tail {
+		channels[ch] = append(channels[ch], tail[ch]...)
+	}
+}
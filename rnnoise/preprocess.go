@@ -0,0 +1,232 @@
+package rnnoise
+
+import (
+	"fmt"
+	"math"
+)
+
+// dcBlockerCutoff DCBlocker的截止频率（Hz）
+const dcBlockerCutoff = 5.0
+
+// DefaultPreEmphasis 语音处理常用的预加重系数
+const DefaultPreEmphasis = 0.97
+
+// PreProcessor 降噪前的预处理阶段
+//
+// 作用于降噪后端采样率下的单声道样本，在RNNoise之前去除直流偏移、低频隆隆声和工频嗡声等
+// 会降低降噪效果的成分。滤波器状态跨调用保留，分块方式不影响结果；每个声道使用单独的实例
+type PreProcessor interface {
+	// Process 原地处理一块样本
+	Process(samples []float32)
+	// Reset 清空状态，开始新的一段音频
+	Reset()
+}
+
+// PreProcessorFactory 为一个声道创建预处理阶段，sampleRate为降噪后端的采样率
+type PreProcessorFactory func(sampleRate int) (PreProcessor, error)
+
+// DCBlocker 一阶直流阻断滤波器（截止频率约5Hz），去除廉价USB耳麦常见的直流偏移
+func DCBlocker() PreProcessorFactory {
+	return func(sampleRate int) (PreProcessor, error) {
+		if sampleRate <= 0 {
+			return nil, fmt.Errorf("采样率必须大于0，当前为%d", sampleRate)
+		}
+		return &dcBlocker{r: math.Exp(-2 * math.Pi * dcBlockerCutoff / float64(sampleRate))}, nil
+	}
+}
+
+// HighPass 二阶高通滤波器，去除cutoff（Hz）以下的隆隆声和风噪
+//
+// q为品质因数，0.7071为巴特沃斯响应（通带最平坦）；例如HighPass(80, 0.7071)
+func HighPass(cutoff, q float64) PreProcessorFactory {
+	return func(sampleRate int) (PreProcessor, error) {
+		if err := checkFilterParams("高通滤波器", cutoff, q, sampleRate); err != nil {
+			return nil, err
+		}
+		return &biquadStage{filter: highPassBiquad(float64(sampleRate), cutoff, q)}, nil
+	}
+}
+
+// Notch 二阶陷波滤波器，去除freq（Hz）附近的窄带干扰，例如Notch(50, 10)去除50Hz工频嗡声
+//
+// q越大陷波越窄，对附近的语音影响越小；谐波可以再叠加Notch(100, q)、Notch(150, q)等
+func Notch(freq, q float64) PreProcessorFactory {
+	return func(sampleRate int) (PreProcessor, error) {
+		if err := checkFilterParams("陷波滤波器", freq, q, sampleRate); err != nil {
+			return nil, err
+		}
+		return &biquadStage{filter: notchBiquad(float64(sampleRate), freq, q)}, nil
+	}
+}
+
+// PreEmphasis 一阶预加重滤波器 y[n] = x[n] - coeff*x[n-1]，提升高频使语音频谱更平坦
+//
+// coeff必须在0到1之间，通常使用DefaultPreEmphasis。预加重只用于改善RNNoise的分析，
+// 降噪和干湿混合之后会用 y[n] = x[n] + coeff*y[n-1] 去加重，输出的频谱倾斜与输入一致；
+// 应当放在DCBlocker和HighPass之后
+func PreEmphasis(coeff float64) PreProcessorFactory {
+	return func(sampleRate int) (PreProcessor, error) {
+		if coeff <= 0 || coeff >= 1 {
+			return nil, fmt.Errorf("预加重系数必须在0到1之间，当前为%v", coeff)
+		}
+		return &preEmphasis{coeff: coeff}, nil
+	}
+}
+
+// checkFilterParams 检查滤波器的频率和品质因数
+func checkFilterParams(name string, freq, q float64, sampleRate int) error {
+	if freq <= 0 || freq >= float64(sampleRate)/2 {
+		return fmt.Errorf("%s的频率必须在0到%dHz之间，当前为%v", name, sampleRate/2, freq)
+	}
+	if q <= 0 {
+		return fmt.Errorf("%s的品质因数必须大于0，当前为%v", name, q)
+	}
+	return nil
+}
+
+// dcBlocker y[n] = x[n] - x[n-1] + r*y[n-1]
+type dcBlocker struct {
+	r      float64
+	x1, y1 float64
+}
+
+// Process 去除直流分量
+func (d *dcBlocker) Process(samples []float32) {
+	for i, sample := range samples {
+		x := float64(sample)
+		d.y1 = x - d.x1 + d.r*d.y1
+		d.x1 = x
+		samples[i] = float32(d.y1)
+	}
+}
+
+// Reset 清空滤波器状态
+func (d *dcBlocker) Reset() {
+	d.x1, d.y1 = 0, 0
+}
+
+// preEmphasis y[n] = x[n] - coeff*x[n-1]
+type preEmphasis struct {
+	coeff float64
+	x1    float64
+}
+
+// Process 提升高频
+func (p *preEmphasis) Process(samples []float32) {
+	for i, sample := range samples {
+		x := float64(sample)
+		samples[i] = float32(x - p.coeff*p.x1)
+		p.x1 = x
+	}
+}
+
+// Reset 清空滤波器状态
+func (p *preEmphasis) Reset() {
+	p.x1 = 0
+}
+
+// biquadStage 由一个二阶滤波器组成的预处理阶段
+type biquadStage struct {
+	filter biquadFilter
+}
+
+// Process 逐样本滤波
+func (s *biquadStage) Process(samples []float32) {
+	for i, sample := range samples {
+		samples[i] = float32(s.filter.process(float64(sample)))
+	}
+}
+
+// Reset 清空滤波器状态
+func (s *biquadStage) Reset() {
+	s.filter.reset()
+}
+
+// preProcessorFactories 配置的预处理阶段，WithPreEmphasis的预加重排在最后
+func preProcessorFactories(cfg *config) []PreProcessorFactory {
+	if cfg.preEmphasis == 0 {
+		return cfg.preProcessors
+	}
+	factories := append([]PreProcessorFactory(nil), cfg.preProcessors...)
+	return append(factories, PreEmphasis(cfg.preEmphasis))
+}
+
+// preChain 按顺序执行的预处理阶段，未配置时为nil
+type preChain []PreProcessor
+
+// newPreChain 为一个声道创建过滤器配置的预处理阶段
+func (nf *NoiseFilter) newPreChain() (preChain, error) {
+	if len(nf.preProcessors) == 0 {
+		return nil, nil
+	}
+	chain := make(preChain, len(nf.preProcessors))
+	for i, factory := range nf.preProcessors {
+		stage, err := factory(nf.denoiser.SampleRateHz())
+		if err != nil {
+			return nil, fmt.Errorf("创建预处理阶段失败: %v", err)
+		}
+		chain[i] = stage
+	}
+	return chain, nil
+}
+
+// newDeEmphasis 创建抵消链中所有预加重的去加重，链中没有预加重时返回nil
+func (c preChain) newDeEmphasis() *deEmphasis {
+	var coeffs []float64
+	for _, stage := range c {
+		if p, ok := stage.(*preEmphasis); ok {
+			coeffs = append(coeffs, p.coeff)
+		}
+	}
+	if len(coeffs) == 0 {
+		return nil
+	}
+	return &deEmphasis{coeffs: coeffs, y1: make([]float64, len(coeffs))}
+}
+
+// process 依次执行所有阶段（原地修改）
+func (c preChain) process(samples []float32) {
+	for _, stage := range c {
+		stage.Process(samples)
+	}
+}
+
+// reset 清空所有阶段的状态
+func (c preChain) reset() {
+	for _, stage := range c {
+		stage.Reset()
+	}
+}
+
+// deEmphasis 预加重的逆滤波 y[n] = x[n] + coeff*y[n-1]，每个预加重对应一级
+//
+// 预处理各阶段都是线性时不变的，因此在降噪和混合之后去加重即可恢复原始的频谱倾斜
+type deEmphasis struct {
+	coeffs []float64
+	y1     []float64
+}
+
+// process 去加重（原地修改），d为nil时不做处理
+func (d *deEmphasis) process(samples []float32) {
+	if d == nil {
+		return
+	}
+	for k, coeff := range d.coeffs {
+		y1 := d.y1[k]
+		for i, sample := range samples {
+			y1 = float64(sample) + coeff*y1
+			samples[i] = float32(y1)
+		}
+		d.y1[k] = y1
+	}
+}
+
+// reset 清空滤波器状态
+func (d *deEmphasis) reset() {
+	if d == nil {
+		return
+	}
+	for k := range d.y1 {
+		d.y1[k] = 0
+	}
+}
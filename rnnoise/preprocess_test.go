@@ -0,0 +1,286 @@
+package rnnoise
+
+import (
+	"math"
+	"testing"
+)
+
+// preprocess 用一个预处理阶段按chunks分块处理样本的副本
+func preprocess(t *testing.T, factory PreProcessorFactory, samples []float32, chunks []int) []float32 {
+	t.Helper()
+	stage, err := factory(48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := append([]float32(nil), samples...)
+	processInChunks(out, chunks, func(chunk []float32) []float32 {
+		stage.Process(chunk)
+		return chunk
+	})
+	return out
+}
+
+func mean(samples []float32) float64 {
+	var sum float64
+	for _, sample := range samples {
+		sum += float64(sample)
+	}
+	return sum / float64(len(samples))
+}
+
+func TestDCBlockerRemovesOffset(t *testing.T) {
+	input := tone(48000*2, 48000, 440, -20)
+	for i := range input {
+		input[i] += 0.2
+	}
+	out := preprocess(t, DCBlocker(), input, []int{480})
+	if m := mean(out[48000:]); math.Abs(m) > 1e-3 {
+		t.Errorf("DC offset after blocking %f, want 0", m)
+	}
+	// 语音频段基本不受影响
+	if level := rmsDB(out[48000:]); math.Abs(level-rmsDB(tone(48000, 48000, 440, -20))) > 0.1 {
+		t.Errorf("440Hz level changed to %.2f dBFS", level)
+	}
+}
+
+func TestHighPassAndNotch(t *testing.T) {
+	tests := []struct {
+		name    string
+		factory PreProcessorFactory
+		freq    float64
+		minLoss float64 // 至少衰减的dB数，0表示应当通过（衰减不超过0.5dB）
+	}{
+		{"high-pass stopband", HighPass(80, 0.7071), 20, 20},
+		{"high-pass passband", HighPass(80, 0.7071), 1000, 0},
+		{"notch 50Hz", Notch(50, 10), 50, 30},
+		{"notch passband", Notch(50, 10), 300, 0},
+	}
+	for _, tt := range tests {
+		input := tone(48000*2, 48000, tt.freq, -10)
+		out := preprocess(t, tt.factory, input, []int{480, 7, 1000})
+		loss := rmsDB(input[48000:]) - rmsDB(out[48000:])
+		if tt.minLoss > 0 && loss < tt.minLoss {
+			t.Errorf("%s: %.0fHz attenuated by %.1f dB, want at least %.0f dB", tt.name, tt.freq, loss, tt.minLoss)
+		}
+		if tt.minLoss == 0 && math.Abs(loss) > 0.5 {
+			t.Errorf("%s: %.0fHz attenuated by %.1f dB, want unchanged", tt.name, tt.freq, loss)
+		}
+	}
+}
+
+func TestPreProcessorChunkingInvariant(t *testing.T) {
+	input := tone(4801, 48000, 60, -6)
+	for i := range input {
+		input[i] += 0.1
+	}
+	want := preprocess(t, HighPass(100, 0.7071), input, []int{len(input)})
+	got := preprocess(t, HighPass(100, 0.7071), input, []int{1, 479, 13})
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d = %f, want %f", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPreEmphasis(t *testing.T) {
+	// 阶跃输入：第一个样本不变，之后为1-coeff
+	step := make([]float32, 1000)
+	for i := range step {
+		step[i] = 1
+	}
+	out := preprocess(t, PreEmphasis(DefaultPreEmphasis), step, []int{1, 479, 13})
+	if out[0] != 1 {
+		t.Errorf("first sample %f, want 1", out[0])
+	}
+	for i, sample := range out[1:] {
+		if math.Abs(float64(sample)-(1-DefaultPreEmphasis)) > 1e-6 {
+			t.Fatalf("sample %d = %f, want %f", i+1, sample, 1-DefaultPreEmphasis)
+		}
+	}
+
+	// 低频衰减，高频提升
+	low := tone(4800, 48000, 100, -20)
+	high := tone(4800, 48000, 12000, -20)
+	if loss := rmsDB(low) - rmsDB(preprocess(t, PreEmphasis(DefaultPreEmphasis), low, []int{480})[480:]); loss < 20 {
+		t.Errorf("100Hz attenuated by %.1f dB, want at least 20 dB", loss)
+	}
+	if gain := rmsDB(preprocess(t, PreEmphasis(DefaultPreEmphasis), high, []int{480})[480:]) - rmsDB(high); gain < 2 {
+		t.Errorf("12kHz boosted by %.1f dB, want at least 2 dB", gain)
+	}
+}
+
+func TestWithPreEmphasis(t *testing.T) {
+	input := sineSamples(16000, 16000, 300)
+	for i := range input {
+		input[i] += 0.1
+	}
+	run := func(opts ...Option) []float32 {
+		filter, err := NewNoiseFilter(append([]Option{WithDenoiser(NewFakeDenoiser(0.9)),
+			WithOutputPolicy(OutputSilence)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer filter.Destroy()
+		result, err := filter.FilterAudio(&AudioData{Samples: input, SampleRate: 16000, Channels: 1, BitDepth: 16}, 0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.DenoisedAudio.Samples
+	}
+
+	want := run(WithPreProcessing(DCBlocker(), PreEmphasis(0.9)))
+	got := run(WithPreEmphasis(0.9), WithPreProcessing(DCBlocker()))
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d = %f, want %f", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPreEmphasisIsUndoneAfterDenoising(t *testing.T) {
+	input := sineSamples(48000/2+123, 48000, 3000)
+	audio := &AudioData{Samples: input, SampleRate: 48000, Channels: 1, BitDepth: 16}
+	silent := NewFakeDenoiser(0.9)
+	silent.Gain = 0
+
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"wet", []Option{WithDenoiser(NewFakeDenoiser(0.9))}},
+		// 完全使用干信号时输出也不应保留高频提升
+		{"dry", []Option{WithDenoiser(silent), WithMix(0)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{WithOutputPolicy(OutputSilence), WithThreshold(0.5),
+				WithPreEmphasis(DefaultPreEmphasis)}, tc.opts...)
+			filter, err := NewNoiseFilter(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer filter.Destroy()
+			result, err := filter.FilterAudio(audio, 0.5)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sf, err := NewStreamFilter(48000, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sf.Destroy()
+			streamed := processInChunks(input, []int{480, 77}, func(chunk []float32) []float32 {
+				out, _, err := sf.Process(chunk)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return out
+			})
+			tail, _, err := sf.Flush()
+			if err != nil {
+				t.Fatal(err)
+			}
+			streamed = append(streamed, tail...)
+
+			for name, out := range map[string][]float32{"FilterAudio": result.DenoisedAudio.Samples, "StreamFilter": streamed} {
+				if len(out) != len(input) {
+					t.Fatalf("%s: output %d samples, want %d", name, len(out), len(input))
+				}
+				for i := range input {
+					if math.Abs(float64(out[i]-input[i])) > 1e-4 {
+						t.Fatalf("%s: sample %d = %f, want %f", name, i, out[i], input[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFilterAudioPreProcessing(t *testing.T) {
+	filter, err := NewNoiseFilterWithDenoiser(NewFakeDenoiser(0.9),
+		WithOutputPolicy(OutputSilence), WithPreProcessing(DCBlocker(), Notch(50, 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	input := tone(16000*2, 16000, 50, -20)
+	for i := range input {
+		input[i] += 0.25
+	}
+	result, err := filter.FilterAudio(&AudioData{Samples: input, SampleRate: 16000, Channels: 1, BitDepth: 16}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := result.DenoisedAudio.Samples
+	if len(out) != len(input) {
+		t.Fatalf("output %d samples, want %d", len(out), len(input))
+	}
+	if level := rmsDB(out[16000:]); level > -40 {
+		t.Errorf("output level %.1f dBFS after removing DC and 50Hz hum, want below -40", level)
+	}
+	// 调用方的输入不被修改
+	if input[0] != 0.25 {
+		t.Errorf("input modified: sample 0 = %f", input[0])
+	}
+}
+
+func TestStreamFilterPreProcessingMatchesFilterAudio(t *testing.T) {
+	opts := func() []Option {
+		return []Option{WithDenoiser(NewFakeDenoiser(0.9, 0.2)), WithOutputPolicy(OutputAttenuate),
+			WithThreshold(0.5), WithPreProcessing(DCBlocker(), HighPass(80, 0.7071))}
+	}
+	input := sineSamples(16000+321, 16000, 300)
+	for i := range input {
+		input[i] += 0.1
+	}
+
+	filter, err := NewNoiseFilter(opts()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+	want, err := filter.FilterAudio(&AudioData{Samples: input, SampleRate: 16000, Channels: 1, BitDepth: 16}, DefaultThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sf, err := NewStreamFilter(16000, opts()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Destroy()
+	got := processInChunks(input, []int{160, 999, 7}, func(chunk []float32) []float32 {
+		out, _, err := sf.Process(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	})
+	tail, _, err := sf.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, tail...)
+
+	if len(got) != len(want.DenoisedAudio.Samples) {
+		t.Fatalf("stream output %d samples, want %d", len(got), len(want.DenoisedAudio.Samples))
+	}
+	for i, sample := range want.DenoisedAudio.Samples {
+		if math.Abs(float64(got[i]-sample)) > 1e-5 {
+			t.Fatalf("sample %d = %f, want %f", i, got[i], sample)
+		}
+	}
+}
+
+func TestNoiseFilterRejectsInvalidPreProcessing(t *testing.T) {
+	for _, factory := range []PreProcessorFactory{HighPass(30000, 0.7071), HighPass(80, 0), Notch(-50, 10), PreEmphasis(1)} {
+		if _, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser()), WithPreProcessing(factory)); err == nil {
+			t.Error("expected error for invalid filter parameters")
+		}
+	}
+	if _, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser()), WithPreEmphasis(-0.5)); err == nil {
+		t.Error("expected error for invalid pre-emphasis coefficient")
+	}
+}
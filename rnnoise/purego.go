@@ -0,0 +1,431 @@
+package rnnoise
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+)
+
+// GoModel 纯Go后端使用的RNNoise模型权重
+//
+// 模型文件采用RNNoise rnnoise_model_from_file 读取的文本格式
+// （以 "rnnoise-nu model file version 1" 开头），
+// 网络结构为 输入全连接层 → VAD GRU → 噪声 GRU → 降噪 GRU → 增益输出层/VAD输出层
+type GoModel struct {
+	inputDense    denseLayer
+	vadGRU        gruLayer
+	noiseGRU      gruLayer
+	denoiseGRU    gruLayer
+	denoiseOutput denseLayer
+	vadOutput     denseLayer
+}
+
+// LoadGoModel 从文本格式的模型数据中读取权重
+func LoadGoModel(r io.Reader) (*GoModel, error) {
+	p := &modelParser{scanner: bufio.NewScanner(r)}
+	p.scanner.Split(bufio.ScanWords)
+
+	for _, word := range []string{"rnnoise-nu", "model", "file", "version"} {
+		if p.word() != word {
+			return nil, fmt.Errorf("无效的RNNoise模型文件头")
+		}
+	}
+	if version := p.int(); p.err != nil || version != 1 {
+		return nil, fmt.Errorf("不支持的RNNoise模型版本")
+	}
+
+	m := &GoModel{}
+	p.dense(&m.inputDense)
+	p.gru(&m.vadGRU)
+	p.gru(&m.noiseGRU)
+	p.gru(&m.denoiseGRU)
+	p.dense(&m.denoiseOutput)
+	p.dense(&m.vadOutput)
+	if p.err != nil {
+		return nil, fmt.Errorf("读取RNNoise模型失败: %v", p.err)
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadGoModelFile 从文件中读取纯Go后端使用的模型权重
+func LoadGoModelFile(path string) (*GoModel, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开模型文件 %s: %v", path, err)
+	}
+	defer file.Close()
+
+	return LoadGoModel(file)
+}
+
+// validate 检查各层维度是否与RNNoise网络结构一致
+func (m *GoModel) validate() error {
+	checks := []struct {
+		name     string
+		got      int
+		expected int
+	}{
+		{"input_dense输入", m.inputDense.nbInputs, goNbFeatures},
+		{"vad_gru输入", m.vadGRU.nbInputs, m.inputDense.nbNeurons},
+		{"noise_gru输入", m.noiseGRU.nbInputs, m.inputDense.nbNeurons + m.vadGRU.nbNeurons + goNbFeatures},
+		{"denoise_gru输入", m.denoiseGRU.nbInputs, m.vadGRU.nbNeurons + m.noiseGRU.nbNeurons + goNbFeatures},
+		{"denoise_output输入", m.denoiseOutput.nbInputs, m.denoiseGRU.nbNeurons},
+		{"denoise_output输出", m.denoiseOutput.nbNeurons, goNbBands},
+		{"vad_output输入", m.vadOutput.nbInputs, m.vadGRU.nbNeurons},
+		{"vad_output输出", m.vadOutput.nbNeurons, 1},
+	}
+	for _, c := range checks {
+		if c.got != c.expected {
+			return fmt.Errorf("RNNoise模型维度不匹配: %s为%d，期望%d", c.name, c.got, c.expected)
+		}
+	}
+	return nil
+}
+
+func (m *GoModel) maxNeurons() int {
+	n := m.vadGRU.nbNeurons
+	if m.noiseGRU.nbNeurons > n {
+		n = m.noiseGRU.nbNeurons
+	}
+	if m.denoiseGRU.nbNeurons > n {
+		n = m.denoiseGRU.nbNeurons
+	}
+	return n
+}
+
+// modelParser 按空白分隔读取模型文件，遇到第一个错误后停止
+type modelParser struct {
+	scanner *bufio.Scanner
+	err     error
+}
+
+func (p *modelParser) word() string {
+	if p.err != nil {
+		return ""
+	}
+	if !p.scanner.Scan() {
+		p.err = p.scanner.Err()
+		if p.err == nil {
+			p.err = io.ErrUnexpectedEOF
+		}
+		return ""
+	}
+	return p.scanner.Text()
+}
+
+func (p *modelParser) int() int {
+	word := p.word()
+	if p.err != nil {
+		return 0
+	}
+	v, err := strconv.Atoi(word)
+	if err != nil {
+		p.err = err
+	}
+	return v
+}
+
+func (p *modelParser) size() int {
+	v := p.int()
+	if p.err == nil && (v <= 0 || v > 4096) {
+		p.err = fmt.Errorf("无效的层大小: %d", v)
+	}
+	return v
+}
+
+func (p *modelParser) activation() int {
+	v := p.int()
+	if p.err == nil && (v < activationTanh || v > activationRelu) {
+		p.err = fmt.Errorf("无效的激活函数: %d", v)
+	}
+	return v
+}
+
+func (p *modelParser) array(n int) []float32 {
+	if p.err != nil {
+		return nil
+	}
+	values := make([]float32, n)
+	for i := range values {
+		v := p.int()
+		if p.err != nil {
+			return nil
+		}
+		if v < math.MinInt8 || v > math.MaxInt8 {
+			p.err = fmt.Errorf("权重超出8位整数范围: %d", v)
+			return nil
+		}
+		values[i] = float32(v)
+	}
+	return values
+}
+
+func (p *modelParser) dense(l *denseLayer) {
+	l.nbInputs = p.size()
+	l.nbNeurons = p.size()
+	l.activation = p.activation()
+	l.inputWeights = p.array(l.nbInputs * l.nbNeurons)
+	l.bias = p.array(l.nbNeurons)
+}
+
+func (p *modelParser) gru(l *gruLayer) {
+	l.nbInputs = p.size()
+	l.nbNeurons = p.size()
+	l.activation = p.activation()
+	l.inputWeights = p.array(l.nbInputs * l.nbNeurons * 3)
+	l.recurrentWeights = p.array(l.nbNeurons * l.nbNeurons * 3)
+	l.bias = p.array(l.nbNeurons * 3)
+}
+
+// NewGoRNNoise 使用纯Go后端创建RNNoise实例
+//
+// 纯Go后端完整实现了RNNoise的处理流程（高通滤波、基音分析、频带能量、
+// GRU网络推理、基音滤波和增益插值），不依赖cgo和动态库，
+// 适用于静态编译、CGO_ENABLED=0的容器和交叉编译。
+//
+// 纯Go后端没有内置权重，必须提供 rnnoise-nu 文本格式的模型；
+// 随项目发布的动态库使用的是不同结构的内置模型，无法直接用于纯Go后端，
+// 因此不能在不提供模型的情况下代替 NewRNNoise("")。
+//
+// 参数:
+//   - model: 通过LoadGoModel或LoadGoModelFile读取的模型权重
+//
+// 示例:
+//
+//	model, err := LoadGoModelFile("weights.rnnn")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	rnn, err := NewGoRNNoise(model)
+func NewGoRNNoise(model *GoModel) (*RNNoise, error) {
+	if model == nil {
+		return nil, fmt.Errorf("纯Go后端需要提供RNNoise模型")
+	}
+	return newRNNoise(newGoDenoiseState(model)), nil
+}
+
+// goDenoiseState 纯Go后端的降噪状态，对应C实现中的DenoiseState
+type goDenoiseState struct {
+	analysisMem  [goFrameSize]float32
+	cepstralMem  [goCepsMem][goNbBands]float32
+	memID        int
+	synthesisMem [goFrameSize]float32
+	pitchBuf     [goPitchBufSize]float32
+	lastGain     float32
+	lastPeriod   int
+	memHPX       [2]float32
+	lastG        [goNbBands]float32
+	rnn          *goRNNState
+
+	// 每帧复用的临时缓冲区
+	fftIn       [goWindowSize]complex64
+	fftOut      [goWindowSize]complex64
+	window      [goWindowSize]float32
+	pitchLP     [goPitchBufSize >> 1]float32
+	xcorr       [goPitchMaxPeriod >> 1]float32
+	x           [goFreqSize]complex64
+	p           [goFreqSize]complex64
+	hpIn        [goFrameSize]float32
+	features    [goNbFeatures]float32
+	freqGain    [goFreqSize]float32
+	bandScratch [goNbBands]float32
+}
+
+func newGoDenoiseState(model *GoModel) *goDenoiseState {
+	return &goDenoiseState{rnn: newGoRNNState(model)}
+}
+
+// processFrames 依次处理len(probs)个连续帧
+func (d *goDenoiseState) processFrames(out, in, probs []float32) {
+	for i := range probs {
+		start := i * goFrameSize
+		probs[i] = d.processFrame(out[start:start+goFrameSize], in[start:start+goFrameSize])
+	}
+}
+
+func (d *goDenoiseState) reset() error {
+	rnn := d.rnn
+	rnn.reset()
+	*d = goDenoiseState{rnn: rnn}
+	return nil
+}
+
+func (d *goDenoiseState) close() {}
+
+// frameAnalysis 加窗并计算当前帧的频谱和频带能量
+func (d *goDenoiseState) frameAnalysis(x []complex64, ex []float32, in []float32) {
+	copy(d.window[:goFrameSize], d.analysisMem[:])
+	copy(d.window[goFrameSize:], in)
+	copy(d.analysisMem[:], in)
+	applyWindow(d.window[:])
+	d.forwardTransform(x, d.window[:])
+	computeBandEnergy(ex, x)
+}
+
+// frameSynthesis 逆变换并与上一帧重叠相加
+func (d *goDenoiseState) frameSynthesis(out []float32, y []complex64) {
+	d.inverseTransform(d.window[:], y)
+	applyWindow(d.window[:])
+	for i := 0; i < goFrameSize; i++ {
+		out[i] = d.window[i] + d.synthesisMem[i]
+	}
+	copy(d.synthesisMem[:], d.window[goFrameSize:])
+}
+
+// computeFrameFeatures 计算网络输入特征，返回true表示当前帧为静音
+func (d *goDenoiseState) computeFrameFeatures(ex, ep, exp, features, in []float32) bool {
+	x, p := d.x[:], d.p[:]
+	d.frameAnalysis(x, ex, in)
+
+	copy(d.pitchBuf[:], d.pitchBuf[goFrameSize:])
+	copy(d.pitchBuf[goPitchBufSize-goFrameSize:], in)
+	pitchDownsample(d.pitchBuf[:], d.pitchLP[:], goPitchBufSize)
+	pitchIndex := pitchSearch(d.pitchLP[goPitchMaxPeriod>>1:], d.pitchLP[:], goPitchFrameSize,
+		goPitchMaxPeriod-3*goPitchMinPeriod, d.xcorr[:])
+	pitchIndex = goPitchMaxPeriod - pitchIndex
+
+	gain := removeDoubling(d.pitchLP[:], goPitchMaxPeriod, goPitchMinPeriod,
+		goPitchFrameSize, &pitchIndex, d.lastPeriod, d.lastGain)
+	d.lastPeriod = pitchIndex
+	d.lastGain = gain
+
+	copy(d.window[:], d.pitchBuf[goPitchBufSize-goWindowSize-pitchIndex:])
+	applyWindow(d.window[:])
+	d.forwardTransform(p, d.window[:])
+	computeBandEnergy(ep, p)
+	computeBandCorr(exp, x, p)
+	for i := 0; i < goNbBands; i++ {
+		exp[i] /= float32(math.Sqrt(float64(.001 + ex[i]*ep[i])))
+	}
+
+	tmp := d.bandScratch[:]
+	dct(tmp, exp)
+	for i := 0; i < goNbDeltaCeps; i++ {
+		features[goNbBands+2*goNbDeltaCeps+i] = tmp[i]
+	}
+	features[goNbBands+2*goNbDeltaCeps] -= 1.3
+	features[goNbBands+2*goNbDeltaCeps+1] -= .9
+	features[goNbBands+3*goNbDeltaCeps] = .01 * float32(pitchIndex-300)
+
+	var ly [goNbBands]float32
+	var e float32
+	logMax := float32(-2)
+	follow := float32(-2)
+	for i := 0; i < goNbBands; i++ {
+		ly[i] = float32(math.Log10(float64(1e-2 + ex[i])))
+		ly[i] = maxFloat32(logMax-7, maxFloat32(follow-1.5, ly[i]))
+		logMax = maxFloat32(logMax, ly[i])
+		follow = maxFloat32(follow-1.5, ly[i])
+		e += ex[i]
+	}
+	if e < goSilenceEnergy {
+		// 没有音频时避免扰乱网络状态
+		for i := range features {
+			features[i] = 0
+		}
+		return true
+	}
+
+	dct(features, ly[:])
+	features[0] -= 12
+	features[1] -= 4
+	ceps0 := &d.cepstralMem[d.memID]
+	ceps1 := &d.cepstralMem[(d.memID+goCepsMem-1)%goCepsMem]
+	ceps2 := &d.cepstralMem[(d.memID+goCepsMem-2)%goCepsMem]
+	copy(ceps0[:], features[:goNbBands])
+	d.memID++
+	for i := 0; i < goNbDeltaCeps; i++ {
+		features[i] = ceps0[i] + ceps1[i] + ceps2[i]
+		features[goNbBands+i] = ceps0[i] - ceps2[i]
+		features[goNbBands+goNbDeltaCeps+i] = ceps0[i] - 2*ceps1[i] + ceps2[i]
+	}
+
+	// 频谱变化特征
+	if d.memID == goCepsMem {
+		d.memID = 0
+	}
+	var specVariability float32
+	for i := 0; i < goCepsMem; i++ {
+		minDist := float32(1e15)
+		for j := 0; j < goCepsMem; j++ {
+			if j == i {
+				continue
+			}
+			var dist float32
+			for k := 0; k < goNbBands; k++ {
+				t := d.cepstralMem[i][k] - d.cepstralMem[j][k]
+				dist += t * t
+			}
+			minDist = minFloat32(minDist, dist)
+		}
+		specVariability += minDist
+	}
+	features[goNbBands+3*goNbDeltaCeps+1] = specVariability/goCepsMem - 2.1
+	return false
+}
+
+// pitchFilter 根据基音相关性增强谐波成分，并保持各频带能量不变
+func (d *goDenoiseState) pitchFilter(ex, ep, exp, g []float32) {
+	x, p := d.x[:], d.p[:]
+	var r [goNbBands]float32
+	for i := 0; i < goNbBands; i++ {
+		if exp[i] > g[i] {
+			r[i] = 1
+		} else {
+			r[i] = exp[i] * exp[i] * (1 - g[i]*g[i]) / (.001 + g[i]*g[i]*(1-exp[i]*exp[i]))
+		}
+		r[i] = float32(math.Sqrt(float64(minFloat32(1, maxFloat32(0, r[i])))))
+		r[i] *= float32(math.Sqrt(float64(ex[i] / (1e-8 + ep[i]))))
+	}
+
+	rf := d.freqGain[:]
+	interpBandGain(rf, r[:])
+	for i := 0; i < goFreqSize; i++ {
+		x[i] += complex(rf[i], 0) * p[i]
+	}
+
+	newE := d.bandScratch[:]
+	computeBandEnergy(newE, x)
+	var norm [goNbBands]float32
+	for i := 0; i < goNbBands; i++ {
+		norm[i] = float32(math.Sqrt(float64(ex[i] / (1e-8 + newE[i]))))
+	}
+	interpBandGain(rf, norm[:])
+	for i := 0; i < goFreqSize; i++ {
+		x[i] *= complex(rf[i], 0)
+	}
+}
+
+// processFrame 对应C实现中的rnnoise_process_frame
+func (d *goDenoiseState) processFrame(out, in []float32) float32 {
+	var ex, ep, exp, g [goNbBands]float32
+	var vadProb float32
+
+	// 去除直流的高通滤波
+	biquad(d.hpIn[:], d.memHPX[:], in, [2]float32{-2, 1}, [2]float32{-1.99599, 0.99600})
+	silence := d.computeFrameFeatures(ex[:], ep[:], exp[:], d.features[:], d.hpIn[:])
+
+	if !silence {
+		vadProb = d.rnn.compute(g[:], d.features[:])
+		d.pitchFilter(ex[:], ep[:], exp[:], g[:])
+		for i := 0; i < goNbBands; i++ {
+			g[i] = maxFloat32(g[i], goGainSmoothAlpha*d.lastG[i])
+			d.lastG[i] = g[i]
+		}
+		gf := d.freqGain[:]
+		interpBandGain(gf, g[:])
+		for i := 0; i < goFreqSize; i++ {
+			d.x[i] *= complex(gf[i], 0)
+		}
+	}
+
+	d.frameSynthesis(out, d.x[:])
+	return vadProb
+}
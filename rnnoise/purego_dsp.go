@@ -0,0 +1,562 @@
+package rnnoise
+
+import (
+	"math"
+)
+
+// 纯Go后端的信号处理部分，对应RNNoise C实现中的denoise.c、pitch.c和celt_lpc.c
+
+const (
+	goFrameSizeShift  = 2
+	goFrameSize       = 120 << goFrameSizeShift // 480
+	goWindowSize      = 2 * goFrameSize         // 960
+	goFreqSize        = goFrameSize + 1         // 481
+	goPitchMinPeriod  = 60
+	goPitchMaxPeriod  = 768
+	goPitchFrameSize  = 960
+	goPitchBufSize    = goPitchMaxPeriod + goPitchFrameSize
+	goNbBands         = 22
+	goCepsMem         = 8
+	goNbDeltaCeps     = 6
+	goNbFeatures      = goNbBands + 3*goNbDeltaCeps + 2
+	goMaxFFTRadix     = 5
+	goSilenceEnergy   = 0.04
+	goGainSmoothAlpha = 0.6
+)
+
+// eband5ms 每个频带的起始位置（以5ms帧的FFT bin为单位）
+var eband5ms = [goNbBands]int{
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 10, 12, 14, 16, 20, 24, 28, 34, 40, 48, 60, 78, 100,
+}
+
+// dspTables 各实例共享的只读表
+type dspTables struct {
+	halfWindow [goFrameSize]float32
+	dctTable   [goNbBands * goNbBands]float32
+	fft        *fftPlan
+}
+
+var commonTables = newDSPTables()
+
+func newDSPTables() *dspTables {
+	t := &dspTables{fft: newFFTPlan(goWindowSize)}
+	for i := 0; i < goFrameSize; i++ {
+		s := math.Sin(.5 * math.Pi * (float64(i) + .5) / goFrameSize)
+		t.halfWindow[i] = float32(math.Sin(.5 * math.Pi * s * s))
+	}
+	for i := 0; i < goNbBands; i++ {
+		for j := 0; j < goNbBands; j++ {
+			v := math.Cos((float64(i) + .5) * float64(j) * math.Pi / goNbBands)
+			if j == 0 {
+				v *= math.Sqrt(.5)
+			}
+			t.dctTable[i*goNbBands+j] = float32(v)
+		}
+	}
+	return t
+}
+
+// fftPlan 混合基（2/3/4/5）复数FFT
+type fftPlan struct {
+	n       int
+	factors []int
+	twiddle []complex64
+}
+
+func newFFTPlan(n int) *fftPlan {
+	p := &fftPlan{n: n, twiddle: make([]complex64, n)}
+	for i := 0; i < n; i++ {
+		phase := -2 * math.Pi * float64(i) / float64(n)
+		p.twiddle[i] = complex(float32(math.Cos(phase)), float32(math.Sin(phase)))
+	}
+	for rest := n; rest > 1; {
+		switch {
+		case rest%4 == 0:
+			p.factors = append(p.factors, 4)
+			rest /= 4
+		case rest%2 == 0:
+			p.factors = append(p.factors, 2)
+			rest /= 2
+		case rest%3 == 0:
+			p.factors = append(p.factors, 3)
+			rest /= 3
+		case rest%5 == 0:
+			p.factors = append(p.factors, 5)
+			rest /= 5
+		default:
+			panic("fftPlan: 不支持的FFT长度")
+		}
+	}
+	return p
+}
+
+// transform 计算未归一化的正向DFT，out与in不能重叠
+func (p *fftPlan) transform(out, in []complex64) {
+	p.work(out, in, 1, p.n, 0)
+}
+
+func (p *fftPlan) work(out, in []complex64, stride, n, stage int) {
+	if n == 1 {
+		out[0] = in[0]
+		return
+	}
+	radix := p.factors[stage]
+	m := n / radix
+	for q := 0; q < radix; q++ {
+		p.work(out[q*m:], in[q*stride:], stride*radix, m, stage+1)
+	}
+
+	step := p.n / n
+	radixStep := p.n / radix
+	var t [goMaxFFTRadix]complex64
+	for k := 0; k < m; k++ {
+		for q := 0; q < radix; q++ {
+			t[q] = out[q*m+k] * p.twiddle[(q*k*step)%p.n]
+		}
+		for s := 0; s < radix; s++ {
+			var sum complex64
+			for q := 0; q < radix; q++ {
+				sum += t[q] * p.twiddle[(q*s*radixStep)%p.n]
+			}
+			out[k+s*m] = sum
+		}
+	}
+}
+
+// forwardTransform 对实数窗口做FFT，输出前FREQ_SIZE个bin（按1/N归一化）
+func (d *goDenoiseState) forwardTransform(out []complex64, in []float32) {
+	for i := range d.fftIn {
+		d.fftIn[i] = complex(in[i], 0)
+	}
+	commonTables.fft.transform(d.fftOut[:], d.fftIn[:])
+	scale := float32(1) / goWindowSize
+	for i := 0; i < goFreqSize; i++ {
+		out[i] = d.fftOut[i] * complex(scale, 0)
+	}
+}
+
+// inverseTransform 由共轭对称频谱恢复实数窗口
+func (d *goDenoiseState) inverseTransform(out []float32, in []complex64) {
+	copy(d.fftIn[:goFreqSize], in)
+	for i := goFreqSize; i < goWindowSize; i++ {
+		v := d.fftIn[goWindowSize-i]
+		d.fftIn[i] = complex(real(v), -imag(v))
+	}
+	commonTables.fft.transform(d.fftOut[:], d.fftIn[:])
+	out[0] = real(d.fftOut[0])
+	for i := 1; i < goWindowSize; i++ {
+		out[i] = real(d.fftOut[goWindowSize-i])
+	}
+}
+
+func applyWindow(x []float32) {
+	for i := 0; i < goFrameSize; i++ {
+		x[i] *= commonTables.halfWindow[i]
+		x[goWindowSize-1-i] *= commonTables.halfWindow[i]
+	}
+}
+
+func computeBandEnergy(bandE []float32, x []complex64) {
+	var sum [goNbBands]float32
+	for i := 0; i < goNbBands-1; i++ {
+		bandSize := (eband5ms[i+1] - eband5ms[i]) << goFrameSizeShift
+		for j := 0; j < bandSize; j++ {
+			frac := float32(j) / float32(bandSize)
+			v := x[(eband5ms[i]<<goFrameSizeShift)+j]
+			tmp := real(v)*real(v) + imag(v)*imag(v)
+			sum[i] += (1 - frac) * tmp
+			sum[i+1] += frac * tmp
+		}
+	}
+	sum[0] *= 2
+	sum[goNbBands-1] *= 2
+	copy(bandE, sum[:])
+}
+
+func computeBandCorr(bandE []float32, x, p []complex64) {
+	var sum [goNbBands]float32
+	for i := 0; i < goNbBands-1; i++ {
+		bandSize := (eband5ms[i+1] - eband5ms[i]) << goFrameSizeShift
+		for j := 0; j < bandSize; j++ {
+			frac := float32(j) / float32(bandSize)
+			k := (eband5ms[i] << goFrameSizeShift) + j
+			tmp := real(x[k])*real(p[k]) + imag(x[k])*imag(p[k])
+			sum[i] += (1 - frac) * tmp
+			sum[i+1] += frac * tmp
+		}
+	}
+	sum[0] *= 2
+	sum[goNbBands-1] *= 2
+	copy(bandE, sum[:])
+}
+
+// interpBandGain 将每个频带的增益线性插值到每个频率bin
+func interpBandGain(g, bandE []float32) {
+	for i := range g {
+		g[i] = 0
+	}
+	for i := 0; i < goNbBands-1; i++ {
+		bandSize := (eband5ms[i+1] - eband5ms[i]) << goFrameSizeShift
+		for j := 0; j < bandSize; j++ {
+			frac := float32(j) / float32(bandSize)
+			g[(eband5ms[i]<<goFrameSizeShift)+j] = (1-frac)*bandE[i] + frac*bandE[i+1]
+		}
+	}
+}
+
+func dct(out, in []float32) {
+	scale := float32(math.Sqrt(2. / goNbBands))
+	for i := 0; i < goNbBands; i++ {
+		var sum float32
+		for j := 0; j < goNbBands; j++ {
+			sum += in[j] * commonTables.dctTable[j*goNbBands+i]
+		}
+		out[i] = sum * scale
+	}
+}
+
+// biquad 二阶IIR滤波，与RNNoise中去除直流的高通滤波器一致
+func biquad(y, mem, x []float32, b, a [2]float32) {
+	for i := range x {
+		xi := x[i]
+		yi := x[i] + mem[0]
+		mem[0] = mem[1] + (b[0]*xi - a[0]*yi)
+		mem[1] = b[1]*xi - a[1]*yi
+		y[i] = yi
+	}
+}
+
+func innerProd(x, y []float32, n int) float32 {
+	var sum float32
+	for i := 0; i < n; i++ {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
+
+func pitchXcorr(x, y, xcorr []float32, n, maxPitch int) {
+	for i := 0; i < maxPitch; i++ {
+		xcorr[i] = innerProd(x, y[i:], n)
+	}
+}
+
+func celtAutocorr(x, ac []float32, lag, n int) {
+	for k := 0; k <= lag; k++ {
+		var d float32
+		for i := k; i < n; i++ {
+			d += x[i] * x[i-k]
+		}
+		ac[k] = d
+	}
+}
+
+func celtLPC(lpc, ac []float32, p int) {
+	for i := 0; i < p; i++ {
+		lpc[i] = 0
+	}
+	errorE := ac[0]
+	if ac[0] == 0 {
+		return
+	}
+	for i := 0; i < p; i++ {
+		var rr float32
+		for j := 0; j < i; j++ {
+			rr += lpc[j] * ac[i-j]
+		}
+		rr += ac[i+1]
+		r := -rr / errorE
+		lpc[i] = r
+		for j := 0; j < (i+1)>>1; j++ {
+			tmp1 := lpc[j]
+			tmp2 := lpc[i-1-j]
+			lpc[j] = tmp1 + r*tmp2
+			lpc[i-1-j] = tmp2 + r*tmp1
+		}
+		errorE -= r * r * errorE
+		if errorE < .001*ac[0] {
+			break
+		}
+	}
+}
+
+func celtFIR5(x []float32, num [5]float32, n int) {
+	var mem [5]float32
+	for i := 0; i < n; i++ {
+		sum := x[i]
+		sum += num[0]*mem[0] + num[1]*mem[1] + num[2]*mem[2] + num[3]*mem[3] + num[4]*mem[4]
+		mem[4] = mem[3]
+		mem[3] = mem[2]
+		mem[2] = mem[1]
+		mem[1] = mem[0]
+		mem[0] = x[i]
+		x[i] = sum
+	}
+}
+
+// pitchDownsample 2倍降采样并做LPC白化
+func pitchDownsample(x, xLP []float32, n int) {
+	half := n >> 1
+	for i := 1; i < half; i++ {
+		xLP[i] = .5 * (.5*(x[2*i-1]+x[2*i+1]) + x[2*i])
+	}
+	xLP[0] = .5 * (.5*x[1] + x[0])
+
+	var ac [5]float32
+	celtAutocorr(xLP, ac[:], 4, half)
+	// 噪声底限 -40 dB
+	ac[0] *= 1.0001
+	// 滞后窗
+	for i := 1; i <= 4; i++ {
+		f := .008 * float32(i)
+		ac[i] -= ac[i] * f * f
+	}
+
+	var lpc [4]float32
+	celtLPC(lpc[:], ac[:], 4)
+	tmp := float32(1)
+	for i := 0; i < 4; i++ {
+		tmp *= .9
+		lpc[i] *= tmp
+	}
+
+	// 增加一个零点
+	const c1 = .8
+	lpc2 := [5]float32{
+		lpc[0] + .8,
+		lpc[1] + c1*lpc[0],
+		lpc[2] + c1*lpc[1],
+		lpc[3] + c1*lpc[2],
+		c1 * lpc[3],
+	}
+	celtFIR5(xLP, lpc2, half)
+}
+
+func findBestPitch(xcorr, y []float32, n, maxPitch int, bestPitch *[2]int) {
+	syy := float32(1)
+	bestNum := [2]float32{-1, -1}
+	bestDen := [2]float32{0, 0}
+	bestPitch[0] = 0
+	bestPitch[1] = 1
+	for j := 0; j < n; j++ {
+		syy += y[j] * y[j]
+	}
+	for i := 0; i < maxPitch; i++ {
+		if xcorr[i] > 0 {
+			// 缩放以避免平方时溢出或下溢
+			xcorr16 := xcorr[i] * 1e-12
+			num := xcorr16 * xcorr16
+			if num*bestDen[1] > bestNum[1]*syy {
+				if num*bestDen[0] > bestNum[0]*syy {
+					bestNum[1] = bestNum[0]
+					bestDen[1] = bestDen[0]
+					bestPitch[1] = bestPitch[0]
+					bestNum[0] = num
+					bestDen[0] = syy
+					bestPitch[0] = i
+				} else {
+					bestNum[1] = num
+					bestDen[1] = syy
+					bestPitch[1] = i
+				}
+			}
+		}
+		syy += y[i+n]*y[i+n] - y[i]*y[i]
+		if syy < 1 {
+			syy = 1
+		}
+	}
+}
+
+// pitchSearch 在降采样信号上先粗后细地搜索基音周期
+func pitchSearch(xLP, y []float32, n, maxPitch int, xcorr []float32) int {
+	lag := n + maxPitch
+	// 按RNNoise的最大尺寸在栈上分配，避免每帧分配内存
+	var xLP4Buf [goPitchFrameSize >> 2]float32
+	var yLP4Buf [(goPitchFrameSize + goPitchMaxPeriod) >> 2]float32
+	xLP4 := xLP4Buf[:n>>2]
+	yLP4 := yLP4Buf[:lag>>2]
+	for j := range xLP4 {
+		xLP4[j] = xLP[2*j]
+	}
+	for j := range yLP4 {
+		yLP4[j] = y[2*j]
+	}
+
+	// 4倍降采样的粗搜索
+	var bestPitch [2]int
+	pitchXcorr(xLP4, yLP4, xcorr, n>>2, maxPitch>>2)
+	findBestPitch(xcorr, yLP4, n>>2, maxPitch>>2, &bestPitch)
+
+	// 2倍降采样的细搜索
+	for i := 0; i < maxPitch>>1; i++ {
+		xcorr[i] = 0
+		if absInt(i-2*bestPitch[0]) > 2 && absInt(i-2*bestPitch[1]) > 2 {
+			continue
+		}
+		sum := innerProd(xLP, y[i:], n>>1)
+		if sum < -1 {
+			sum = -1
+		}
+		xcorr[i] = sum
+	}
+	findBestPitch(xcorr, y, n>>1, maxPitch>>1, &bestPitch)
+
+	// 伪插值细化
+	offset := 0
+	if bestPitch[0] > 0 && bestPitch[0] < (maxPitch>>1)-1 {
+		a := xcorr[bestPitch[0]-1]
+		b := xcorr[bestPitch[0]]
+		c := xcorr[bestPitch[0]+1]
+		if c-a > .7*(b-a) {
+			offset = 1
+		} else if a-c > .7*(b-c) {
+			offset = -1
+		}
+	}
+	return 2*bestPitch[0] - offset
+}
+
+func computePitchGain(xy, xx, yy float32) float32 {
+	return xy / float32(math.Sqrt(float64(1+xx*yy)))
+}
+
+var secondCheck = [16]int{0, 0, 3, 2, 3, 2, 5, 2, 3, 2, 3, 2, 5, 2, 3, 2}
+
+// removeDoubling 检查候选周期的约数，消除倍频错误，返回基音增益
+func removeDoubling(xBuf []float32, maxPeriod, minPeriod, n int, t0 *int, prevPeriod int, prevGain float32) float32 {
+	minPeriod0 := minPeriod
+	maxPeriod /= 2
+	minPeriod /= 2
+	*t0 /= 2
+	prevPeriod /= 2
+	n /= 2
+	// x[i] 对应 xBuf[base+i]，允许负下标
+	base := maxPeriod
+	x := func(i int) float32 { return xBuf[base+i] }
+	dotAt := func(a, b int) float32 {
+		var sum float32
+		for i := 0; i < n; i++ {
+			sum += xBuf[base+a+i] * xBuf[base+b+i]
+		}
+		return sum
+	}
+
+	if *t0 >= maxPeriod {
+		*t0 = maxPeriod - 1
+	}
+	T := *t0
+	T0 := *t0
+	xx := dotAt(0, 0)
+	xy := dotAt(0, -T0)
+
+	var yyLookupBuf [goPitchMaxPeriod/2 + 1]float32
+	yyLookup := yyLookupBuf[:maxPeriod+1]
+	yyLookup[0] = xx
+	yy := xx
+	for i := 1; i <= maxPeriod; i++ {
+		yy = yy + x(-i)*x(-i) - x(n-i)*x(n-i)
+		if yy < 0 {
+			yyLookup[i] = 0
+		} else {
+			yyLookup[i] = yy
+		}
+	}
+	yy = yyLookup[T0]
+	bestXY := xy
+	bestYY := yy
+	g0 := computePitchGain(xy, xx, yy)
+	g := g0
+
+	// 查找 T/k 处的基音
+	for k := 2; k <= 15; k++ {
+		T1 := (2*T0 + k) / (2 * k)
+		if T1 < minPeriod {
+			break
+		}
+		// 在 T1b 处寻找另一个强相关
+		var T1b int
+		if k == 2 {
+			if T1+T0 > maxPeriod {
+				T1b = T0
+			} else {
+				T1b = T0 + T1
+			}
+		} else {
+			T1b = (2*secondCheck[k]*T0 + k) / (2 * k)
+		}
+		xy = .5 * (dotAt(0, -T1) + dotAt(0, -T1b))
+		yy = .5 * (yyLookup[T1] + yyLookup[T1b])
+		g1 := computePitchGain(xy, xx, yy)
+
+		var cont float32
+		switch {
+		case absInt(T1-prevPeriod) <= 1:
+			cont = prevGain
+		case absInt(T1-prevPeriod) <= 2 && 5*k*k < T0:
+			cont = .5 * prevGain
+		}
+		thresh := maxFloat32(.3, .7*g0-cont)
+		// 对很短的周期提高门限，避免短时相关造成误判
+		if T1 < 3*minPeriod {
+			thresh = maxFloat32(.4, .85*g0-cont)
+		} else if T1 < 2*minPeriod {
+			thresh = maxFloat32(.5, .9*g0-cont)
+		}
+		if g1 > thresh {
+			bestXY = xy
+			bestYY = yy
+			T = T1
+			g = g1
+		}
+	}
+
+	if bestXY < 0 {
+		bestXY = 0
+	}
+	var pg float32
+	if bestYY <= bestXY {
+		pg = 1
+	} else {
+		pg = bestXY / (bestYY + 1)
+	}
+
+	var xcorr [3]float32
+	for k := 0; k < 3; k++ {
+		xcorr[k] = dotAt(0, -(T + k - 1))
+	}
+	offset := 0
+	if xcorr[2]-xcorr[0] > .7*(xcorr[1]-xcorr[0]) {
+		offset = 1
+	} else if xcorr[0]-xcorr[2] > .7*(xcorr[1]-xcorr[2]) {
+		offset = -1
+	}
+	if pg > g {
+		pg = g
+	}
+	*t0 = 2*T + offset
+	if *t0 < minPeriod0 {
+		*t0 = minPeriod0
+	}
+	return pg
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func maxFloat32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minFloat32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,100 @@
+//go:build cgo
+
+package rnnoise
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRecordGoBackendFixtures 用动态库加载真实模型权重处理一段WAV录音，
+// 保存为纯Go后端与C实现对比的基准数据
+//
+// 仅在设置了以下环境变量时运行：
+//
+//	RNNOISE_FIXTURE_LIB=/path/to/librnnoise.so \
+//	RNNOISE_FIXTURE_MODEL=/path/to/weights.rnnn \
+//	RNNOISE_FIXTURE_WAV=/path/to/noisy.wav \
+//	    go test -run TestRecordGoBackendFixtures ./rnnoise
+//
+// 模型需要是 rnnoise-nu 文本格式（可由RNNoise的 rnn_data.c 转换得到），
+// 动态库需要支持用 rnnoise_model_from_file 加载该格式，保证两个后端使用相同的权重。
+// 模型会复制到 testdata/purego 下与录制结果同名的 .rnnn 文件，
+// TestGoBackendFixtures 据此加载同一份权重。
+func TestRecordGoBackendFixtures(t *testing.T) {
+	libPath := os.Getenv("RNNOISE_FIXTURE_LIB")
+	modelPath := os.Getenv("RNNOISE_FIXTURE_MODEL")
+	wavPath := os.Getenv("RNNOISE_FIXTURE_WAV")
+	if libPath == "" || modelPath == "" || wavPath == "" {
+		t.Skip("未设置 RNNOISE_FIXTURE_LIB / RNNOISE_FIXTURE_MODEL / RNNOISE_FIXTURE_WAV，跳过录制")
+	}
+
+	libData, err := os.ReadFile(libPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	libSum := sha256.Sum256(libData)
+	modelData, err := os.ReadFile(modelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGoModelFile(modelPath); err != nil {
+		t.Fatalf("纯Go后端无法读取该模型: %v", err)
+	}
+
+	rnn, err := NewRNNoise(libPath, WithModelFile(modelPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rnn.Destroy()
+
+	processor := NewAudioProcessor(rnn)
+	audioData, err := processor.ReadWAV(wavPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted, err := processor.ConvertToRNNoiseFormat(audioData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames, err := processor.GetFrames(converted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(wavPath), filepath.Ext(wavPath))
+	fx := goFixture{
+		Source:  goFixtureSourceLibrary,
+		Library: hex.EncodeToString(libSum[:]),
+		Model:   base + ".rnnn",
+		Input:   frames,
+	}
+	for _, frame := range frames {
+		prob, out, err := rnn.ProcessFrame(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fx.Output = append(fx.Output, out)
+		fx.VoiceProbs = append(fx.VoiceProbs, prob)
+	}
+
+	data, err := json.Marshal(fx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(goFixtureDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(goFixtureDir, fx.Model), modelData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(goFixtureDir, base+".json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("已录制 %d 帧到 %s", len(frames), filepath.Join(goFixtureDir, base+".json"))
+}
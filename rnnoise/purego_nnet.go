@@ -0,0 +1,164 @@
+package rnnoise
+
+import (
+	"math"
+)
+
+// 纯Go后端的神经网络部分，对应RNNoise C实现中的rnn.c
+
+const (
+	activationTanh    = 0
+	activationSigmoid = 1
+	activationRelu    = 2
+
+	// goWeightsScale 模型权重以8位整数存储，计算时按1/256缩放
+	goWeightsScale = 1.0 / 256
+)
+
+// denseLayer 全连接层
+type denseLayer struct {
+	bias         []float32
+	inputWeights []float32
+	nbInputs     int
+	nbNeurons    int
+	activation   int
+}
+
+// gruLayer GRU层，权重按 [更新门, 重置门, 输出] 三段交错存储
+type gruLayer struct {
+	bias             []float32
+	inputWeights     []float32
+	recurrentWeights []float32
+	nbInputs         int
+	nbNeurons        int
+	activation       int
+}
+
+// goRNNState 三个GRU层的隐藏状态
+type goRNNState struct {
+	model             *GoModel
+	vadGRUState       []float32
+	noiseGRUState     []float32
+	denoiseGRUState   []float32
+	denseOut          []float32
+	noiseInput        []float32
+	denoiseInput      []float32
+	gruZ, gruR, gruHH []float32
+}
+
+func newGoRNNState(model *GoModel) *goRNNState {
+	maxNeurons := model.maxNeurons()
+	return &goRNNState{
+		model:           model,
+		vadGRUState:     make([]float32, model.vadGRU.nbNeurons),
+		noiseGRUState:   make([]float32, model.noiseGRU.nbNeurons),
+		denoiseGRUState: make([]float32, model.denoiseGRU.nbNeurons),
+		denseOut:        make([]float32, model.inputDense.nbNeurons),
+		noiseInput:      make([]float32, model.noiseGRU.nbInputs),
+		denoiseInput:    make([]float32, model.denoiseGRU.nbInputs),
+		gruZ:            make([]float32, maxNeurons),
+		gruR:            make([]float32, maxNeurons),
+		gruHH:           make([]float32, maxNeurons),
+	}
+}
+
+func (s *goRNNState) reset() {
+	for _, state := range [][]float32{s.vadGRUState, s.noiseGRUState, s.denoiseGRUState} {
+		for i := range state {
+			state[i] = 0
+		}
+	}
+}
+
+// compute 由特征计算每个频带的增益和语音概率
+func (s *goRNNState) compute(gains []float32, input []float32) float32 {
+	m := s.model
+	var vad [1]float32
+
+	m.inputDense.compute(s.denseOut, input)
+	m.vadGRU.compute(s.vadGRUState, s.denseOut, s)
+	m.vadOutput.compute(vad[:], s.vadGRUState)
+
+	n := copy(s.noiseInput, s.denseOut)
+	n += copy(s.noiseInput[n:], s.vadGRUState)
+	copy(s.noiseInput[n:], input)
+	m.noiseGRU.compute(s.noiseGRUState, s.noiseInput, s)
+
+	n = copy(s.denoiseInput, s.vadGRUState)
+	n += copy(s.denoiseInput[n:], s.noiseGRUState)
+	copy(s.denoiseInput[n:], input)
+	m.denoiseGRU.compute(s.denoiseGRUState, s.denoiseInput, s)
+	m.denoiseOutput.compute(gains, s.denoiseGRUState)
+
+	return vad[0]
+}
+
+func (l *denseLayer) compute(output, input []float32) {
+	stride := l.nbNeurons
+	for i := 0; i < l.nbNeurons; i++ {
+		sum := l.bias[i]
+		for j := 0; j < l.nbInputs; j++ {
+			sum += l.inputWeights[j*stride+i] * input[j]
+		}
+		output[i] = activate(l.activation, goWeightsScale*sum)
+	}
+}
+
+func (l *gruLayer) compute(state, input []float32, s *goRNNState) {
+	n := l.nbNeurons
+	stride := 3 * n
+	z, r, h := s.gruZ[:n], s.gruR[:n], s.gruHH[:n]
+
+	// 更新门
+	for i := 0; i < n; i++ {
+		sum := l.bias[i]
+		for j := 0; j < l.nbInputs; j++ {
+			sum += l.inputWeights[j*stride+i] * input[j]
+		}
+		for j := 0; j < n; j++ {
+			sum += l.recurrentWeights[j*stride+i] * state[j]
+		}
+		z[i] = sigmoid(goWeightsScale * sum)
+	}
+	// 重置门
+	for i := 0; i < n; i++ {
+		sum := l.bias[n+i]
+		for j := 0; j < l.nbInputs; j++ {
+			sum += l.inputWeights[n+j*stride+i] * input[j]
+		}
+		for j := 0; j < n; j++ {
+			sum += l.recurrentWeights[n+j*stride+i] * state[j]
+		}
+		r[i] = sigmoid(goWeightsScale * sum)
+	}
+	// 输出
+	for i := 0; i < n; i++ {
+		sum := l.bias[2*n+i]
+		for j := 0; j < l.nbInputs; j++ {
+			sum += l.inputWeights[2*n+j*stride+i] * input[j]
+		}
+		for j := 0; j < n; j++ {
+			sum += l.recurrentWeights[2*n+j*stride+i] * state[j] * r[j]
+		}
+		h[i] = z[i]*state[i] + (1-z[i])*activate(l.activation, goWeightsScale*sum)
+	}
+	copy(state, h)
+}
+
+func activate(activation int, x float32) float32 {
+	switch activation {
+	case activationSigmoid:
+		return sigmoid(x)
+	case activationRelu:
+		if x < 0 {
+			return 0
+		}
+		return x
+	default:
+		return float32(math.Tanh(float64(x)))
+	}
+}
+
+func sigmoid(x float32) float32 {
+	return .5 + .5*float32(math.Tanh(.5*float64(x)))
+}
@@ -0,0 +1,292 @@
+package rnnoise
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// goFixtureDir 纯Go后端输出对比所用的录制数据目录
+//
+// 每个 *.json 记录一段输入帧及其处理结果，model 字段指明录制时使用的同目录模型文件：
+//   - source 为 "librnnoise" 的数据由 purego_fixture_cgo_test.go 通过动态库加载真实模型权重录制，
+//     是纯Go后端与C实现数值一致性的基准
+//   - source 为 "purego" 的数据由纯Go后端自身录制（synthetic.json 使用
+//     syntheticModelText(7) 生成的 model.rnnn），只作为数值回归基准，不能代替与C实现的对比
+const goFixtureDir = "testdata/purego"
+
+const (
+	goFixtureSourceLibrary = "librnnoise"
+	goFixtureSourceGo      = "purego"
+
+	// goFixtureMaxProbDiff 每帧语音概率与录制值的最大允许偏差
+	goFixtureMaxProbDiff = 0.02
+	// goFixtureMinSNR 输出相对录制值的最低信噪比（dB），
+	// 纯Go后端的激活函数近似和浮点运算顺序与C实现不同，误差应远小于信号本身
+	goFixtureMinSNR = 30
+)
+
+// goFixture 一段录制的处理结果
+type goFixture struct {
+	Source     string      `json:"source"`
+	Library    string      `json:"library,omitempty"` // 录制所用动态库的SHA-256
+	Model      string      `json:"model"`
+	Input      [][]float32 `json:"input"`
+	Output     [][]float32 `json:"output"`
+	VoiceProbs []float32   `json:"voice_probs"`
+}
+
+// loadGoFixtures 读取指定来源的录制数据
+func loadGoFixtures(t *testing.T, source string) map[string]goFixture {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(goFixtureDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := make(map[string]goFixture)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fx goFixture
+		if err := json.Unmarshal(data, &fx); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if fx.Source == source {
+			fixtures[filepath.Base(path)] = fx
+		}
+	}
+	return fixtures
+}
+
+// checkGoFixture 用纯Go后端处理录制的输入，并在容差内与录制的输出对比
+func checkGoFixture(t *testing.T, fx goFixture) {
+	t.Helper()
+	model, err := LoadGoModelFile(filepath.Join(goFixtureDir, fx.Model))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnn, err := NewGoRNNoise(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rnn.Destroy()
+
+	var errEnergy, refEnergy float64
+	for i, frame := range fx.Input {
+		prob, out, err := rnn.ProcessFrame(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := math.Abs(float64(prob - fx.VoiceProbs[i])); d > goFixtureMaxProbDiff {
+			t.Errorf("frame %d: voice probability %f, want %f", i, prob, fx.VoiceProbs[i])
+		}
+		for j := range out {
+			e := float64(out[j] - fx.Output[i][j])
+			errEnergy += e * e
+			refEnergy += float64(fx.Output[i][j]) * float64(fx.Output[i][j])
+		}
+	}
+
+	if snr := 10 * math.Log10(refEnergy/(errEnergy+1e-20)); snr < goFixtureMinSNR {
+		t.Errorf("output SNR against fixture = %.1f dB, want >= %d dB", snr, goFixtureMinSNR)
+	}
+}
+
+// syntheticModelText 生成维度合法、权重随机的模型文本，用于不依赖真实权重的测试
+func syntheticModelText(seed int64) string {
+	rng := rand.New(rand.NewSource(seed))
+	var b strings.Builder
+	b.WriteString("rnnoise-nu model file version 1\n")
+	array := func(n int) {
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, "%d ", rng.Intn(255)-127)
+		}
+		b.WriteString("\n")
+	}
+	dense := func(in, out, act int) {
+		fmt.Fprintf(&b, "%d %d %d\n", in, out, act)
+		array(in * out)
+		array(out)
+	}
+	gru := func(in, out, act int) {
+		fmt.Fprintf(&b, "%d %d %d\n", in, out, act)
+		array(in * out * 3)
+		array(out * out * 3)
+		array(out * 3)
+	}
+	dense(goNbFeatures, 4, activationTanh)
+	gru(4, 3, activationRelu)
+	gru(4+3+goNbFeatures, 5, activationRelu)
+	gru(3+5+goNbFeatures, 6, activationRelu)
+	dense(6, goNbBands, activationSigmoid)
+	dense(3, 1, activationSigmoid)
+	return b.String()
+}
+
+func TestFFTMatchesDFT(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	in := make([]complex64, goWindowSize)
+	for i := range in {
+		in[i] = complex(rng.Float32()*2-1, rng.Float32()*2-1)
+	}
+	out := make([]complex64, goWindowSize)
+	commonTables.fft.transform(out, in)
+
+	for k := 0; k < goWindowSize; k++ {
+		var want complex128
+		for n := 0; n < goWindowSize; n++ {
+			phase := -2 * math.Pi * float64(k*n) / goWindowSize
+			want += complex128(in[n]) * cmplx.Exp(complex(0, phase))
+		}
+		if d := cmplx.Abs(complex128(out[k]) - want); d > 1e-2 {
+			t.Fatalf("bin %d: got %v, want %v", k, out[k], want)
+		}
+	}
+}
+
+func TestAnalysisSynthesisReconstruction(t *testing.T) {
+	d := newGoDenoiseState(mustSyntheticModel(t))
+	rng := rand.New(rand.NewSource(2))
+	var ex [goNbBands]float32
+	var prev []float32
+
+	for frame := 0; frame < 4; frame++ {
+		in := make([]float32, goFrameSize)
+		for i := range in {
+			in[i] = (rng.Float32()*2 - 1) * 10000
+		}
+		out := make([]float32, goFrameSize)
+		d.frameAnalysis(d.x[:], ex[:], in)
+		d.frameSynthesis(out, d.x[:])
+
+		// 窗函数满足功率互补，输出应为延迟一帧的输入
+		if prev != nil {
+			for i := range out {
+				if math.Abs(float64(out[i]-prev[i])) > 0.5 {
+					t.Fatalf("frame %d sample %d: got %f, want %f", frame, i, out[i], prev[i])
+				}
+			}
+		}
+		prev = in
+	}
+}
+
+func TestPitchSearchPeriodicSignal(t *testing.T) {
+	const period = 200
+	var buf [goPitchBufSize]float32
+	for i := range buf {
+		phase := float64(i%period) / period
+		buf[i] = float32(math.Exp(-20*phase)*8000 + 500*math.Sin(2*math.Pi*3*phase))
+	}
+
+	var lp [goPitchBufSize >> 1]float32
+	var xcorr [goPitchMaxPeriod >> 1]float32
+	pitchDownsample(buf[:], lp[:], goPitchBufSize)
+	index := pitchSearch(lp[goPitchMaxPeriod>>1:], lp[:], goPitchFrameSize,
+		goPitchMaxPeriod-3*goPitchMinPeriod, xcorr[:])
+	index = goPitchMaxPeriod - index
+	gain := removeDoubling(lp[:], goPitchMaxPeriod, goPitchMinPeriod, goPitchFrameSize, &index, 0, 0)
+
+	if absInt(index-period) > 2 {
+		t.Errorf("pitch period = %d, want %d", index, period)
+	}
+	if gain < 0.5 {
+		t.Errorf("pitch gain = %f, want strongly periodic", gain)
+	}
+}
+
+func TestLoadGoModel(t *testing.T) {
+	if _, err := LoadGoModel(strings.NewReader("not a model")); err == nil {
+		t.Error("LoadGoModel() expected error for invalid header")
+	}
+
+	truncated := syntheticModelText(3)
+	if _, err := LoadGoModel(strings.NewReader(truncated[:len(truncated)/2])); err == nil {
+		t.Error("LoadGoModel() expected error for truncated model")
+	}
+
+	if _, err := NewGoRNNoise(nil); err == nil {
+		t.Error("NewGoRNNoise(nil) expected error")
+	}
+}
+
+func TestGoRNNoiseProcessFrame(t *testing.T) {
+	rnn, err := NewGoRNNoise(mustSyntheticModel(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rnn.Destroy()
+
+	rng := rand.New(rand.NewSource(4))
+	frame := make([]float32, 480)
+	for n := 0; n < 20; n++ {
+		for i := range frame {
+			frame[i] = float32(0.3*math.Sin(2*math.Pi*220*float64(n*480+i)/48000)) + (rng.Float32()-0.5)*0.05
+		}
+		prob, out, err := rnn.ProcessFrame(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prob < 0 || prob > 1 {
+			t.Fatalf("voice probability %f out of range", prob)
+		}
+		for _, v := range out {
+			if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+				t.Fatal("output contains NaN/Inf")
+			}
+		}
+	}
+
+	if err := rnn.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	rnn.Destroy()
+	if _, _, err := rnn.ProcessFrame(frame); err == nil {
+		t.Error("ProcessFrame() after Destroy expected error")
+	}
+}
+
+// TestGoBackendFixtures 对比纯Go后端与 testdata/purego 中所有录制数据的输出
+//
+// 与C实现数值一致的验收标准目前未满足：仓库中还没有 source 为 "librnnoise" 的数据。
+// 随附的动态库是 rnnoise 0.2 构建，其内置模型与纯Go后端读取的 rnnoise-nu 文本模型不兼容，
+// 需要在能加载兼容动态库和模型的平台上用 TestRecordGoBackendFixtures 录制后提交；
+// 录制的数据提交后由本测试自动覆盖，在此之前只校验纯Go后端自身的回归数据
+func TestGoBackendFixtures(t *testing.T) {
+	fixtures := loadGoFixtures(t, goFixtureSourceGo)
+	if len(fixtures) == 0 {
+		t.Fatalf("未找到纯Go后端的回归数据（%s）", goFixtureDir)
+	}
+	modelText, err := os.ReadFile(filepath.Join(goFixtureDir, "model.rnnn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(modelText) != syntheticModelText(7) {
+		t.Fatal("model.rnnn 与 syntheticModelText(7) 不一致")
+	}
+	for name, fx := range loadGoFixtures(t, goFixtureSourceLibrary) {
+		fixtures[name] = fx
+	}
+	for name, fx := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			checkGoFixture(t, fx)
+		})
+	}
+}
+
+func mustSyntheticModel(t *testing.T) *GoModel {
+	t.Helper()
+	model, err := LoadGoModel(strings.NewReader(syntheticModelText(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model
+}
@@ -0,0 +1,187 @@
+package rnnoise
+
+import "github.com/zhangzhao-gg/go-rnnoise/rnnoise/resample"
+
+// Resampler 采样率转换器
+//
+// 采样率由调用方保证大于0：NoiseFilter、StreamFilter、AudioProcessor和流水线阶段在调用之前检查采样率并返回错误，
+// 直接调用时传入不大于0的采样率，PolyphaseResampler会panic
+type Resampler interface {
+	// Resample 将单声道样本从fromRate转换到toRate
+	Resample(samples []float32, fromRate, toRate int) []float32
+}
+
+// PolyphaseResampler 带限多相采样率转换（默认）
+//
+// 使用resample包的Kaiser窗sinc滤波器，采样率之比按精确的有理数处理，
+// 降采样时抑制混叠、升采样时抑制镜像。Quality为零值时使用resample.QualityMedium
+type PolyphaseResampler struct {
+	Quality resample.Quality
+}
+
+// Resample 带限重采样
+func (p PolyphaseResampler) Resample(samples []float32, fromRate, toRate int) []float32 {
+	return resample.Resampler{Quality: p.Quality}.Resample(samples, fromRate, toRate)
+}
+
+// NewStream 创建同样质量的流式转换器，输出与Resample逐样本一致
+func (p PolyphaseResampler) NewStream(fromRate, toRate int) StreamResampler {
+	if fromRate == toRate {
+		return passthroughResampler{}
+	}
+	return resample.NewStream(fromRate, toRate, p.Quality)
+}
+
+// LinearResampler 简单的线性插值采样率转换
+//
+// 实现简单、开销小，但没有抗混叠滤波，降采样时会产生混叠；
+// 需要与旧版本完全一致的输出时可以通过WithResampler(LinearResampler{})使用
+type LinearResampler struct{}
+
+// Resample 线性插值重采样
+func (LinearResampler) Resample(samples []float32, fromRate, toRate int) []float32 {
+	if fromRate == toRate {
+		return samples
+	}
+
+	ratio := float64(toRate) / float64(fromRate)
+	newLength := int(float64(len(samples)) * ratio)
+	resampledSamples := make([]float32, newLength)
+
+	for i := 0; i < newLength; i++ {
+		srcIndex := float64(i) / ratio
+		srcIndexInt := int(srcIndex)
+
+		if srcIndexInt >= len(samples)-1 {
+			resampledSamples[i] = samples[len(samples)-1]
+		} else {
+			// 线性插值
+			frac := float32(srcIndex - float64(srcIndexInt))
+			resampledSamples[i] = samples[srcIndexInt]*(1-frac) + samples[srcIndexInt+1]*frac
+		}
+	}
+
+	return resampledSamples
+}
+
+// StreamResampler 跨调用保持历史的流式采样率转换器
+//
+// 连续调用Process的输出拼接起来与一次性转换整段音频的结果一致，
+// 分块边界不会产生不连续
+type StreamResampler interface {
+	// Process 转换一块样本，返回目前可以确定的输出样本
+	Process(samples []float32) []float32
+	// Flush 输出内部缓存的剩余样本，之后重新开始一段新的流
+	Flush() []float32
+	// Reset 丢弃内部缓存，重新开始一段新的流
+	Reset()
+}
+
+// StreamResamplerFactory 可以创建流式转换器的Resampler
+//
+// StreamFilter 优先使用配置的Resampler创建流式转换器，
+// 未实现该接口的Resampler会退回到PolyphaseResampler的流式转换
+type StreamResamplerFactory interface {
+	NewStream(fromRate, toRate int) StreamResampler
+}
+
+// NewStream 创建线性插值的流式转换器，输出与Resample逐样本一致
+func (LinearResampler) NewStream(fromRate, toRate int) StreamResampler {
+	if fromRate == toRate {
+		return passthroughResampler{}
+	}
+	return &linearStreamResampler{fromRate: int64(fromRate), toRate: int64(toRate)}
+}
+
+// newStreamResampler 根据配置的Resampler创建流式转换器
+func newStreamResampler(resampler Resampler, fromRate, toRate int) StreamResampler {
+	if factory, ok := resampler.(StreamResamplerFactory); ok {
+		return factory.NewStream(fromRate, toRate)
+	}
+	return PolyphaseResampler{}.NewStream(fromRate, toRate)
+}
+
+// passthroughResampler 采样率相同时直接输出
+type passthroughResampler struct{}
+
+func (passthroughResampler) Process(samples []float32) []float32 {
+	return append([]float32(nil), samples...)
+}
+
+func (passthroughResampler) Flush() []float32 { return nil }
+
+func (passthroughResampler) Reset() {}
+
+// linearStreamResampler 流式线性插值
+//
+// 第n个输出样本位于输入的n*fromRate/toRate处，位置用整数计算，长时间运行也不会累积误差
+type linearStreamResampler struct {
+	fromRate, toRate int64
+	history          []float32 // 尚未用完的输入样本
+	historyStart     int64     // history[0]在整个流中的下标
+	produced         int64     // 已经输出的样本数
+}
+
+func (l *linearStreamResampler) Process(samples []float32) []float32 {
+	l.history = append(l.history, samples...)
+
+	var out []float32
+	for l.withinLength() {
+		i := l.produced * l.fromRate / l.toRate
+		// 插值需要i+1处的样本，没有时等待下一块
+		if i+1 >= l.consumed() {
+			break
+		}
+		out = append(out, l.interpolate())
+		l.produced++
+	}
+
+	// 只保留下一个输出样本需要的历史
+	keepFrom := l.produced * l.fromRate / l.toRate
+	if last := l.consumed() - 1; keepFrom > last {
+		keepFrom = last
+	}
+	if drop := keepFrom - l.historyStart; drop > 0 {
+		l.history = l.history[:copy(l.history, l.history[drop:])]
+		l.historyStart = keepFrom
+	}
+	return out
+}
+
+func (l *linearStreamResampler) Flush() []float32 {
+	// 与LinearResampler一致：输出floor(输入长度*toRate/fromRate)个样本，超出末尾时保持最后一个样本
+	var out []float32
+	for l.withinLength() {
+		out = append(out, l.interpolate())
+		l.produced++
+	}
+	l.Reset()
+	return out
+}
+
+func (l *linearStreamResampler) Reset() {
+	l.history = l.history[:0]
+	l.historyStart, l.produced = 0, 0
+}
+
+// consumed 已经输入的样本数
+func (l *linearStreamResampler) consumed() int64 {
+	return l.historyStart + int64(len(l.history))
+}
+
+// withinLength 下一个输出样本是否在floor(已输入长度*toRate/fromRate)之内
+func (l *linearStreamResampler) withinLength() bool {
+	return (l.produced+1)*l.fromRate <= l.consumed()*l.toRate
+}
+
+// interpolate 计算下一个输出样本
+func (l *linearStreamResampler) interpolate() float32 {
+	num := l.produced * l.fromRate
+	i := num / l.toRate
+	last := l.consumed() - 1
+	if i >= last {
+		return l.history[last-l.historyStart]
+	}
+	frac := float32(num%l.toRate) / float32(l.toRate)
+	return l.history[i-l.historyStart]*(1-frac) + l.history[i+1-l.historyStart]*frac
+}
@@ -0,0 +1,301 @@
+// Package resample 带限多相（polyphase）采样率转换
+//
+// 采样率之比按最大公约数约分为精确的有理数L/M（例如48000->8000为1/6，44100->48000为160/147），
+// 使用Kaiser窗加权的sinc低通滤波器抑制降采样时的混叠和升采样时的镜像。
+// 滤波器以输出样本为中心（零相位），整段转换和流式转换的结果逐样本一致，且没有时间偏移。
+//
+// 示例:
+//
+//	// 整段转换
+//	out := resample.Resampler{Quality: resample.QualityHigh}.Resample(samples, 48000, 8000)
+//
+//	// 流式转换
+//	stream := resample.NewStream(8000, 48000, resample.QualityMedium)
+//	for chunk := range chunks {
+//	    out := stream.Process(chunk)
+//	    ...
+//	}
+//	tail := stream.Flush()
+package resample
+
+import (
+	"math"
+	"sync"
+)
+
+// Quality 转换质量预设，质量越高滤波器越长、计算量越大
+type Quality int
+
+const (
+	// QualityDefault 使用QualityMedium
+	QualityDefault Quality = iota
+	// QualityLow 阻带衰减约60dB，通带约为新奈奎斯特频率的55%，适合语音检测等对音质要求不高的场景
+	QualityLow
+	// QualityMedium 阻带衰减约85dB，通带约为新奈奎斯特频率的78%
+	QualityMedium
+	// QualityHigh 阻带衰减约110dB，通带约为新奈奎斯特频率的85%
+	QualityHigh
+)
+
+// params 质量预设对应的滤波器参数
+type params struct {
+	zeroCrossings int     // 较低采样率下单侧的sinc过零点数
+	attenuation   float64 // 阻带衰减（dB）
+}
+
+func (q Quality) params() params {
+	switch q {
+	case QualityLow:
+		return params{zeroCrossings: 8, attenuation: 60}
+	case QualityHigh:
+		return params{zeroCrossings: 48, attenuation: 110}
+	default:
+		return params{zeroCrossings: 24, attenuation: 85}
+	}
+}
+
+// Ratio 将采样率之比约分为toRate/fromRate = L/M
+func Ratio(fromRate, toRate int) (l, m int) {
+	g := gcd(fromRate, toRate)
+	return toRate / g, fromRate / g
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// filterBank 多相滤波器组
+//
+// 第n个输出样本位于输入的t=n*M/L处，取phases[(n*M)%L]与
+// 输入x[floor(t)-halfLen+1 .. floor(t)+halfLen]做内积
+type filterBank struct {
+	l, m    int64
+	halfLen int         // 单侧使用的输入样本数
+	phases  [][]float32 // L个相位，每个2*halfLen个系数
+}
+
+type bankKey struct {
+	l, m    int
+	quality Quality
+}
+
+// cachedRates 常用采样率，两端都在其中的转换比的滤波器组会被缓存，
+// 数量有限（每种质量最多20个）；其他转换比每个转换器单独设计，随转换器一起释放，
+// 避免长期运行的服务遇到任意输入采样率时缓存无限增长
+var cachedRates = map[int]bool{8000: true, 16000: true, 22050: true, 44100: true, 48000: true}
+
+// banks 常用转换比的滤波器组缓存，同一转换比和质量只设计一次
+var banks sync.Map
+
+func getFilterBank(fromRate, toRate int, quality Quality) *filterBank {
+	l, m := Ratio(fromRate, toRate)
+	if !cachedRates[fromRate] || !cachedRates[toRate] {
+		return designFilterBank(l, m, quality.params())
+	}
+	key := bankKey{l, m, quality}
+	if bank, ok := banks.Load(key); ok {
+		return bank.(*filterBank)
+	}
+	bank, _ := banks.LoadOrStore(key, designFilterBank(l, m, quality.params()))
+	return bank.(*filterBank)
+}
+
+// designFilterBank 设计Kaiser窗sinc低通滤波器并拆分为L个相位
+func designFilterBank(l, m int, p params) *filterBank {
+	// 滤波器在较低的采样率下设计，scale为较低采样率与输入采样率之比
+	scale := math.Min(1, float64(l)/float64(m))
+	beta := kaiserBeta(p.attenuation)
+	// Kaiser窗的过渡带宽度（相对较低采样率），截止频率放在过渡带中心，
+	// 使阻带从较低采样率的奈奎斯特频率开始
+	transition := (p.attenuation - 7.95) / (14.36 * float64(2*p.zeroCrossings))
+	cutoff := (0.5 - transition/2) * scale // 相对输入采样率（周期/样本）
+
+	halfLen := int(math.Ceil(float64(p.zeroCrossings) / scale))
+	taps := 2 * halfLen
+	i0Beta := besselI0(beta)
+
+	bank := &filterBank{l: int64(l), m: int64(m), halfLen: halfLen, phases: make([][]float32, l)}
+	coefs := make([]float64, taps)
+	for phase := 0; phase < l; phase++ {
+		frac := float64(phase) / float64(l)
+		var sum float64
+		for k := 0; k < taps; k++ {
+			t := frac + float64(halfLen-1-k)
+			x := t / float64(halfLen)
+			if x <= -1 || x >= 1 {
+				coefs[k] = 0
+				continue
+			}
+			window := besselI0(beta*math.Sqrt(1-x*x)) / i0Beta
+			coefs[k] = 2 * cutoff * sinc(2*cutoff*t) * window
+			sum += coefs[k]
+		}
+
+		// 每个相位归一化为单位直流增益
+		bank.phases[phase] = make([]float32, taps)
+		for k, c := range coefs {
+			bank.phases[phase][k] = float32(c / sum)
+		}
+	}
+	return bank
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiserBeta 根据阻带衰减（dB）计算Kaiser窗的beta
+func kaiserBeta(attenuation float64) float64 {
+	switch {
+	case attenuation > 50:
+		return 0.1102 * (attenuation - 8.7)
+	case attenuation >= 21:
+		return 0.5842*math.Pow(attenuation-21, 0.4) + 0.07886*(attenuation-21)
+	default:
+		return 0
+	}
+}
+
+// besselI0 第一类零阶修正贝塞尔函数（级数展开）
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 500; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-17 {
+			break
+		}
+	}
+	return sum
+}
+
+// Resampler 整段转换的带限重采样器
+type Resampler struct {
+	Quality Quality
+}
+
+// Resample 将单声道样本从fromRate转换到toRate
+//
+// 输出floor(len(samples)*toRate/fromRate)个样本，首尾之外的输入视为静音。
+// 采样率不大于0时panic（见NewStream）
+func (r Resampler) Resample(samples []float32, fromRate, toRate int) []float32 {
+	if fromRate == toRate {
+		return samples
+	}
+	stream := NewStream(fromRate, toRate, r.Quality)
+	return append(stream.Process(samples), stream.Flush()...)
+}
+
+// NewStream 创建同样质量的流式转换器
+func (r Resampler) NewStream(fromRate, toRate int) *Stream {
+	return NewStream(fromRate, toRate, r.Quality)
+}
+
+// Stream 跨调用保持历史的流式转换器
+//
+// 连续调用Process的输出加上Flush的输出，与Resampler对整段音频的转换结果逐样本一致。
+// 每个输出样本需要其后Latency个输入样本，因此输出相对输入有固定的延迟
+type Stream struct {
+	bank         *filterBank
+	history      []float32 // 尚未用完的输入，history[0]为第historyStart个样本
+	historyStart int64
+	consumed     int64 // 已输入的样本数
+	produced     int64 // 已输出的样本数
+}
+
+// NewStream 创建从fromRate转换到toRate的流式转换器
+//
+// 采样率不大于0属于编程错误，会panic；采样率来自外部输入时调用方需要先检查
+func NewStream(fromRate, toRate int, quality Quality) *Stream {
+	if fromRate <= 0 || toRate <= 0 {
+		panic("resample: 采样率必须大于0")
+	}
+	s := &Stream{bank: getFilterBank(fromRate, toRate, quality)}
+	s.Reset()
+	return s
+}
+
+// Latency 输出第n个样本前需要额外等待的输入样本数
+func (s *Stream) Latency() int {
+	return s.bank.halfLen
+}
+
+// Process 转换一块样本，返回目前可以确定的输出样本
+func (s *Stream) Process(samples []float32) []float32 {
+	s.history = append(s.history, samples...)
+	s.consumed += int64(len(samples))
+
+	var out []float32
+	for s.withinLength() {
+		first := s.firstInput()
+		// 需要的最后一个输入样本还没有到达时等待下一块
+		if first+int64(2*s.bank.halfLen) > s.consumed {
+			break
+		}
+		out = append(out, s.next(first))
+	}
+	s.trim()
+	return out
+}
+
+// Flush 将流结束之后的输入视为静音，输出剩余的样本，然后重新开始一段新的流
+func (s *Stream) Flush() []float32 {
+	s.history = append(s.history, make([]float32, s.bank.halfLen)...)
+
+	var out []float32
+	for s.withinLength() {
+		out = append(out, s.next(s.firstInput()))
+	}
+	s.Reset()
+	return out
+}
+
+// Reset 丢弃历史，重新开始一段新的流
+func (s *Stream) Reset() {
+	// 流开始之前的输入视为静音
+	s.history = append(s.history[:0], make([]float32, s.bank.halfLen)...)
+	s.historyStart = -int64(s.bank.halfLen)
+	s.consumed, s.produced = 0, 0
+}
+
+// withinLength 下一个输出样本是否在floor(已输入长度*L/M)之内
+func (s *Stream) withinLength() bool {
+	return (s.produced+1)*s.bank.m <= s.consumed*s.bank.l
+}
+
+// firstInput 下一个输出样本需要的第一个输入样本的下标
+func (s *Stream) firstInput() int64 {
+	return s.produced*s.bank.m/s.bank.l - int64(s.bank.halfLen) + 1
+}
+
+// next 计算下一个输出样本
+func (s *Stream) next(first int64) float32 {
+	coefs := s.bank.phases[(s.produced*s.bank.m)%s.bank.l]
+	x := s.history[first-s.historyStart:]
+	x = x[:len(coefs)]
+
+	var sum float32
+	for k, c := range coefs {
+		sum += c * x[k]
+	}
+	s.produced++
+	return sum
+}
+
+// trim 丢弃之后不会再用到的历史
+func (s *Stream) trim() {
+	keepFrom := s.firstInput()
+	if drop := keepFrom - s.historyStart; drop > 0 {
+		if drop > int64(len(s.history)) {
+			drop = int64(len(s.history))
+		}
+		s.history = s.history[:copy(s.history, s.history[drop:])]
+		s.historyStart += drop
+	}
+}
@@ -0,0 +1,235 @@
+package resample
+
+import (
+	"math"
+	"testing"
+)
+
+func sine(n, rate int, freq, amp float64) []float32 {
+	x := make([]float32, n)
+	for i := range x {
+		x[i] = float32(amp * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return x
+}
+
+// toneAmplitude 用正交投影测量x中freq频率分量的幅度，x应包含整数个周期
+func toneAmplitude(x []float32, rate int, freq float64) float64 {
+	var s, c float64
+	for i, v := range x {
+		phase := 2 * math.Pi * freq * float64(i) / float64(rate)
+		s += float64(v) * math.Sin(phase)
+		c += float64(v) * math.Cos(phase)
+	}
+	return 2 * math.Hypot(s, c) / float64(len(x))
+}
+
+func rms(x []float32) float64 {
+	var sum float64
+	for _, v := range x {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum / float64(len(x)))
+}
+
+func db(x float64) float64 {
+	return 20 * math.Log10(x)
+}
+
+// steady 去掉首尾受边界影响的部分，保留整数秒的样本
+func steady(x []float32, rate int) []float32 {
+	return x[rate/2 : rate/2+rate]
+}
+
+func TestRatio(t *testing.T) {
+	cases := []struct{ from, to, l, m int }{
+		{8000, 48000, 6, 1},
+		{16000, 48000, 3, 1},
+		{22050, 48000, 320, 147},
+		{44100, 48000, 160, 147},
+		{48000, 44100, 147, 160},
+		{48000, 8000, 1, 6},
+		{48000, 16000, 1, 3},
+	}
+	for _, tc := range cases {
+		if l, m := Ratio(tc.from, tc.to); l != tc.l || m != tc.m {
+			t.Errorf("Ratio(%d, %d) = %d/%d, want %d/%d", tc.from, tc.to, l, m, tc.l, tc.m)
+		}
+	}
+}
+
+func TestOutputLengthIsExact(t *testing.T) {
+	for _, rates := range [][2]int{{44100, 48000}, {48000, 22050}, {8000, 48000}, {48000, 16000}} {
+		for _, n := range []int{0, 1, 147, 1000, 44101} {
+			out := Resampler{}.Resample(make([]float32, n), rates[0], rates[1])
+			if want := n * rates[1] / rates[0]; len(out) != want {
+				t.Errorf("%d->%d with %d samples: got %d, want %d", rates[0], rates[1], n, len(out), want)
+			}
+		}
+	}
+}
+
+func TestPassbandRipple(t *testing.T) {
+	cases := []struct {
+		quality  Quality
+		from, to int
+		maxFreq  float64 // 通带上限
+		maxDB    float64 // 允许的最大幅度误差
+	}{
+		{QualityLow, 48000, 8000, 2000, 0.1},
+		{QualityMedium, 48000, 8000, 3000, 0.01},
+		{QualityMedium, 8000, 48000, 3000, 0.01},
+		{QualityMedium, 48000, 16000, 6000, 0.01},
+		{QualityHigh, 44100, 48000, 18000, 0.01},
+	}
+	for _, tc := range cases {
+		lowRate := tc.from
+		if tc.to < lowRate {
+			lowRate = tc.to
+		}
+		worst := 0.0
+		for freq := 100.0; freq <= tc.maxFreq; freq += tc.maxFreq / 20 {
+			freq = math.Round(freq)
+			out := Resampler{Quality: tc.quality}.Resample(sine(2*tc.from, tc.from, freq, 0.5), tc.from, tc.to)
+			gain := db(toneAmplitude(steady(out, tc.to), tc.to, freq) / 0.5)
+			worst = math.Max(worst, math.Abs(gain))
+		}
+		if worst > tc.maxDB {
+			t.Errorf("quality %d %d->%d: passband ripple %.4f dB up to %.0f Hz (low rate %d), want <= %.3f dB",
+				tc.quality, tc.from, tc.to, worst, tc.maxFreq, lowRate, tc.maxDB)
+		}
+	}
+}
+
+func TestAliasingRejection(t *testing.T) {
+	// 降采样到8kHz时，高于4kHz的分量必须被滤除，而不是混叠到可听频段
+	cases := []struct {
+		quality Quality
+		freq    float64
+		minDB   float64
+	}{
+		{QualityLow, 6000, 55},
+		{QualityMedium, 4500, 85},
+		{QualityMedium, 10000, 85},
+		{QualityHigh, 4200, 110},
+	}
+	for _, tc := range cases {
+		out := Resampler{Quality: tc.quality}.Resample(sine(96000, 48000, tc.freq, 0.5), 48000, 8000)
+		rejection := -db(rms(steady(out, 8000)) / (0.5 / math.Sqrt2))
+		if rejection < tc.minDB {
+			t.Errorf("quality %d: %.0f Hz rejected by %.1f dB, want >= %.0f dB", tc.quality, tc.freq, rejection, tc.minDB)
+		}
+	}
+
+	// 线性插值没有抗混叠滤波，作为对照
+	linear := linearResample(sine(96000, 48000, 6000, 0.5), 6)
+	if rejection := -db(rms(steady(linear, 8000)) / (0.5 / math.Sqrt2)); rejection > 20 {
+		t.Errorf("expected naive decimation to alias, rejection %.1f dB", rejection)
+	}
+}
+
+// linearResample 线性插值降采样（整数倍时等价于直接抽取）
+func linearResample(x []float32, factor int) []float32 {
+	out := make([]float32, len(x)/factor)
+	for i := range out {
+		out[i] = x[i*factor]
+	}
+	return out
+}
+
+func TestImageRejection(t *testing.T) {
+	// 升采样时3kHz的镜像（5kHz、11kHz……）必须被滤除
+	out := Resampler{Quality: QualityMedium}.Resample(sine(16000, 8000, 3000, 0.5), 8000, 48000)
+	x := steady(out, 48000)
+
+	// 去掉3kHz基波后剩余的能量即镜像
+	var s, c float64
+	for i, v := range x {
+		phase := 2 * math.Pi * 3000 * float64(i) / 48000
+		s += float64(v) * math.Sin(phase)
+		c += float64(v) * math.Cos(phase)
+	}
+	s, c = 2*s/float64(len(x)), 2*c/float64(len(x))
+	residual := make([]float32, len(x))
+	for i, v := range x {
+		phase := 2 * math.Pi * 3000 * float64(i) / 48000
+		residual[i] = v - float32(s*math.Sin(phase)+c*math.Cos(phase))
+	}
+
+	if gain := db(math.Hypot(s, c) / 0.5); math.Abs(gain) > 0.01 {
+		t.Errorf("3 kHz gain = %.4f dB, want 0", gain)
+	}
+	if rejection := -db(rms(residual) / (0.5 / math.Sqrt2)); rejection < 80 {
+		t.Errorf("image rejection %.1f dB, want >= 80 dB", rejection)
+	}
+}
+
+func TestStreamMatchesBatch(t *testing.T) {
+	for _, rates := range [][2]int{{8000, 48000}, {48000, 8000}, {44100, 48000}, {48000, 22050}} {
+		input := sine(5003, rates[0], 440, 0.5)
+		want := Resampler{}.Resample(input, rates[0], rates[1])
+
+		stream := NewStream(rates[0], rates[1], QualityDefault)
+		var got []float32
+		sizes := []int{1, 37, 160, 999, 2}
+		for i, pos := 0, 0; pos < len(input); i++ {
+			end := pos + sizes[i%len(sizes)]
+			if end > len(input) {
+				end = len(input)
+			}
+			got = append(got, stream.Process(input[pos:end])...)
+			pos = end
+		}
+		got = append(got, stream.Flush()...)
+
+		if len(got) != len(want) {
+			t.Fatalf("%d->%d: stream produced %d samples, batch %d", rates[0], rates[1], len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%d->%d: sample %d = %g, want %g", rates[0], rates[1], i, got[i], want[i])
+			}
+		}
+
+		// Flush之后可以开始新的一段流
+		again := append(stream.Process(input), stream.Flush()...)
+		if len(again) != len(want) || again[len(want)/2] != want[len(want)/2] {
+			t.Fatalf("%d->%d: stream not reusable after Flush", rates[0], rates[1])
+		}
+	}
+}
+
+func TestStreamHistoryIsBounded(t *testing.T) {
+	stream := NewStream(48000, 8000, QualityHigh)
+	chunk := sine(480, 48000, 440, 0.5)
+	for i := 0; i < 1000; i++ {
+		stream.Process(chunk)
+	}
+	if limit := 2*stream.Latency() + 2*480; len(stream.history) > limit {
+		t.Errorf("history holds %d samples, want <= %d", len(stream.history), limit)
+	}
+}
+
+func TestFilterBankCacheIsBounded(t *testing.T) {
+	count := func() int {
+		n := 0
+		banks.Range(func(_, _ interface{}) bool {
+			n++
+			return true
+		})
+		return n
+	}
+
+	NewStream(48000, 16000, QualityLow)
+	before := count()
+	if NewStream(48000, 16000, QualityLow).bank != NewStream(48000, 16000, QualityLow).bank {
+		t.Error("common ratio should share a cached filter bank")
+	}
+
+	for rate := 11000; rate < 11020; rate++ {
+		NewStream(rate, 48000, QualityLow)
+	}
+	if got := count(); got != before {
+		t.Errorf("cache grew from %d to %d entries for uncommon rates", before, got)
+	}
+}
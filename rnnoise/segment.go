@@ -0,0 +1,124 @@
+package rnnoise
+
+import "time"
+
+// Segment 一段连续的语音
+type Segment struct {
+	Start    time.Duration // 起始时间
+	End      time.Duration // 结束时间（不含）
+	MeanProb float32       // 段内各帧语音概率的平均值
+	PeakProb float32       // 段内各帧语音概率的最大值
+}
+
+// Duration 语音段时长
+func (s Segment) Duration() time.Duration {
+	return s.End - s.Start
+}
+
+// SpeechSegments 根据每帧的语音概率（例如FilterResult.VoiceProbabilities）提取语音段
+//
+// 语音判定使用vad（先被重置），vad为nil时使用DefaultVADConfig。
+// 第i帧对应的时间为[i, i+1)乘以VADConfig.FrameDuration
+func SpeechSegments(voiceProbs []float32, vad *VAD) []Segment {
+	if vad == nil {
+		vad, _ = NewVAD(DefaultVADConfig())
+	}
+	frameDuration := vad.cfg.FrameDuration
+	speech := vad.Detect(voiceProbs)
+
+	var segments []Segment
+	for i := 0; i < len(speech); {
+		if !speech[i] {
+			i++
+			continue
+		}
+
+		start := i
+		var sum float32
+		segment := Segment{Start: time.Duration(start) * frameDuration}
+		for ; i < len(speech) && speech[i]; i++ {
+			sum += voiceProbs[i]
+			if voiceProbs[i] > segment.PeakProb {
+				segment.PeakProb = voiceProbs[i]
+			}
+		}
+		segment.End = time.Duration(i) * frameDuration
+		segment.MeanProb = sum / float32(i-start)
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// DetectSegments 计算音频每帧的语音概率并提取语音段，用于按语音切分录音
+//
+// 最后一段的结束时间不会超过音频时长。vad为nil时使用DefaultVADConfig
+//
+// 示例:
+//
+//	segments, err := filter.DetectSegments(audioData, nil)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, seg := range segments {
+//	    clip := audioData.Slice(seg.Start, seg.End)
+//	    ...
+//	}
+func (nf *NoiseFilter) DetectSegments(audioData *AudioData, vad *VAD) ([]Segment, error) {
+	if err := nf.guard.acquire(); err != nil {
+		return nil, err
+	}
+	defer nf.guard.release()
+
+	voiceProbs, err := nf.analyzeVoiceProbs(audioData)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := SpeechSegments(voiceProbs, vad)
+	if n := len(segments); n > 0 {
+		if duration := audioData.Duration(); segments[n-1].End > duration {
+			segments[n-1].End = duration
+		}
+	}
+	return segments, nil
+}
+
+// Duration 音频时长
+func (a *AudioData) Duration() time.Duration {
+	if a.SampleRate <= 0 || a.Channels <= 0 {
+		return 0
+	}
+	return time.Duration(len(a.Samples)/a.Channels) * time.Second / time.Duration(a.SampleRate)
+}
+
+// Slice 截取[start, end)时间范围内的音频，超出音频范围的部分会被截断
+//
+// 返回的AudioData与a共享样本数据
+func (a *AudioData) Slice(start, end time.Duration) *AudioData {
+	frames := 0
+	if a.Channels > 0 {
+		frames = len(a.Samples) / a.Channels
+	}
+	toFrame := func(d time.Duration) int {
+		i := int(d * time.Duration(a.SampleRate) / time.Second)
+		if i < 0 {
+			return 0
+		}
+		if i > frames {
+			return frames
+		}
+		return i
+	}
+
+	first, last := toFrame(start), toFrame(end)
+	if last < first {
+		last = first
+	}
+	return &AudioData{
+		Samples:    a.Samples[first*a.Channels : last*a.Channels],
+		SampleRate: a.SampleRate,
+		Channels:   a.Channels,
+		BitDepth:   a.BitDepth,
+		Float:      a.Float,
+	}
+}
@@ -0,0 +1,105 @@
+package rnnoise
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpeechSegments(t *testing.T) {
+	vad, err := NewVAD(rawVADConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	probs := []float32{0.1, 0.6, 0.9, 0.1, 0.1, 0.7, 0.1}
+	segments := SpeechSegments(probs, vad)
+
+	want := []Segment{
+		{Start: 10 * time.Millisecond, End: 30 * time.Millisecond, MeanProb: 0.75, PeakProb: 0.9},
+		{Start: 50 * time.Millisecond, End: 60 * time.Millisecond, MeanProb: 0.7, PeakProb: 0.7},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("SpeechSegments() = %+v, want %+v", segments, want)
+	}
+	for i := range want {
+		if segments[i] != want[i] {
+			t.Errorf("segment %d = %+v, want %+v", i, segments[i], want[i])
+		}
+	}
+	if d := segments[0].Duration(); d != 20*time.Millisecond {
+		t.Errorf("Duration() = %v, want 20ms", d)
+	}
+
+	if got := SpeechSegments(nil, nil); len(got) != 0 {
+		t.Errorf("SpeechSegments(nil) = %+v, want none", got)
+	}
+}
+
+func TestDetectSegments(t *testing.T) {
+	filter, err := NewNoiseFilter(WithDenoiser(NewFakeDenoiser(0.1, 0.9, 0.9, 0.9)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	// 3帧加半帧，最后半帧补零处理后仍为语音，段结束时间截断到音频时长
+	audioData := &AudioData{Samples: make([]float32, 8000*35/1000), SampleRate: 8000, Channels: 1, BitDepth: 16}
+	vad, err := NewVAD(rawVADConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments, err := filter.DetectSegments(audioData, vad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 || segments[0].Start != 10*time.Millisecond || segments[0].End != 35*time.Millisecond {
+		t.Errorf("DetectSegments() = %+v, want one segment from 10ms to 35ms", segments)
+	}
+}
+
+func TestAudioDataSlice(t *testing.T) {
+	audioData := &AudioData{SampleRate: 1000, Channels: 2, BitDepth: 16}
+	for i := 0; i < 100; i++ {
+		audioData.Samples = append(audioData.Samples, float32(i), -float32(i))
+	}
+
+	clip := audioData.Slice(10*time.Millisecond, 20*time.Millisecond)
+	if len(clip.Samples) != 20 || clip.Samples[0] != 10 || clip.Samples[1] != -10 || clip.Channels != 2 {
+		t.Errorf("Slice(10ms, 20ms) = %v", clip.Samples)
+	}
+	if d := audioData.Duration(); d != 100*time.Millisecond {
+		t.Errorf("Duration() = %v, want 100ms", d)
+	}
+	if clip := audioData.Slice(90*time.Millisecond, time.Second); len(clip.Samples) != 20 {
+		t.Errorf("Slice past the end returned %d samples, want 20", len(clip.Samples))
+	}
+}
+
+func TestAudioDataSliceFloatRoundTrip(t *testing.T) {
+	audioData := &AudioData{SampleRate: 1000, Channels: 1, BitDepth: 32, Float: true}
+	for i := 0; i < 100; i++ {
+		audioData.Samples = append(audioData.Samples, float32(i)/100+0.001)
+	}
+
+	ap := NewAudioProcessor(nil)
+	path := filepath.Join(t.TempDir(), "clip.wav")
+	clip := audioData.Slice(10*time.Millisecond, 20*time.Millisecond)
+	if !clip.Float || clip.BitDepth != 32 {
+		t.Fatalf("Slice() Float = %v, BitDepth = %d, want 32-bit float", clip.Float, clip.BitDepth)
+	}
+	if err := ap.WriteWAV(path, clip); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ap.ReadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Float || got.BitDepth != 32 || len(got.Samples) != len(clip.Samples) {
+		t.Fatalf("ReadWAV() Float = %v, BitDepth = %d, %d samples", got.Float, got.BitDepth, len(got.Samples))
+	}
+	for i, s := range got.Samples {
+		if s != clip.Samples[i] {
+			t.Fatalf("sample %d = %v, want %v", i, s, clip.Samples[i])
+		}
+	}
+}
@@ -0,0 +1,561 @@
+package rnnoise
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// gateAttack 噪声门打开时增益上升的时间常数
+	gateAttack = time.Millisecond
+	// gateRelease 噪声门关闭时增益下降的时间常数
+	gateRelease = 10 * time.Millisecond
+)
+
+// resampleStage 流式采样率转换阶段
+type resampleStage struct {
+	rate      int
+	resampler Resampler
+	from      int
+	streams   []StreamResampler // 每个声道的转换器，收到第一块时创建
+	clock     timeline
+}
+
+// NewResampleStage 将采样率转换为sampleRate，resampler为nil时使用PolyphaseResampler
+//
+// resampler实现了StreamResamplerFactory时用于流式转换，否则使用PolyphaseResampler
+func NewResampleStage(sampleRate int, resampler Resampler) (Stage, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("采样率必须大于0，当前为%d", sampleRate)
+	}
+	if resampler == nil {
+		resampler = PolyphaseResampler{}
+	}
+	return &resampleStage{rate: sampleRate, resampler: resampler}, nil
+}
+
+// Process 转换一块样本
+func (s *resampleStage) Process(block *Block) (*Block, error) {
+	if s.streams == nil {
+		if block.SampleRate <= 0 || block.Channels() == 0 {
+			return nil, fmt.Errorf("无效的音频格式: %dHz, %d声道", block.SampleRate, block.Channels())
+		}
+		s.from = block.SampleRate
+		s.streams = make([]StreamResampler, block.Channels())
+		for ch := range s.streams {
+			s.streams[ch] = newStreamResampler(s.resampler, s.from, s.rate)
+		}
+	} else if block.SampleRate != s.from || block.Channels() != len(s.streams) {
+		return nil, fmt.Errorf("流中的音频格式发生变化: %dHz %d声道 -> %dHz %d声道",
+			s.from, len(s.streams), block.SampleRate, block.Channels())
+	}
+
+	s.clock.start(block)
+	out := &Block{
+		Samples:    make([][]float32, len(s.streams)),
+		SampleRate: s.rate,
+		VoiceProbs: block.VoiceProbs,
+	}
+	for ch, stream := range s.streams {
+		out.Samples[ch] = stream.Process(block.Samples[ch])
+	}
+	out.Timestamp = s.clock.next(out.Len(), s.rate)
+	return out, nil
+}
+
+// Flush 输出转换器中的剩余样本
+func (s *resampleStage) Flush() (*Block, error) {
+	if s.streams == nil {
+		return nil, nil
+	}
+	out := &Block{Samples: make([][]float32, len(s.streams)), SampleRate: s.rate}
+	for ch, stream := range s.streams {
+		out.Samples[ch] = stream.Flush()
+	}
+	out.Timestamp = s.clock.next(out.Len(), s.rate)
+	s.clock.reset()
+	return out, nil
+}
+
+// Reset 丢弃转换器中缓存的样本
+func (s *resampleStage) Reset() error {
+	for _, stream := range s.streams {
+		stream.Reset()
+	}
+	s.clock.reset()
+	return nil
+}
+
+// channelMixStage 声道数转换阶段
+type channelMixStage struct {
+	channels int
+}
+
+// NewChannelMixStage 将声道数转换为channels
+//
+// 多声道混合为单声道时取各声道的平均值，单声道扩展为多声道时复制到每个声道，
+// 声道数相同时原样输出；其他组合返回错误
+func NewChannelMixStage(channels int) (Stage, error) {
+	if channels <= 0 {
+		return nil, fmt.Errorf("声道数必须大于0，当前为%d", channels)
+	}
+	return &channelMixStage{channels: channels}, nil
+}
+
+// Process 转换一块样本的声道数
+func (s *channelMixStage) Process(block *Block) (*Block, error) {
+	switch in := block.Channels(); {
+	case in == s.channels:
+		return block, nil
+	case s.channels == 1:
+		block.Samples = [][]float32{mixDown(block.Samples)}
+	case in == 1:
+		samples := make([][]float32, s.channels)
+		samples[0] = block.Samples[0]
+		for ch := 1; ch < s.channels; ch++ {
+			samples[ch] = append([]float32(nil), block.Samples[0]...)
+		}
+		block.Samples = samples
+	default:
+		return nil, fmt.Errorf("不支持从%d声道转换为%d声道", in, s.channels)
+	}
+	return block, nil
+}
+
+// Flush 没有缓存的样本
+func (s *channelMixStage) Flush() (*Block, error) {
+	return nil, nil
+}
+
+// Reset 没有状态
+func (s *channelMixStage) Reset() error {
+	return nil
+}
+
+// mixDown 将各声道取平均值混合为单声道，与downmix的结果一致
+func mixDown(channels [][]float32) []float32 {
+	if len(channels) == 1 {
+		return channels[0]
+	}
+	out := make([]float32, len(channels[0]))
+	for i := range out {
+		var sum float32
+		for _, samples := range channels {
+			sum += samples[i]
+		}
+		out[i] = sum / float32(len(channels))
+	}
+	return out
+}
+
+// DenoiseStage 按NoiseFilter的配置降噪的阶段
+//
+// 与StreamFilter一样在内部转换到降噪后端的采样率并逐帧处理，依次完成预处理、降噪、
+// 干湿混合、AGC、输出策略和限幅，输出采样率与输入相同。多声道按WithChannelMode处理，
+// ChannelDownmix时输出单声道。响度归一化需要整段音频，不在本阶段内完成，需要时在之后接NewLoudnessStage
+type DenoiseStage struct {
+	nf      *NoiseFilter
+	owned   bool // nf由NewDenoiseStage创建，Close时销毁
+	opts    coreOptions
+	collect func(*streamOutput) // 每次输出前调用，离线处理用来收集语音概率和统计信息，可以为nil
+
+	core     *streamCore // 收到第一块时按采样率和声道数创建
+	rate     int
+	channels int
+	clock    timeline
+}
+
+// NewDenoiseStage 创建降噪阶段，选项与NewNoiseFilter相同
+func NewDenoiseStage(opts ...Option) (*DenoiseStage, error) {
+	nf, err := NewNoiseFilter(opts...)
+	if err != nil {
+		return nil, err
+	}
+	stageOpts := nf.coreOptions(DefaultThreshold)
+	stageOpts.limit = nf.limiter
+	return &DenoiseStage{nf: nf, owned: true, opts: stageOpts}, nil
+}
+
+// newDenoiseStage 创建使用nf自身降噪后端的降噪阶段，Close时不销毁nf
+func (nf *NoiseFilter) newDenoiseStage(opts coreOptions, collect func(*streamOutput)) *DenoiseStage {
+	return &DenoiseStage{nf: nf, opts: opts, collect: collect}
+}
+
+// coreOptions 按阈值和延迟补偿配置的流式核心参数，不在核心内限幅
+func (nf *NoiseFilter) coreOptions(voiceProbThreshold float32) coreOptions {
+	return coreOptions{threshold: nf.resolveThreshold(voiceProbThreshold), latency: nf.latency()}
+}
+
+// Process 降噪一块样本，只处理凑满的帧
+func (s *DenoiseStage) Process(block *Block) (*Block, error) {
+	if s.core == nil {
+		if err := s.init(block); err != nil {
+			return nil, err
+		}
+	} else if block.SampleRate != s.rate || block.Channels() != s.channels {
+		return nil, fmt.Errorf("流中的音频格式发生变化: %dHz %d声道 -> %dHz %d声道",
+			s.rate, s.channels, block.SampleRate, block.Channels())
+	}
+
+	inputs := block.Samples
+	if len(s.core.channels) == 1 {
+		inputs = [][]float32{mixDown(block.Samples)}
+	}
+	s.clock.start(block)
+	out, err := s.core.process(inputs)
+	if err != nil {
+		return nil, err
+	}
+	return s.output(out), nil
+}
+
+// init 按第一块的格式创建流式核心
+func (s *DenoiseStage) init(block *Block) error {
+	if block.SampleRate <= 0 || block.Channels() == 0 {
+		return fmt.Errorf("无效的音频格式: %dHz, %d声道", block.SampleRate, block.Channels())
+	}
+	denoisers := []FrameDenoiser{s.nf.denoiser}
+	if s.nf.channelMode != ChannelDownmix && block.Channels() > 1 {
+		var err error
+		if denoisers, err = s.nf.channelDenoisersFor(block.Channels()); err != nil {
+			return err
+		}
+	}
+	core, err := newStreamCore(s.nf, block.SampleRate, denoisers, s.opts)
+	if err != nil {
+		return err
+	}
+	s.core, s.rate, s.channels = core, block.SampleRate, block.Channels()
+	return nil
+}
+
+// Flush 处理缓存的剩余样本并重置降噪状态
+//
+// 过滤器离线处理内部使用的阶段只处理一段音频，不重置降噪后端，降噪状态由过滤器保留（见NoiseFilter.Reset）
+func (s *DenoiseStage) Flush() (*Block, error) {
+	if s.core == nil {
+		return nil, nil
+	}
+	out, err := s.core.flush()
+	if err != nil {
+		return nil, err
+	}
+	block := s.output(out)
+	s.clock.reset()
+	if !s.owned {
+		return block, nil
+	}
+	if err := s.core.reset(); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// output 将流式核心的输出转换为块
+func (s *DenoiseStage) output(out *streamOutput) *Block {
+	if s.collect != nil {
+		s.collect(out)
+	}
+	block := &Block{Samples: out.samples, SampleRate: s.rate, VoiceProbs: out.voiceProbs}
+	block.Timestamp = s.clock.next(block.Len(), s.rate)
+	return block
+}
+
+// Reset 丢弃缓存的样本并重置降噪状态
+func (s *DenoiseStage) Reset() error {
+	s.clock.reset()
+	if s.core == nil {
+		return nil
+	}
+	return s.core.reset()
+}
+
+// Close 销毁NewDenoiseStage创建的过滤器，释放降噪后端
+func (s *DenoiseStage) Close() error {
+	if s.owned {
+		s.nf.Destroy()
+	}
+	return nil
+}
+
+// gateStage 按电平开关的噪声门
+type gateStage struct {
+	threshold float64
+	hold      time.Duration
+
+	rate    int
+	holdFor int     // 电平低于阈值后保持打开的样本数
+	attack  float64 // 打开时每个样本的平滑系数
+	release float64 // 关闭时每个样本的平滑系数
+	open    int     // 剩余保持打开的样本数
+	gain    float64
+}
+
+// NewGateStage 噪声门：任一声道的样本幅度达到thresholdDB（dBFS）时打开，
+// 低于阈值hold时长后关闭，增益平滑过渡，各声道共享同一个增益
+func NewGateStage(thresholdDB float64, hold time.Duration) (Stage, error) {
+	if thresholdDB > 0 {
+		return nil, fmt.Errorf("噪声门阈值不能大于0dBFS，当前为%v", thresholdDB)
+	}
+	if hold < 0 {
+		return nil, fmt.Errorf("噪声门保持时间不能为负数，当前为%v", hold)
+	}
+	return &gateStage{threshold: math.Pow(10, thresholdDB/20), hold: hold}, nil
+}
+
+// Process 对一块样本施加噪声门（原地修改）
+func (s *gateStage) Process(block *Block) (*Block, error) {
+	if block.SampleRate != s.rate {
+		s.rate = block.SampleRate
+		rate := float64(s.rate)
+		s.holdFor = int(s.hold.Seconds() * rate)
+		s.attack = 1 - math.Exp(-1/(gateAttack.Seconds()*rate))
+		s.release = 1 - math.Exp(-1/(gateRelease.Seconds()*rate))
+	}
+
+	for i := 0; i < block.Len(); i++ {
+		var level float64
+		for _, samples := range block.Samples {
+			level = math.Max(level, math.Abs(float64(samples[i])))
+		}
+		if level >= s.threshold {
+			s.open = s.holdFor + 1
+		}
+		if s.open > 0 {
+			s.open--
+			s.gain += s.attack * (1 - s.gain)
+		} else {
+			s.gain -= s.release * s.gain
+		}
+		for _, samples := range block.Samples {
+			samples[i] = float32(float64(samples[i]) * s.gain)
+		}
+	}
+	return block, nil
+}
+
+// Flush 没有缓存的样本，开始新的一段流
+func (s *gateStage) Flush() (*Block, error) {
+	return nil, s.Reset()
+}
+
+// Reset 噪声门回到关闭状态
+func (s *gateStage) Reset() error {
+	s.open, s.gain = 0, 0
+	return nil
+}
+
+// gainStage 固定增益
+type gainStage struct {
+	gain float32
+}
+
+// NewGainStage 对所有样本施加gainDB（dB）的固定增益
+func NewGainStage(gainDB float64) Stage {
+	return &gainStage{gain: float32(math.Pow(10, gainDB/20))}
+}
+
+// Process 对一块样本施加增益（原地修改）
+func (s *gainStage) Process(block *Block) (*Block, error) {
+	for _, samples := range block.Samples {
+		for i := range samples {
+			samples[i] *= s.gain
+		}
+	}
+	return block, nil
+}
+
+// Flush 没有缓存的样本
+func (s *gainStage) Flush() (*Block, error) {
+	return nil, nil
+}
+
+// Reset 没有状态
+func (s *gainStage) Reset() error {
+	return nil
+}
+
+// loudnessStage 响度归一化阶段
+type loudnessStage struct {
+	target  float64
+	logger  logrus.FieldLogger
+	pending *Block // 缓存的整段音频，收到第一块之前为nil
+}
+
+// NewLoudnessStage 将整段流的积分响度（ITU-R BS.1770 / EBU R128）归一化到targetLUFS（见WithLoudnessNormalization）
+//
+// 响度需要整段音频才能测量：Process缓存所有样本不输出，Flush时测量响度、施加增益后一次输出，
+// 内存占用与流的长度成正比。没有可测量的响度时原样输出；通常在之后接LimiterStage
+func NewLoudnessStage(targetLUFS float64) (Stage, error) {
+	if targetLUFS >= 0 || targetLUFS <= loudnessAbsoluteGate {
+		return nil, fmt.Errorf("目标响度必须在%v到0 LUFS之间，当前为%v", loudnessAbsoluteGate, targetLUFS)
+	}
+	return &loudnessStage{target: targetLUFS, logger: logrus.StandardLogger()}, nil
+}
+
+// Process 缓存一块样本
+func (s *loudnessStage) Process(block *Block) (*Block, error) {
+	if s.pending == nil {
+		s.pending = &Block{Samples: make([][]float32, block.Channels()), SampleRate: block.SampleRate, Timestamp: block.Timestamp}
+	} else if block.SampleRate != s.pending.SampleRate || block.Channels() != s.pending.Channels() {
+		return nil, fmt.Errorf("流中的音频格式发生变化: %dHz %d声道 -> %dHz %d声道",
+			s.pending.SampleRate, s.pending.Channels(), block.SampleRate, block.Channels())
+	}
+	for ch, samples := range block.Samples {
+		s.pending.Samples[ch] = append(s.pending.Samples[ch], samples...)
+	}
+	s.pending.VoiceProbs = append(s.pending.VoiceProbs, block.VoiceProbs...)
+	return nil, nil
+}
+
+// Flush 测量缓存音频的响度，归一化后输出
+func (s *loudnessStage) Flush() (*Block, error) {
+	out := s.pending
+	if out == nil {
+		return nil, nil
+	}
+	s.pending = nil
+	meter, err := NewLoudnessMeter(out.SampleRate, out.Channels())
+	if err != nil {
+		return nil, err
+	}
+	meter.writeChannels(out.Samples)
+	applyGain(out.Samples, normalizationGain(s.logger, meter.Integrated(), s.target))
+	return out, nil
+}
+
+// Reset 丢弃缓存的音频
+func (s *loudnessStage) Reset() error {
+	s.pending = nil
+	return nil
+}
+
+// limiterStage 真峰值限幅阶段
+type limiterStage struct {
+	ceiling  float64
+	limiter  *truePeakLimiter // 收到第一块时按采样率和声道数创建
+	rate     int
+	channels int
+	clock    timeline
+}
+
+// NewLimiterStage 上限为ceiling dBTP的真峰值限幅器（见WithTruePeakLimiter）
+//
+// 限幅器带来约2ms的延迟，Flush时取回，整个流的输出与输入等长
+func NewLimiterStage(ceiling float64) (Stage, error) {
+	if ceiling > 0 {
+		return nil, fmt.Errorf("真峰值上限不能大于0dBTP，当前为%v", ceiling)
+	}
+	return &limiterStage{ceiling: ceiling}, nil
+}
+
+// Process 限幅一块样本
+func (s *limiterStage) Process(block *Block) (*Block, error) {
+	if s.limiter == nil {
+		s.limiter = newTruePeakLimiter(s.ceiling, block.SampleRate, block.Channels())
+		s.rate, s.channels = block.SampleRate, block.Channels()
+	} else if block.SampleRate != s.rate || block.Channels() != s.channels {
+		return nil, fmt.Errorf("流中的音频格式发生变化: %dHz %d声道 -> %dHz %d声道",
+			s.rate, s.channels, block.SampleRate, block.Channels())
+	}
+
+	s.clock.start(block)
+	out := &Block{Samples: s.limiter.process(block.Samples), SampleRate: s.rate, VoiceProbs: block.VoiceProbs}
+	out.Timestamp = s.clock.next(out.Len(), s.rate)
+	return out, nil
+}
+
+// Flush 取回延迟线中的样本，之后限幅器回到初始状态
+func (s *limiterStage) Flush() (*Block, error) {
+	if s.limiter == nil {
+		return nil, nil
+	}
+	out := &Block{Samples: s.limiter.flush(), SampleRate: s.rate}
+	out.Timestamp = s.clock.next(out.Len(), s.rate)
+	s.limiter.reset()
+	s.clock.reset()
+	return out, nil
+}
+
+// Reset 清空延迟线和增益状态
+func (s *limiterStage) Reset() error {
+	s.limiter.reset()
+	s.clock.reset()
+	return nil
+}
+
+// tapStage 观察经过的块
+type tapStage struct {
+	fn func(*Block)
+}
+
+// NewTapStage 对经过的每一块调用fn后原样传递，用于测量、录制或调试
+//
+// fn不应修改或保留块中的样本，需要保留时请复制
+func NewTapStage(fn func(*Block)) Stage {
+	return &tapStage{fn: fn}
+}
+
+// Process 调用fn
+func (s *tapStage) Process(block *Block) (*Block, error) {
+	s.fn(block)
+	return block, nil
+}
+
+// Flush 没有缓存的样本
+func (s *tapStage) Flush() (*Block, error) {
+	return nil, nil
+}
+
+// Reset 没有状态
+func (s *tapStage) Reset() error {
+	return nil
+}
+
+// NewNoiseFilterPipeline 创建NoiseFilter流式处理所用的流水线，选项与NewNoiseFilter相同
+//
+// 由以下阶段组成：ChannelDownmix时混合为单声道的ChannelMixStage、DenoiseStage，
+// 启用限幅（WithTruePeakLimiter、WithAGC或WithLoudnessNormalization）时再接LimiterStage。
+// StreamFilter就运行在这条流水线上；FilterAudio等离线处理使用同样的阶段，
+// 启用WithLoudnessNormalization时在限幅之前多一个NewLoudnessStage。
+// 可以在返回的阶段前后加入其他阶段组成新的流水线
+func NewNoiseFilterPipeline(opts ...Option) (*Pipeline, error) {
+	p, _, err := newNoiseFilterPipeline(opts)
+	return p, err
+}
+
+// newNoiseFilterPipeline 创建NoiseFilter的流式流水线，同时返回其中的降噪阶段
+func newNoiseFilterPipeline(opts []Option) (*Pipeline, *DenoiseStage, error) {
+	nf, err := NewNoiseFilter(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 限幅作为单独的阶段，降噪阶段内不再限幅
+	denoise := &DenoiseStage{nf: nf, owned: true, opts: nf.coreOptions(DefaultThreshold)}
+	return nf.newPipeline(denoise, nf.outputStages(false)...), denoise, nil
+}
+
+// newPipeline 按过滤器的配置组装流水线：ChannelDownmix时先混合为单声道，然后是denoise和post中的阶段
+func (nf *NoiseFilter) newPipeline(denoise *DenoiseStage, post ...Stage) *Pipeline {
+	var stages []Stage
+	if nf.channelMode == ChannelDownmix {
+		stages = append(stages, &channelMixStage{channels: 1})
+	}
+	stages = append(stages, denoise)
+	return NewPipeline(append(stages, post...)...)
+}
+
+// outputStages 降噪之后的输出阶段：loudness为true且启用了响度归一化时先归一化响度，启用限幅时再限幅
+func (nf *NoiseFilter) outputStages(loudness bool) []Stage {
+	var stages []Stage
+	if loudness && nf.normalizeLoudness {
+		stages = append(stages, &loudnessStage{target: nf.loudnessTarget, logger: nf.logger})
+	}
+	if nf.limiter {
+		stages = append(stages, &limiterStage{ceiling: nf.truePeakCeiling})
+	}
+	return stages
+}
@@ -0,0 +1,499 @@
+package rnnoise
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StreamFilter 有状态的流式降噪过滤器
+//
+// 与FilterStream要求每次恰好传入一帧48kHz音频不同，StreamFilter接受任意采样率、任意长度的分块：
+// 不足一帧的样本会缓存到下一次调用，重采样器的历史跨调用保留，
+// 因此分块边界不会引入补零和重采样造成的不连续。每凑满一帧就立即输出。
+//
+// 输出相对输入有一定延迟（缓存的不足一帧的样本以及重采样器的历史），
+// 流结束时调用Flush取回剩余的输出；整个流的输出与输入逐样本等长（OutputDrop策略下减去被丢弃的帧）。
+// WithLatencyCompensation时同时补偿降噪后端的固有延迟；启用真峰值限幅器时输出另有约2ms的前瞻延迟。
+// WithAGC和WithTruePeakLimiter同样适用，WithLoudnessNormalization只用于离线处理。
+// StreamFilter运行在NewNoiseFilterPipeline创建的流水线上，与自行组装的流水线结果一致。
+// 与NoiseFilter一样，同一实例同时只能被一个goroutine使用。
+//
+// 示例:
+//
+//	// 8kHz电话音频，每次20ms（160个样本）
+//	sf, err := NewStreamFilter(8000, WithThreshold(0.3), WithOutputPolicy(OutputSilence))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer sf.Destroy()
+//
+//	for chunk := range chunks {
+//	    out, voiceProbs, err := sf.Process(chunk)
+//	    ...
+//	}
+//	tail, _, err := sf.Flush()
+type StreamFilter struct {
+	guard      useGuard
+	denoise    *DenoiseStage
+	pipeline   *Pipeline
+	sampleRate int
+}
+
+// NewStreamFilter 创建处理sampleRate采样率单声道音频的流式过滤器
+//
+// 选项与NewNoiseFilter相同；低于阈值的帧按WithOutputPolicy处理，交叉淡化状态跨调用保留，
+// 阈值使用WithThreshold配置的默认阈值。
+// WithResampler指定的转换器实现了StreamResamplerFactory时用于流式重采样，否则使用PolyphaseResampler
+func NewStreamFilter(sampleRate int, opts ...Option) (*StreamFilter, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("采样率必须大于0，当前为%d", sampleRate)
+	}
+
+	pipeline, denoise, err := newNoiseFilterPipeline(opts)
+	if err != nil {
+		return nil, err
+	}
+	// 提前按采样率创建流式核心，参数错误在构造时报告
+	if err := denoise.init(&Block{Samples: [][]float32{nil}, SampleRate: sampleRate}); err != nil {
+		pipeline.Close()
+		return nil, err
+	}
+	return &StreamFilter{
+		denoise:    denoise,
+		pipeline:   pipeline,
+		sampleRate: sampleRate,
+	}, nil
+}
+
+// SampleRate 输入和输出的采样率
+func (sf *StreamFilter) SampleRate() int {
+	return sf.sampleRate
+}
+
+// Denoiser 获取流式过滤器使用的降噪后端
+func (sf *StreamFilter) Denoiser() FrameDenoiser {
+	return sf.denoise.nf.denoiser
+}
+
+// Process 处理一块任意长度的样本（范围-1.0到1.0）
+//
+// 返回:
+//   - []float32: 目前可以输出的降噪样本（输入采样率），可能为空
+//   - []float32: 本次处理的每一帧的语音概率
+//   - error: 处理失败时的错误信息
+func (sf *StreamFilter) Process(samples []float32) ([]float32, []float32, error) {
+	if err := sf.guard.acquire(); err != nil {
+		return nil, nil, err
+	}
+	defer sf.guard.release()
+
+	out, err := sf.pipeline.Process(&Block{Samples: [][]float32{samples}, SampleRate: sf.sampleRate})
+	if err != nil {
+		return nil, nil, err
+	}
+	return blockSamples(out)
+}
+
+// Flush 处理缓存的剩余样本并结束当前流
+//
+// 不足一帧的样本补零后处理，只输出其中真实样本对应的部分。
+// Flush之后降噪状态被重置，StreamFilter可以继续处理新的一段流
+func (sf *StreamFilter) Flush() ([]float32, []float32, error) {
+	if err := sf.guard.acquire(); err != nil {
+		return nil, nil, err
+	}
+	defer sf.guard.release()
+
+	out, err := sf.pipeline.Flush()
+	if err != nil {
+		return nil, nil, err
+	}
+	return blockSamples(out)
+}
+
+// Reset 丢弃缓存的样本和重采样历史，并重置降噪状态
+func (sf *StreamFilter) Reset() error {
+	if err := sf.guard.acquire(); err != nil {
+		return err
+	}
+	defer sf.guard.release()
+
+	return sf.pipeline.Reset()
+}
+
+// Destroy 销毁流式过滤器，释放降噪后端
+func (sf *StreamFilter) Destroy() {
+	sf.pipeline.Close()
+}
+
+// blockSamples 流水线输出的单声道样本和语音概率，没有输出时为空
+func blockSamples(block *Block) ([]float32, []float32, error) {
+	if block == nil || block.Channels() == 0 {
+		return nil, nil, nil
+	}
+	return block.Samples[0], block.VoiceProbs, nil
+}
+
+// channelStream 流式处理中单个声道的状态
+type channelStream struct {
+	denoiser FrameDenoiser
+	up       StreamResampler // 输入采样率 -> 降噪后端采样率
+	down     StreamResampler // 降噪后端采样率 -> 输入采样率
+	pre      preChain        // 降噪前的预处理，未配置时为nil
+	deEmph   *deEmphasis     // 抵消预加重的去加重，没有预加重时为nil
+	pending  []float32       // 尚未凑满一帧的样本（降噪后端采样率，已预处理）
+	shaper   *frameShaper    // 输出策略和交叉淡化状态
+	mixer    *wetDryMixer    // 干湿混合状态，不混合时为nil
+}
+
+// streamOutput streamCore一次处理的结果
+type streamOutput struct {
+	samples      [][]float32 // 每个声道目前可以输出的样本（输入采样率）
+	voiceProbs   []float32   // 每帧的语音概率（各声道的最大值）
+	channelProbs [][]float32 // 每个声道每帧的语音概率
+	keptFrames   int         // 保留的帧数
+}
+
+// keepRun 连续n个输入样本（同一帧内）的判定
+type keepRun struct {
+	n    int
+	keep []bool // 每个声道是否保留
+	any  bool   // 是否有声道保留
+}
+
+// coreOptions 流式核心的处理参数
+type coreOptions struct {
+	threshold float32
+	latency   int  // 需要补偿的延迟（降噪后端采样率）
+	limit     bool // 在核心内做真峰值限幅（过滤器启用限幅时）
+	whole     bool // 整段处理：未实现StreamResamplerFactory的转换器缓存整段音频，结束时一次转换
+}
+
+// streamCore 降噪阶段（DenoiseStage）的流式降噪核心，StreamFilter和FilterAudio等离线处理都经由它降噪
+//
+// 每个声道有独立的降噪后端、重采样器和输出状态，各声道按帧同步处理；
+// 共享判定时（ChannelLinked或OutputDrop策略）按各声道语音概率的最大值决定输出，保证声道之间对齐
+type streamCore struct {
+	nf         *NoiseFilter
+	channels   []*channelStream
+	linked     bool
+	threshold  float32
+	frameIndex int
+
+	agc     *agc             // 各声道共享的自动增益控制，未启用时为nil
+	limiter *truePeakLimiter // 输出的真峰值限幅器，未启用时为nil
+
+	runs []keepRun // 已判定、尚未输出的输入样本的判定，按时间顺序
+
+	sampleRate    int // 输入和输出的采样率
+	dropped       int // OutputDrop策略下丢弃的样本数（降噪后端采样率）
+	latency       int // 需要补偿的延迟（降噪后端采样率）
+	skip          int // 开头尚待丢弃的样本数
+	inputSamples  int // 每个声道已输入的样本数
+	outputSamples int // 每个声道已输出的样本数
+}
+
+// newStreamCore 创建处理sampleRate采样率音频的流式核心，每个声道使用denoisers中对应的降噪后端
+func newStreamCore(nf *NoiseFilter, sampleRate int, denoisers []FrameDenoiser, opts coreOptions) (*streamCore, error) {
+	targetRate := nf.denoiser.SampleRateHz()
+	channels := make([]*channelStream, len(denoisers))
+	for ch, denoiser := range denoisers {
+		pre, err := nf.newPreChain()
+		if err != nil {
+			return nil, err
+		}
+		channels[ch] = &channelStream{
+			denoiser: denoiser,
+			up:       newCoreResampler(nf.resampler, sampleRate, targetRate, opts.whole),
+			down:     newCoreResampler(nf.resampler, targetRate, sampleRate, opts.whole),
+			pre:      pre,
+			deEmph:   pre.newDeEmphasis(),
+			shaper:   nf.newFrameShaper(),
+			mixer:    nf.newWetDryMixer(),
+		}
+	}
+	core := &streamCore{
+		nf:         nf,
+		channels:   channels,
+		linked:     nf.channelMode == ChannelLinked || nf.outputPolicy == OutputDrop,
+		threshold:  opts.threshold,
+		agc:        nf.newAGC(),
+		sampleRate: sampleRate,
+		latency:    opts.latency,
+		skip:       opts.latency,
+	}
+	if opts.limit {
+		core.limiter = nf.newLimiter(sampleRate, len(denoisers))
+	}
+	return core, nil
+}
+
+// newCoreResampler 创建流式核心使用的转换器
+//
+// whole为true且resampler未实现StreamResamplerFactory时缓存整段音频后用resampler一次转换，
+// 与直接对整段音频调用Resample的结果一致；否则与newStreamResampler相同
+func newCoreResampler(resampler Resampler, fromRate, toRate int, whole bool) StreamResampler {
+	if _, ok := resampler.(StreamResamplerFactory); ok || !whole || fromRate == toRate {
+		return newStreamResampler(resampler, fromRate, toRate)
+	}
+	return &wholeResampler{resampler: resampler, fromRate: fromRate, toRate: toRate}
+}
+
+// wholeResampler 缓存所有输入、Flush时一次转换的StreamResampler
+type wholeResampler struct {
+	resampler        Resampler
+	fromRate, toRate int
+	buf              []float32
+}
+
+// Process 缓存样本，不输出
+func (w *wholeResampler) Process(samples []float32) []float32 {
+	w.buf = append(w.buf, samples...)
+	return nil
+}
+
+// Flush 转换缓存的整段样本
+func (w *wholeResampler) Flush() []float32 {
+	out := w.resampler.Resample(w.buf, w.fromRate, w.toRate)
+	w.buf = nil
+	return out
+}
+
+// Reset 丢弃缓存的样本
+func (w *wholeResampler) Reset() {
+	w.buf = nil
+}
+
+// process 处理各声道等长的一块样本，只处理凑满的帧
+func (sc *streamCore) process(inputs [][]float32) (*streamOutput, error) {
+	whole := -1
+	for ch, cs := range sc.channels {
+		chunk := cs.up.Process(inputs[ch])
+		cs.pre.process(chunk)
+		cs.pending = append(cs.pending, chunk...)
+		if n := len(cs.pending); whole < 0 || n < whole {
+			whole = n
+		}
+	}
+	frameSize := sc.nf.denoiser.FrameSize()
+	whole = whole / frameSize * frameSize
+
+	out, err := sc.denoise(whole, whole)
+	if err != nil {
+		return nil, err
+	}
+	sc.inputSamples += len(inputs[0])
+	for ch, cs := range sc.channels {
+		// 保留不足一帧的样本
+		cs.pending = cs.pending[:copy(cs.pending, cs.pending[whole:])]
+		out.samples[ch] = cs.down.Process(out.samples[ch])
+	}
+	out.samples = sc.limiter.process(out.samples)
+	sc.outputSamples += len(out.samples[0])
+	return out, nil
+}
+
+// flush 处理缓存的剩余样本并输出重采样器中的剩余部分，不足一帧的样本补零后处理
+//
+// 整个流的输出与输入逐样本等长，OutputDrop策略下只减去被丢弃的部分
+func (sc *streamCore) flush() (*streamOutput, error) {
+	valid := -1
+	tails := make([]int, len(sc.channels))
+	for ch, cs := range sc.channels {
+		tails[ch] = len(cs.pending)
+		// 补偿延迟时多处理latency个零，取回最后的输出
+		cs.pending = append(cs.pending, cs.up.Flush()...)
+		cs.pending = append(cs.pending, make([]float32, sc.latency)...)
+		if n := len(cs.pending); valid < 0 || n < valid {
+			valid = n
+		}
+	}
+	frameSize := sc.nf.denoiser.FrameSize()
+	padded := (valid + frameSize - 1) / frameSize * frameSize
+	for ch, cs := range sc.channels {
+		if padding := padded - len(cs.pending); padding > 0 {
+			cs.pending = append(cs.pending, make([]float32, padding)...)
+		}
+		// 与离线处理一致，补入的零也经过预处理
+		cs.pre.process(cs.pending[tails[ch]:])
+	}
+
+	out, err := sc.denoise(padded, valid)
+	if err != nil {
+		return nil, err
+	}
+	target := keptLength(sc.inputSamples, sc.dropped, sc.sampleRate, sc.nf.denoiser.SampleRateHz())
+	remaining := target - sc.outputSamples
+	if remaining < 0 {
+		remaining = 0
+	}
+	for ch, cs := range sc.channels {
+		cs.pending = cs.pending[:0]
+		out.samples[ch] = append(cs.down.Process(out.samples[ch]), cs.down.Flush()...)
+	}
+	out.samples = sc.limiter.process(out.samples)
+	appendChannels(out.samples, sc.limiter.flush())
+	for ch := range out.samples {
+		out.samples[ch] = fitLength(out.samples[ch], remaining)
+	}
+	sc.outputSamples += len(out.samples[0])
+	return out, nil
+}
+
+// reset 丢弃缓存的样本和重采样历史，并重置各声道的降噪状态
+func (sc *streamCore) reset() error {
+	sc.frameIndex = 0
+	sc.runs = nil
+	sc.skip = sc.latency
+	sc.inputSamples = 0
+	sc.outputSamples = 0
+	sc.dropped = 0
+	sc.agc.reset()
+	sc.limiter.reset()
+	for _, cs := range sc.channels {
+		cs.up.Reset()
+		cs.down.Reset()
+		cs.pending = cs.pending[:0]
+		cs.pre.reset()
+		cs.deEmph.reset()
+		cs.shaper.reset()
+		cs.mixer.reset()
+		if err := cs.denoiser.Reset(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// denoise 逐帧处理各声道pending的前n个样本（帧大小的整数倍），只输出前valid个样本对应的结果
+//
+// 降噪后端实现了BatchDenoiser时按批处理
+func (sc *streamCore) denoise(n, valid int) (*streamOutput, error) {
+	frameSize := sc.nf.denoiser.FrameSize()
+	numFrames := n / frameSize
+	out := &streamOutput{
+		samples:      make([][]float32, len(sc.channels)),
+		channelProbs: make([][]float32, len(sc.channels)),
+	}
+
+	denoised := make([][][]float32, len(sc.channels))
+	elapsed := make([][]time.Duration, len(sc.channels))
+	errs := make([]error, len(sc.channels))
+	process := func(ch int) {
+		cs := sc.channels[ch]
+		frames := make([][]float32, numFrames)
+		for i := range frames {
+			frames[i] = cs.pending[i*frameSize : (i+1)*frameSize]
+		}
+		denoised[ch] = make([][]float32, numFrames)
+		elapsed[ch] = make([]time.Duration, numFrames)
+		out.channelProbs[ch] = make([]float32, numFrames)
+		errs[ch] = denoiseFramesWith(cs.denoiser, frames, func(i int, voiceProb float32, frame []float32, d time.Duration) {
+			denoised[ch][i] = cs.mixer.apply(frame, frames[i])
+			cs.deEmph.process(denoised[ch][i])
+			out.channelProbs[ch][i] = voiceProb
+			elapsed[ch][i] = d
+		})
+	}
+	// WithParallelChannels时各声道的降噪后端并行处理，之后的输出判定按帧同步进行
+	if sc.nf.parallelChannels && len(sc.channels) > 1 && numFrames > 0 {
+		var wg sync.WaitGroup
+		for ch := range sc.channels {
+			wg.Add(1)
+			go func(ch int) {
+				defer wg.Done()
+				process(ch)
+			}(ch)
+		}
+		wg.Wait()
+	} else {
+		for ch := range sc.channels {
+			process(ch)
+		}
+	}
+	for ch, err := range errs {
+		if err == nil {
+			continue
+		}
+		if len(sc.channels) > 1 {
+			return nil, fmt.Errorf("声道%d处理失败: %v", ch+1, err)
+		}
+		return nil, fmt.Errorf("帧处理失败: %v", err)
+	}
+
+	out.voiceProbs = make([]float32, numFrames)
+	frameSet := make([][]float32, len(sc.channels))
+	for i := 0; i < numFrames; i++ {
+		var maxProb float32
+		var frameElapsed time.Duration
+		for ch := range sc.channels {
+			if p := out.channelProbs[ch][i]; p > maxProb {
+				maxProb = p
+			}
+			frameElapsed += elapsed[ch][i]
+		}
+		out.voiceProbs[i] = maxProb
+
+		// 所有声道共享同一个增益
+		for ch := range sc.channels {
+			frameSet[ch] = denoised[ch][i]
+		}
+		sc.agc.update(maxProb, frameSet...)
+
+		m := frameSize
+		if valid-i*frameSize < m {
+			m = valid - i*frameSize
+		}
+		run := keepRun{n: m, keep: make([]bool, len(sc.channels))}
+		for ch := range sc.channels {
+			run.keep[ch] = out.channelProbs[ch][i] >= sc.threshold
+			if sc.linked {
+				run.keep[ch] = maxProb >= sc.threshold
+			}
+			run.any = run.any || run.keep[ch]
+			sc.agc.apply(denoised[ch][i])
+		}
+		if run.any {
+			out.keptFrames++
+		}
+		sc.runs = append(sc.runs, run)
+		sc.shape(out, denoised, i, m)
+		sc.nf.observeFrame(FrameMetrics{Index: sc.frameIndex, VoiceProb: maxProb, Kept: run.any, Duration: frameElapsed})
+		sc.frameIndex++
+	}
+	return out, nil
+}
+
+// shape 按输出策略输出第i帧降噪结果的前m个样本
+//
+// 降噪后端的输出相对输入延迟latency个样本：补偿延迟时时间轴开头的latency个样本不输出，
+// 之后每个样本使用其对应的输入样本所在帧的判定，因此判定也随之延迟latency个样本，
+// OutputDrop策略下丢弃的正是低于阈值的输入帧本身
+func (sc *streamCore) shape(out *streamOutput, denoised [][][]float32, i, m int) {
+	pos := 0
+	if sc.skip > 0 {
+		pos = sc.skip
+		if pos > m {
+			pos = m
+		}
+		sc.skip -= pos
+	}
+	for pos < m {
+		run := &sc.runs[0]
+		n := run.n
+		if n > m-pos {
+			n = m - pos
+		}
+		for ch, cs := range sc.channels {
+			out.samples[ch] = cs.shaper.apply(out.samples[ch], denoised[ch][i][pos:pos+n], run.keep[ch])
+		}
+		if !run.any && sc.nf.outputPolicy == OutputDrop {
+			sc.dropped += n
+		}
+		pos += n
+		if run.n -= n; run.n == 0 {
+			sc.runs = sc.runs[1:]
+		}
+	}
+}
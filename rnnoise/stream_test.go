@@ -0,0 +1,175 @@
+package rnnoise
+
+import (
+	"math"
+	"testing"
+)
+
+func sineSamples(n, sampleRate int, freq float64) []float32 {
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+	}
+	return samples
+}
+
+// processInChunks 按给定的分块大小（循环使用）依次调用process
+func processInChunks(samples []float32, sizes []int, process func([]float32) []float32) []float32 {
+	var out []float32
+	for i, pos := 0, 0; pos < len(samples); i++ {
+		end := pos + sizes[i%len(sizes)]
+		if end > len(samples) {
+			end = len(samples)
+		}
+		out = append(out, process(samples[pos:end])...)
+		pos = end
+	}
+	return out
+}
+
+func TestLinearStreamResamplerMatchesBatch(t *testing.T) {
+	cases := []struct{ from, to int }{{8000, 48000}, {48000, 8000}, {44100, 48000}, {48000, 22050}}
+	for _, tc := range cases {
+		input := sineSamples(3001, tc.from, 440)
+		want := LinearResampler{}.Resample(input, tc.from, tc.to)
+
+		stream := LinearResampler{}.NewStream(tc.from, tc.to)
+		got := processInChunks(input, []int{1, 37, 160, 999}, stream.Process)
+		got = append(got, stream.Flush()...)
+
+		if len(got) != len(want) {
+			t.Fatalf("%d->%d: stream produced %d samples, batch %d", tc.from, tc.to, len(got), len(want))
+		}
+		for i := range want {
+			if math.Abs(float64(got[i]-want[i])) > 1e-5 {
+				t.Fatalf("%d->%d: sample %d = %f, want %f", tc.from, tc.to, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestStreamFilterArbitraryChunks(t *testing.T) {
+	input := sineSamples(8000+123, 8000, 300)
+
+	run := func(sizes []int) ([]float32, int) {
+		sf, err := NewStreamFilter(8000, WithDenoiser(NewFakeDenoiser(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sf.Destroy()
+
+		frames := 0
+		out := processInChunks(input, sizes, func(chunk []float32) []float32 {
+			out, probs, err := sf.Process(chunk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			frames += len(probs)
+			return out
+		})
+		tail, probs, err := sf.Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(out, tail...), frames + len(probs)
+	}
+
+	whole, wholeFrames := run([]int{len(input)})
+	chunked, chunkedFrames := run([]int{160, 7, 333, 1})
+
+	if len(whole) != len(input) {
+		t.Errorf("output length = %d, want %d", len(whole), len(input))
+	}
+	if wantFrames := (len(input)*6 + 479) / 480; wholeFrames != wantFrames || chunkedFrames != wantFrames {
+		t.Errorf("frames = %d / %d, want %d", wholeFrames, chunkedFrames, wantFrames)
+	}
+	if len(chunked) != len(whole) {
+		t.Fatalf("chunked output length = %d, want %d", len(chunked), len(whole))
+	}
+	for i := range whole {
+		if chunked[i] != whole[i] {
+			t.Fatalf("sample %d differs between chunked (%f) and whole (%f) processing", i, chunked[i], whole[i])
+		}
+	}
+
+	// 输入是8kHz，上采样再下采样后应当接近原始信号（首尾受重采样滤波器边界影响）
+	for i := 64; i < len(input)-64; i++ {
+		if math.Abs(float64(whole[i]-input[i])) > 1e-3 {
+			t.Fatalf("sample %d = %f, want %f", i, whole[i], input[i])
+		}
+	}
+}
+
+func TestStreamFilterEmitsFramesAsSoonAsAvailable(t *testing.T) {
+	fake := NewFakeDenoiser(0.9, 0.1)
+	fake.Gain = 0.5
+	sf, err := NewStreamFilter(48000, WithDenoiser(fake), WithThreshold(0.5), WithOutputPolicy(OutputSilence), WithCrossfade(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Destroy()
+
+	chunk := make([]float32, 300)
+	for i := range chunk {
+		chunk[i] = 0.2
+	}
+
+	out, probs, err := sf.Process(chunk)
+	if err != nil || len(out) != 0 || len(probs) != 0 {
+		t.Fatalf("Process(300) = %d samples %d frames %v, want nothing yet", len(out), len(probs), err)
+	}
+	out, probs, err = sf.Process(chunk)
+	if err != nil || len(out) != 480 || len(probs) != 1 {
+		t.Fatalf("Process(300) = %d samples %d frames %v, want one frame", len(out), len(probs), err)
+	}
+	if out[0] != 0.1 {
+		t.Errorf("kept frame sample = %f, want 0.1", out[0])
+	}
+
+	// 剩余120个样本补零处理，第二帧概率0.1低于阈值，输出静音
+	tail, probs, err := sf.Flush()
+	if err != nil || len(tail) != 120 || len(probs) != 1 {
+		t.Fatalf("Flush() = %d samples %d frames %v, want 120 samples", len(tail), len(probs), err)
+	}
+	if tail[0] != 0 {
+		t.Errorf("silenced frame sample = %f, want 0", tail[0])
+	}
+	if fake.Resets != 1 {
+		t.Errorf("Flush() should reset the denoiser, Resets = %d", fake.Resets)
+	}
+}
+
+func TestStreamFilterLatencyCompensation(t *testing.T) {
+	fake := NewFakeDenoiser(1)
+	fake.Delay = 480
+	sf, err := NewStreamFilter(48000, WithDenoiser(fake), WithOutputPolicy(OutputSilence), WithLatencyCompensation(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Destroy()
+
+	input := sineSamples(48000/4+7, 48000, 300)
+	for pass := 0; pass < 2; pass++ { // Flush之后重新开始的流同样补偿
+		out := processInChunks(input, []int{100, 777, 5}, func(chunk []float32) []float32 {
+			out, _, err := sf.Process(chunk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return out
+		})
+		tail, _, err := sf.Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, tail...)
+
+		if len(out) != len(input) {
+			t.Fatalf("pass %d: output %d samples, want %d", pass, len(out), len(input))
+		}
+		for i := range input {
+			if out[i] != input[i] {
+				t.Fatalf("pass %d: sample %d = %f, want %f", pass, i, out[i], input[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package rnnoise
+
+import (
+	"fmt"
+	"time"
+)
+
+// VADConfig 语音活动检测参数
+//
+// 语音概率先做指数平滑，再按迟滞阈值判定：平滑后的概率连续Attack时长达到OnThreshold才进入语音，
+// 进入语音后低于OffThreshold再经过Hangover时长才回到静音。
+// 最后填补短于MinSilence的静音间隙，并丢弃短于MinSpeech的语音段
+type VADConfig struct {
+	OnThreshold   float32       // 进入语音的阈值
+	OffThreshold  float32       // 离开语音的阈值，不能大于OnThreshold
+	Attack        time.Duration // 进入语音前需要持续达到OnThreshold的时长
+	Hangover      time.Duration // 低于OffThreshold后继续保持语音的时长
+	MinSpeech     time.Duration // 最短语音段，更短的语音段视为静音
+	MinSilence    time.Duration // 最短静音间隙，更短的间隙并入两侧的语音
+	Smoothing     float32       // 指数平滑系数（0.0-1.0），0表示不平滑，越大越平滑
+	FrameDuration time.Duration // 每个语音概率对应的时长，0表示10ms
+}
+
+// DefaultVADConfig 适合RNNoise语音概率的默认参数
+func DefaultVADConfig() VADConfig {
+	return VADConfig{
+		OnThreshold:  0.5,
+		OffThreshold: 0.35,
+		Attack:       20 * time.Millisecond,
+		Hangover:     150 * time.Millisecond,
+		MinSpeech:    100 * time.Millisecond,
+		MinSilence:   200 * time.Millisecond,
+		Smoothing:    0.3,
+	}
+}
+
+// VAD 基于RNNoise语音概率的语音活动检测器
+//
+// 离线使用Detect一次判定所有帧；流式使用时每帧调用Process，结束时调用Flush。
+// 起音和最短时长需要参考之后的帧，因此流式判定有最多Latency的延迟，
+// 但结果与Detect逐帧一致：语音起点会回溯到起音开始的帧，不会被截掉。
+// 同一实例同时只能被一个goroutine使用
+//
+// 示例:
+//
+//	vad, err := NewVAD(DefaultVADConfig())
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	// 离线
+//	speech := vad.Detect(result.VoiceProbabilities)
+//
+//	// 流式
+//	for chunk := range chunks {
+//	    _, voiceProbs, err := sf.Process(chunk)
+//	    ...
+//	    for _, p := range voiceProbs {
+//	        for _, isSpeech := range vad.Process(p) {
+//	            ...
+//	        }
+//	    }
+//	}
+type VAD struct {
+	cfg      VADConfig
+	smoothed float32
+	started  bool
+
+	hysteresis hysteresis
+	minSilence runFilter
+	minSpeech  runFilter
+
+	decided []bool // 本次调用确定的帧
+}
+
+// NewVAD 按cfg创建语音活动检测器
+func NewVAD(cfg VADConfig) (*VAD, error) {
+	if cfg.OnThreshold < 0 || cfg.OnThreshold > 1 || cfg.OffThreshold < 0 || cfg.OffThreshold > 1 {
+		return nil, fmt.Errorf("VAD阈值必须在0到1之间，当前为%v/%v", cfg.OnThreshold, cfg.OffThreshold)
+	}
+	if cfg.OffThreshold > cfg.OnThreshold {
+		return nil, fmt.Errorf("VAD离开阈值%v不能大于进入阈值%v", cfg.OffThreshold, cfg.OnThreshold)
+	}
+	if cfg.Smoothing < 0 || cfg.Smoothing >= 1 {
+		return nil, fmt.Errorf("VAD平滑系数必须在[0, 1)之间，当前为%v", cfg.Smoothing)
+	}
+	if cfg.Attack < 0 || cfg.Hangover < 0 || cfg.MinSpeech < 0 || cfg.MinSilence < 0 || cfg.FrameDuration < 0 {
+		return nil, fmt.Errorf("VAD时长参数不能为负数")
+	}
+	if cfg.FrameDuration == 0 {
+		cfg.FrameDuration = 10 * time.Millisecond
+	}
+
+	frames := func(d time.Duration) int {
+		return int((d + cfg.FrameDuration - 1) / cfg.FrameDuration)
+	}
+	v := &VAD{
+		cfg: cfg,
+		hysteresis: hysteresis{
+			on:       cfg.OnThreshold,
+			off:      cfg.OffThreshold,
+			attack:   frames(cfg.Attack),
+			hangover: frames(cfg.Hangover),
+		},
+		minSilence: runFilter{value: false, minLen: frames(cfg.MinSilence), interior: true},
+		minSpeech:  runFilter{value: true, minLen: frames(cfg.MinSpeech)},
+	}
+	if v.hysteresis.attack < 1 {
+		v.hysteresis.attack = 1
+	}
+	return v, nil
+}
+
+// Config 检测器使用的参数
+func (v *VAD) Config() VADConfig {
+	return v.cfg
+}
+
+// Latency 流式判定相对输入的最大延迟
+func (v *VAD) Latency() time.Duration {
+	frames := v.hysteresis.attack - 1
+	if v.minSilence.minLen > 1 {
+		frames += v.minSilence.minLen - 1
+	}
+	if v.minSpeech.minLen > 1 {
+		frames += v.minSpeech.minLen - 1
+	}
+	return time.Duration(frames) * v.cfg.FrameDuration
+}
+
+// Process 输入下一帧的语音概率，返回本次确定的帧的判定结果（true为语音）
+//
+// 返回的帧按顺序紧接上一次返回的帧，可能为空，也可能一次确定多帧。
+// 返回的切片在下一次调用前有效
+func (v *VAD) Process(voiceProb float32) []bool {
+	if !v.started {
+		v.smoothed, v.started = voiceProb, true
+	} else {
+		v.smoothed = v.cfg.Smoothing*v.smoothed + (1-v.cfg.Smoothing)*voiceProb
+	}
+
+	v.decided = v.decided[:0]
+	v.hysteresis.push(v.smoothed, v.pushMinSilence)
+	return v.decided
+}
+
+// Flush 结束当前流，返回尚未确定的帧的判定结果，然后重置检测器
+func (v *VAD) Flush() []bool {
+	v.decided = v.decided[:0]
+	v.hysteresis.flush(v.pushMinSilence)
+	v.minSilence.flush(v.pushMinSpeech)
+	v.minSpeech.flush(v.emit)
+	decided := v.decided
+	v.Reset()
+	return decided
+}
+
+// Reset 丢弃尚未确定的帧，重新开始
+func (v *VAD) Reset() {
+	v.started = false
+	v.hysteresis.reset()
+	v.minSilence.reset()
+	v.minSpeech.reset()
+}
+
+// Detect 离线判定voiceProbs（例如FilterResult.VoiceProbabilities）中的每一帧是否为语音
+//
+// 检测器先被重置，返回的切片与voiceProbs等长
+func (v *VAD) Detect(voiceProbs []float32) []bool {
+	v.Reset()
+	speech := make([]bool, 0, len(voiceProbs))
+	for _, p := range voiceProbs {
+		speech = append(speech, v.Process(p)...)
+	}
+	return append(speech, v.Flush()...)
+}
+
+func (v *VAD) pushMinSilence(speech bool) { v.minSilence.push(speech, v.pushMinSpeech) }
+func (v *VAD) pushMinSpeech(speech bool)  { v.minSpeech.push(speech, v.emit) }
+func (v *VAD) emit(speech bool)           { v.decided = append(v.decided, speech) }
+
+// hysteresis 迟滞阈值、起音和释放
+type hysteresis struct {
+	on, off  float32
+	attack   int // 进入语音需要的连续帧数，至少为1
+	hangover int // 低于off后保持语音的帧数
+
+	speech bool
+	above  int // 静音状态下连续达到on的待定帧数
+	below  int // 语音状态下连续低于off的帧数
+}
+
+func (h *hysteresis) push(p float32, emit func(bool)) {
+	if !h.speech {
+		if p >= h.on {
+			h.above++
+			if h.above >= h.attack {
+				// 起音完成，待定的帧全部计为语音
+				h.speech = true
+				emitRun(emit, true, h.above)
+				h.above, h.below = 0, 0
+			}
+			return
+		}
+		emitRun(emit, false, h.above+1)
+		h.above = 0
+		return
+	}
+
+	if p < h.off {
+		h.below++
+		if h.below > h.hangover {
+			h.speech = false
+			h.below = 0
+			emit(false)
+			return
+		}
+	} else {
+		h.below = 0
+	}
+	emit(true)
+}
+
+// flush 流结束时未完成起音的帧计为静音
+func (h *hysteresis) flush(emit func(bool)) {
+	emitRun(emit, false, h.above)
+	h.reset()
+}
+
+func (h *hysteresis) reset() {
+	h.speech, h.above, h.below = false, 0, 0
+}
+
+// runFilter 将短于minLen帧的value段翻转为相反的取值
+//
+// interior为true时只处理两侧都是相反取值的段（静音间隙），流开头和结尾的段保持不变
+type runFilter struct {
+	value    bool
+	minLen   int
+	interior bool
+
+	pending   int  // 当前value段中待定的帧数
+	confirmed bool // 当前value段已达到minLen
+	seenOther bool // 之前是否出现过相反的取值
+}
+
+func (f *runFilter) push(x bool, emit func(bool)) {
+	if x != f.value {
+		// value段在达到minLen之前结束
+		emitRun(emit, !f.value, f.pending)
+		f.pending, f.confirmed, f.seenOther = 0, false, true
+		emit(x)
+		return
+	}
+
+	if f.confirmed || (f.interior && !f.seenOther) {
+		emit(x)
+		return
+	}
+	f.pending++
+	if f.pending >= f.minLen {
+		emitRun(emit, f.value, f.pending)
+		f.pending, f.confirmed = 0, true
+	}
+}
+
+func (f *runFilter) flush(emit func(bool)) {
+	if f.interior {
+		// 流结尾的段没有被相反取值包围，保持不变
+		emitRun(emit, f.value, f.pending)
+	} else {
+		emitRun(emit, !f.value, f.pending)
+	}
+	f.reset()
+}
+
+func (f *runFilter) reset() {
+	f.pending, f.confirmed, f.seenOther = 0, false, false
+}
+
+func emitRun(emit func(bool), x bool, n int) {
+	for i := 0; i < n; i++ {
+		emit(x)
+	}
+}
@@ -0,0 +1,111 @@
+package rnnoise
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// probsFromPattern 将"_"和"#"组成的模式转换为语音概率（"#"为0.9，"_"为0.1，"~"为0.4）
+func probsFromPattern(pattern string) []float32 {
+	probs := make([]float32, 0, len(pattern))
+	for _, c := range pattern {
+		switch c {
+		case '#':
+			probs = append(probs, 0.9)
+		case '~':
+			probs = append(probs, 0.4)
+		default:
+			probs = append(probs, 0.1)
+		}
+	}
+	return probs
+}
+
+func speechPattern(speech []bool) string {
+	var b strings.Builder
+	for _, s := range speech {
+		if s {
+			b.WriteByte('#')
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// rawVADConfig 不平滑、不要求最短时长的配置，单独测试某个参数时在此基础上修改
+func rawVADConfig() VADConfig {
+	return VADConfig{OnThreshold: 0.5, OffThreshold: 0.35}
+}
+
+func TestVADDetect(t *testing.T) {
+	cases := []struct {
+		name  string
+		cfg   func(*VADConfig)
+		input string
+		want  string
+	}{
+		{"hysteresis", func(c *VADConfig) {}, "__#~#~#~__", "__######__"},
+		{"attack", func(c *VADConfig) { c.Attack = 30 * time.Millisecond }, "_#_##_###__", "______###__"},
+		{"hangover", func(c *VADConfig) { c.Hangover = 20 * time.Millisecond }, "_##____##_", "_####__###"},
+		{"min speech", func(c *VADConfig) { c.MinSpeech = 30 * time.Millisecond }, "_##__###__#", "_____###___"},
+		{"min silence", func(c *VADConfig) { c.MinSilence = 30 * time.Millisecond }, "__#__#___#__", "__####___#__"},
+		{"smoothing", func(c *VADConfig) { c.Smoothing = 0.8 }, "___#___", "_______"},
+	}
+	for _, tc := range cases {
+		cfg := rawVADConfig()
+		tc.cfg(&cfg)
+		vad, err := NewVAD(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := speechPattern(vad.Detect(probsFromPattern(tc.input))); got != tc.want {
+			t.Errorf("%s: Detect(%s) = %s, want %s", tc.name, tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestVADStreamingMatchesDetect(t *testing.T) {
+	cfg := DefaultVADConfig()
+	input := probsFromPattern("___#_##~#~~____##########~_~__###___#____####_#_##______~#~#~#~___" + strings.Repeat("#_", 20))
+
+	vad, err := NewVAD(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := vad.Detect(input)
+	if len(want) != len(input) {
+		t.Fatalf("Detect() returned %d frames, want %d", len(want), len(input))
+	}
+
+	latency := int(vad.Latency() / (10 * time.Millisecond))
+	var got []bool
+	for i, p := range input {
+		got = append(got, vad.Process(p)...)
+		if pending := i + 1 - len(got); pending > latency {
+			t.Fatalf("after frame %d, %d frames undecided, latency is %d frames", i, pending, latency)
+		}
+	}
+	got = append(got, vad.Flush()...)
+
+	if speechPattern(got) != speechPattern(want) {
+		t.Errorf("streaming = %s\nDetect    = %s", speechPattern(got), speechPattern(want))
+	}
+}
+
+func TestNewVADRejectsInvalidConfig(t *testing.T) {
+	invalid := []func(*VADConfig){
+		func(c *VADConfig) { c.OffThreshold = 0.6 },
+		func(c *VADConfig) { c.OnThreshold = 1.5 },
+		func(c *VADConfig) { c.Smoothing = 1 },
+		func(c *VADConfig) { c.Hangover = -time.Millisecond },
+	}
+	for i, modify := range invalid {
+		cfg := DefaultVADConfig()
+		modify(&cfg)
+		if _, err := NewVAD(cfg); err == nil {
+			t.Errorf("case %d: expected error for %+v", i, cfg)
+		}
+	}
+}
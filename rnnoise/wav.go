@@ -0,0 +1,290 @@
+package rnnoise
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WAV格式标签
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatFloat      = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// wavUnknownSize 数据块大小未知（例如实时写入尚未结束的文件），读取到文件末尾
+const wavUnknownSize = -1
+
+// WAVFormat WAV文件的样本格式
+type WAVFormat struct {
+	SampleRate int  // 采样率
+	Channels   int  // 声道数
+	BitDepth   int  // 每个样本占用的位数：整数PCM为8/16/24/32，浮点为32/64
+	Float      bool // IEEE浮点（格式标签3），否则为整数PCM
+}
+
+// Validate 检查是否为支持的样本格式
+func (f WAVFormat) Validate() error {
+	if f.Channels <= 0 || f.SampleRate <= 0 {
+		return fmt.Errorf("无效的WAV格式: %d声道, %dHz", f.Channels, f.SampleRate)
+	}
+	switch {
+	case f.Float && (f.BitDepth == 32 || f.BitDepth == 64):
+	case !f.Float && (f.BitDepth == 8 || f.BitDepth == 16 || f.BitDepth == 24 || f.BitDepth == 32):
+	default:
+		kind := "整数"
+		if f.Float {
+			kind = "浮点"
+		}
+		return fmt.Errorf("不支持的WAV位深度: %d位%s", f.BitDepth, kind)
+	}
+	return nil
+}
+
+func (f WAVFormat) bytesPerSample() int {
+	return f.BitDepth / 8
+}
+
+func (f WAVFormat) blockAlign() int {
+	return f.bytesPerSample() * f.Channels
+}
+
+// readWAVHeader 读取RIFF头和fmt块，返回时r位于data块的第一个字节
+//
+// 支持PCM、IEEE浮点和WAVE_FORMAT_EXTENSIBLE（子格式为PCM或浮点）。
+// dataSize为data块声明的字节数；大小为0xFFFFFFFF，或RIFF和data的大小都为0（写入尚未结束的流）时返回wavUnknownSize
+func readWAVHeader(r io.Reader) (format WAVFormat, dataSize int64, err error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return WAVFormat{}, 0, fmt.Errorf("读取WAV文件头失败: %v", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return WAVFormat{}, 0, errors.New("不是RIFF/WAVE文件")
+	}
+	riffSize := binary.LittleEndian.Uint32(riff[4:8])
+
+	haveFormat := false
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return WAVFormat{}, 0, errors.New("WAV文件缺少data块")
+			}
+			return WAVFormat{}, 0, fmt.Errorf("读取WAV块失败: %v", err)
+		}
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+
+		switch id {
+		case "fmt ":
+			body := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return WAVFormat{}, 0, fmt.Errorf("读取fmt块失败: %v", err)
+			}
+			if format, err = parseWAVFormat(body[:size]); err != nil {
+				return WAVFormat{}, 0, err
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return WAVFormat{}, 0, errors.New("WAV文件的data块位于fmt块之前")
+			}
+			if size == 0xFFFFFFFF || (size == 0 && (riffSize == 0 || riffSize == 0xFFFFFFFF)) {
+				size = wavUnknownSize
+			}
+			return format, size, nil
+		default:
+			// 跳过LIST、fact等其他块（块大小为奇数时有一个填充字节）
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return WAVFormat{}, 0, fmt.Errorf("跳过WAV块%q失败: %v", id, err)
+			}
+		}
+	}
+}
+
+// parseWAVFormat 解析fmt块
+func parseWAVFormat(body []byte) (WAVFormat, error) {
+	if len(body) < 16 {
+		return WAVFormat{}, fmt.Errorf("fmt块长度%d不足16字节", len(body))
+	}
+	tag := binary.LittleEndian.Uint16(body[0:2])
+	format := WAVFormat{
+		Channels:   int(binary.LittleEndian.Uint16(body[2:4])),
+		SampleRate: int(binary.LittleEndian.Uint32(body[4:8])),
+		BitDepth:   int(binary.LittleEndian.Uint16(body[14:16])),
+	}
+
+	if tag == wavFormatExtensible {
+		// cbSize(2) validBits(2) channelMask(4) subFormat GUID(16)，GUID前两个字节为实际的格式标签
+		if len(body) < 40 {
+			return WAVFormat{}, errors.New("WAVE_FORMAT_EXTENSIBLE的fmt块不完整")
+		}
+		tag = binary.LittleEndian.Uint16(body[24:26])
+	}
+
+	switch tag {
+	case wavFormatPCM:
+	case wavFormatFloat:
+		format.Float = true
+	default:
+		return WAVFormat{}, fmt.Errorf("不支持的WAV格式标签: 0x%04X", tag)
+	}
+
+	if blockAlign := int(binary.LittleEndian.Uint16(body[12:14])); blockAlign != format.blockAlign() {
+		return WAVFormat{}, fmt.Errorf("WAV块对齐%d与%d声道%d位不符", blockAlign, format.Channels, format.BitDepth)
+	}
+	return format, format.Validate()
+}
+
+// writeWAVHeader 写入RIFF头、fmt块（浮点格式另有fact块）和data块头，dataSize为样本数据的字节数
+//
+// dataSize为wavUnknownSize时RIFF和data块的大小写为0xFFFFFFFF。
+// 超过两个声道时使用WAVE_FORMAT_EXTENSIBLE并按声道数设置默认的声道掩码
+func writeWAVHeader(w io.Writer, format WAVFormat, dataSize int64) error {
+	if err := format.Validate(); err != nil {
+		return err
+	}
+	if dataSize+wavHeaderSize(format) > math.MaxUint32 {
+		return fmt.Errorf("WAV数据%d字节超过4GB限制", dataSize)
+	}
+	riffSize, dataField := uint32(wavHeaderSize(format)-8+dataSize), uint32(dataSize)
+	if dataSize == wavUnknownSize {
+		riffSize, dataField, dataSize = 0xFFFFFFFF, 0xFFFFFFFF, 0
+	}
+
+	var fmtBody bytes.Buffer
+	tag := uint16(wavFormatPCM)
+	if format.Float {
+		tag = wavFormatFloat
+	}
+	extensible := format.Channels > 2
+	headerTag := tag
+	if extensible {
+		headerTag = wavFormatExtensible
+	}
+	le := binary.LittleEndian
+	_ = binary.Write(&fmtBody, le, headerTag)
+	_ = binary.Write(&fmtBody, le, uint16(format.Channels))
+	_ = binary.Write(&fmtBody, le, uint32(format.SampleRate))
+	_ = binary.Write(&fmtBody, le, uint32(format.SampleRate*format.blockAlign()))
+	_ = binary.Write(&fmtBody, le, uint16(format.blockAlign()))
+	_ = binary.Write(&fmtBody, le, uint16(format.BitDepth))
+	if extensible {
+		_ = binary.Write(&fmtBody, le, uint16(22))
+		_ = binary.Write(&fmtBody, le, uint16(format.BitDepth))
+		_ = binary.Write(&fmtBody, le, uint32(1)<<uint(format.Channels)-1)
+		_ = binary.Write(&fmtBody, le, tag)
+		// KSDATAFORMAT_SUBTYPE GUID的其余部分 {xxxx0000-0000-0010-8000-00AA00389B71}
+		fmtBody.Write([]byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71})
+	} else if format.Float {
+		_ = binary.Write(&fmtBody, le, uint16(0))
+	}
+
+	var header bytes.Buffer
+	header.WriteString("RIFF")
+	_ = binary.Write(&header, le, riffSize)
+	header.WriteString("WAVEfmt ")
+	_ = binary.Write(&header, le, uint32(fmtBody.Len()))
+	header.Write(fmtBody.Bytes())
+	if format.Float {
+		// 非PCM格式需要fact块记录每声道的样本数
+		header.WriteString("fact")
+		_ = binary.Write(&header, le, uint32(4))
+		_ = binary.Write(&header, le, uint32(dataSize/int64(format.blockAlign())))
+	}
+	header.WriteString("data")
+	_ = binary.Write(&header, le, dataField)
+
+	_, err := w.Write(header.Bytes())
+	return err
+}
+
+// wavHeaderSize writeWAVHeader写入的字节数
+func wavHeaderSize(format WAVFormat) int64 {
+	size := int64(12 + 8 + 16 + 8) // RIFF头、fmt块（PCM）、data块头
+	switch {
+	case format.Channels > 2:
+		size += 24
+	case format.Float:
+		size += 2
+	}
+	if format.Float {
+		size += 12
+	}
+	return size
+}
+
+// decodeWAVSamples 将src中完整的样本解码到dst（范围-1.0到1.0），返回解码的样本数
+func decodeWAVSamples(dst []float32, src []byte, format WAVFormat) int {
+	width := format.bytesPerSample()
+	n := len(src) / width
+	if n > len(dst) {
+		n = len(dst)
+	}
+	le := binary.LittleEndian
+	for i := 0; i < n; i++ {
+		b := src[i*width:]
+		switch {
+		case format.Float && width == 4:
+			dst[i] = math.Float32frombits(le.Uint32(b))
+		case format.Float:
+			dst[i] = float32(math.Float64frombits(le.Uint64(b)))
+		case width == 1:
+			// 8位PCM为无符号数，128为零点
+			dst[i] = float32(int(b[0])-128) / 128
+		case width == 2:
+			dst[i] = float32(int16(le.Uint16(b))) / 32768
+		case width == 3:
+			dst[i] = float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / 8388608
+		default:
+			dst[i] = float32(float64(int32(le.Uint32(b))) / 2147483648)
+		}
+	}
+	return n
+}
+
+// encodeWAVSamples 将src编码到dst，dst至少需要len(src)*bytesPerSample字节
+//
+// 整数格式限幅到[-1.0, 1.0]并四舍五入，与decodeWAVSamples互为逆运算
+func encodeWAVSamples(dst []byte, src []float32, format WAVFormat) {
+	width := format.bytesPerSample()
+	le := binary.LittleEndian
+	for i, sample := range src {
+		b := dst[i*width:]
+		switch {
+		case format.Float && width == 4:
+			le.PutUint32(b, math.Float32bits(sample))
+		case format.Float:
+			le.PutUint64(b, math.Float64bits(float64(sample)))
+		case width == 1:
+			b[0] = byte(quantize(sample, 8) + 128)
+		case width == 2:
+			le.PutUint16(b, uint16(quantize(sample, 16)))
+		case width == 3:
+			v := quantize(sample, 24)
+			b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+		default:
+			le.PutUint32(b, uint32(quantize(sample, 32)))
+		}
+	}
+}
+
+// quantize 将样本四舍五入为bits位有符号整数，超出范围的值被限幅
+func quantize(sample float32, bits uint) int64 {
+	scale := float64(int64(1) << (bits - 1))
+	v := math.Round(float64(sample) * scale)
+	if math.IsNaN(v) {
+		return 0
+	}
+	if v > scale-1 {
+		return int64(scale) - 1
+	}
+	if v < -scale {
+		return -int64(scale)
+	}
+	return int64(v)
+}
@@ -0,0 +1,265 @@
+package rnnoise
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// WAVReader 按块读取WAV文件的样本，内存占用与文件大小无关
+//
+// 示例:
+//
+//	reader, err := OpenWAV("long.wav")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer reader.Close()
+//
+//	buf := make([]float32, 4096*reader.Format().Channels)
+//	for {
+//	    n, err := reader.ReadSamples(buf)
+//	    // 处理 buf[:n]
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	}
+type WAVReader struct {
+	r         io.Reader
+	closer    io.Closer
+	format    WAVFormat
+	remaining int64 // data块剩余的字节数，wavUnknownSize表示读到文件末尾
+	raw       []byte
+}
+
+// OpenWAV 打开WAV文件并读取文件头
+func OpenWAV(filename string) (*WAVReader, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开文件 %s: %v", filename, err)
+	}
+	reader, err := NewWAVReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("无效的WAV文件 %s: %v", filename, err)
+	}
+	reader.closer = file
+	return reader, nil
+}
+
+// NewWAVReader 从r读取WAV文件头，之后可以按块读取样本
+//
+// r不需要支持Seek，可以是管道或网络连接
+func NewWAVReader(r io.Reader) (*WAVReader, error) {
+	br := bufio.NewReader(r)
+	format, dataSize, err := readWAVHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	return &WAVReader{r: br, format: format, remaining: dataSize}, nil
+}
+
+// Format 文件的样本格式
+func (wr *WAVReader) Format() WAVFormat {
+	return wr.format
+}
+
+// DataSize data块声明的字节数，未知时返回-1
+func (wr *WAVReader) DataSize() int64 {
+	return wr.remaining
+}
+
+// ReadSamples 读取交织的样本到dst（范围-1.0到1.0），每次只读取完整的采样帧
+//
+// 返回读取的样本数（声道数的整数倍）；没有更多样本时返回io.EOF，
+// 文件末尾不完整的采样帧会被丢弃
+func (wr *WAVReader) ReadSamples(dst []float32) (int, error) {
+	blockAlign := wr.format.blockAlign()
+	size := len(dst) / wr.format.Channels * blockAlign
+	if size == 0 {
+		return 0, fmt.Errorf("缓冲区至少需要容纳一个采样帧（%d个样本）", wr.format.Channels)
+	}
+	if wr.remaining != wavUnknownSize && int64(size) > wr.remaining {
+		size = int(wr.remaining) / blockAlign * blockAlign
+	}
+	if size == 0 {
+		return 0, io.EOF
+	}
+
+	if cap(wr.raw) < size {
+		wr.raw = make([]byte, size)
+	}
+	raw := wr.raw[:size]
+	n, err := io.ReadFull(wr.r, raw)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, fmt.Errorf("读取音频数据失败: %v", err)
+	}
+	if wr.remaining != wavUnknownSize {
+		wr.remaining -= int64(n)
+	}
+	if err != nil {
+		// 文件比头中声明的短，或大小未知时到达文件末尾
+		wr.remaining = 0
+	}
+
+	n = n / blockAlign * blockAlign
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return decodeWAVSamples(dst, raw[:n], wr.format), nil
+}
+
+// Close 关闭由OpenWAV打开的文件
+func (wr *WAVReader) Close() error {
+	if wr.closer == nil {
+		return nil
+	}
+	err := wr.closer.Close()
+	wr.closer = nil
+	return err
+}
+
+// WAVWriter 按块写入WAV文件，关闭时回填RIFF头中的大小
+//
+// 目标支持Seek时（例如文件）Close会回填RIFF、data和fact块的大小；
+// 不支持Seek时（例如管道）头中的大小写为0xFFFFFFFF，表示读到流末尾
+type WAVWriter struct {
+	w        *bufio.Writer
+	seeker   io.WriteSeeker // 目标支持Seek时用于回填文件头，否则为nil
+	start    int64          // 文件头在seeker中的起始偏移
+	closer   io.Closer
+	format   WAVFormat
+	dataSize int64
+	raw      []byte
+	closed   bool
+}
+
+// CreateWAV 创建WAV文件
+func CreateWAV(filename string, format WAVFormat) (*WAVWriter, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("无法创建文件 %s: %v", filename, err)
+	}
+	writer, err := NewWAVWriter(file, format)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	writer.closer = file
+	return writer, nil
+}
+
+// NewWAVWriter 向w写入WAV文件头，之后可以按块写入样本
+//
+// w支持Seek时，文件头从w的当前位置开始写入，Close时在同一位置回填大小，
+// 因此可以追加到已有内容之后。Close不会关闭w
+func NewWAVWriter(w io.Writer, format WAVFormat) (*WAVWriter, error) {
+	writer := &WAVWriter{w: bufio.NewWriter(w), format: format}
+
+	// 管道等*os.File也实现了io.WriteSeeker，但Seek会失败
+	size := int64(wavUnknownSize)
+	if seeker, ok := w.(io.WriteSeeker); ok {
+		if start, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			writer.seeker = seeker
+			writer.start = start
+			size = 0
+		}
+	}
+	if err := writeWAVHeader(writer.w, format, size); err != nil {
+		return nil, fmt.Errorf("写入WAV文件头失败: %v", err)
+	}
+	return writer, nil
+}
+
+// Format 写入的样本格式
+func (ww *WAVWriter) Format() WAVFormat {
+	return ww.format
+}
+
+// WriteSamples 写入交织的样本（范围-1.0到1.0），样本数必须是声道数的整数倍
+func (ww *WAVWriter) WriteSamples(samples []float32) error {
+	if ww.closed {
+		return errors.New("WAVWriter已关闭")
+	}
+	if len(samples)%ww.format.Channels != 0 {
+		return fmt.Errorf("样本数%d不是声道数%d的整数倍", len(samples), ww.format.Channels)
+	}
+
+	size := len(samples) * ww.format.bytesPerSample()
+	if ww.dataSize+int64(size)+wavHeaderSize(ww.format) > 0xFFFFFFFF-1 {
+		return errors.New("WAV数据超过4GB限制")
+	}
+	if cap(ww.raw) < size {
+		ww.raw = make([]byte, size)
+	}
+	raw := ww.raw[:size]
+	encodeWAVSamples(raw, samples, ww.format)
+	if _, err := ww.w.Write(raw); err != nil {
+		return fmt.Errorf("写入音频数据失败: %v", err)
+	}
+	ww.dataSize += int64(size)
+	return nil
+}
+
+// Close 写入剩余数据并回填文件头中的大小，由CreateWAV创建时同时关闭文件
+func (ww *WAVWriter) Close() error {
+	if ww.closed {
+		return nil
+	}
+	ww.closed = true
+
+	err := ww.finish()
+	if ww.closer != nil {
+		if closeErr := ww.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (ww *WAVWriter) finish() error {
+	// RIFF块的数据长度为奇数时需要一个填充字节
+	if ww.dataSize%2 == 1 {
+		if err := ww.w.WriteByte(0); err != nil {
+			return fmt.Errorf("写入音频数据失败: %v", err)
+		}
+	}
+	if err := ww.w.Flush(); err != nil {
+		return fmt.Errorf("写入音频数据失败: %v", err)
+	}
+
+	seeker := ww.seeker
+	if seeker == nil {
+		return nil
+	}
+	headerSize := wavHeaderSize(ww.format)
+	patch := func(offset int64, value uint32) error {
+		if _, err := seeker.Seek(ww.start+offset, io.SeekStart); err != nil {
+			return err
+		}
+		return binary.Write(seeker, binary.LittleEndian, value)
+	}
+
+	riffSize := headerSize - 8 + ww.dataSize + ww.dataSize%2
+	if err := patch(4, uint32(riffSize)); err != nil {
+		return fmt.Errorf("回填WAV文件头失败: %v", err)
+	}
+	if ww.format.Float {
+		// fact块位于data块头之前
+		if err := patch(headerSize-12, uint32(ww.dataSize/int64(ww.format.blockAlign()))); err != nil {
+			return fmt.Errorf("回填WAV文件头失败: %v", err)
+		}
+	}
+	if err := patch(headerSize-4, uint32(ww.dataSize)); err != nil {
+		return fmt.Errorf("回填WAV文件头失败: %v", err)
+	}
+	// 回到写入内容的末尾，w之后的内容（例如容器中的下一段）不受影响
+	_, err := seeker.Seek(ww.start+riffSize+8, io.SeekStart)
+	return err
+}
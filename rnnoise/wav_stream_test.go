@@ -0,0 +1,365 @@
+package rnnoise
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVWriterUnseekableStream(t *testing.T) {
+	format := WAVFormat{SampleRate: 16000, Channels: 2, BitDepth: 16}
+	var buf bytes.Buffer
+	writer, err := NewWAVWriter(&buf, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := sineSamples(5000*2, 16000, 300)
+	for start := 0; start < len(in); start += 998 {
+		end := start + 998
+		if end > len(in) {
+			end = len(in)
+		}
+		if err := writer.WriteSamples(in[start:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.WriteSamples(in[:3]); err == nil {
+		t.Error("expected error for a partial sample frame")
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 无法回填时大小写为0xFFFFFFFF，读取到流末尾
+	raw := buf.Bytes()
+	if size := binary.LittleEndian.Uint32(raw[40:44]); size != 0xFFFFFFFF {
+		t.Fatalf("data size = %#x, want 0xFFFFFFFF", size)
+	}
+	reader, err := NewWAVReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.DataSize() != -1 || reader.Format() != format {
+		t.Fatalf("DataSize() = %d, Format() = %+v", reader.DataSize(), reader.Format())
+	}
+
+	var out []float32
+	block := make([]float32, 777) // 不是声道数的整数倍，每次只读取完整的采样帧
+	for {
+		n, err := reader.ReadSamples(block)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n%2 != 0 {
+			t.Fatalf("read %d samples, want a multiple of 2", n)
+		}
+		out = append(out, block[:n]...)
+	}
+	if len(out) != len(in) {
+		t.Fatalf("read %d samples, want %d", len(out), len(in))
+	}
+	for i := range in {
+		if math.Abs(float64(out[i]-in[i])) > 1.0/32768 {
+			t.Fatalf("sample %d = %f, want %f", i, out[i], in[i])
+		}
+	}
+}
+
+func TestWAVWriterPatchesHeader(t *testing.T) {
+	cases := []struct {
+		format  WAVFormat
+		samples int
+	}{
+		{WAVFormat{SampleRate: 8000, Channels: 1, BitDepth: 8}, 101}, // 奇数字节需要填充
+		{WAVFormat{SampleRate: 48000, Channels: 2, BitDepth: 32, Float: true}, 200},
+		{WAVFormat{SampleRate: 44100, Channels: 6, BitDepth: 24}, 60},
+	}
+	for _, tc := range cases {
+		path := filepath.Join(t.TempDir(), "out.wav")
+		writer, err := CreateWAV(path, tc.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples := make([]float32, tc.samples)
+		if err := writer.WriteSamples(samples[:tc.samples/2]); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.WriteSamples(samples[tc.samples/2:]); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.WriteSamples(samples); err == nil {
+			t.Errorf("%+v: expected error after Close", tc.format)
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dataSize := tc.samples * tc.format.bytesPerSample()
+		headerSize := int(wavHeaderSize(tc.format))
+		if len(raw) != headerSize+dataSize+dataSize%2 {
+			t.Fatalf("%+v: file size %d, want %d", tc.format, len(raw), headerSize+dataSize+dataSize%2)
+		}
+		le := binary.LittleEndian
+		if got := int(le.Uint32(raw[4:8])); got != len(raw)-8 {
+			t.Errorf("%+v: RIFF size %d, want %d", tc.format, got, len(raw)-8)
+		}
+		if got := int(le.Uint32(raw[headerSize-4:])); got != dataSize {
+			t.Errorf("%+v: data size %d, want %d", tc.format, got, dataSize)
+		}
+		if tc.format.Float {
+			if got := int(le.Uint32(raw[headerSize-12:])); got != tc.samples/tc.format.Channels {
+				t.Errorf("%+v: fact sample count %d, want %d", tc.format, got, tc.samples/tc.format.Channels)
+			}
+		}
+
+		reader, err := OpenWAV(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reader.Format() != tc.format || reader.DataSize() != int64(dataSize) {
+			t.Errorf("%+v: read back %+v with %d bytes", tc.format, reader.Format(), reader.DataSize())
+		}
+		reader.Close()
+	}
+}
+
+func TestWAVWriterAfterPrefix(t *testing.T) {
+	format := WAVFormat{SampleRate: 16000, Channels: 1, BitDepth: 16}
+	prefix := []byte("container header")
+
+	path := filepath.Join(t.TempDir(), "out.bin")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.Write(prefix); err != nil {
+		t.Fatal(err)
+	}
+
+	writer, err := NewWAVWriter(file, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteSamples(make([]float32, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte("tail")); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(raw, prefix) || !bytes.HasSuffix(raw, []byte("tail")) {
+		t.Fatal("prefix or trailing data was overwritten")
+	}
+	wav := raw[len(prefix) : len(raw)-len("tail")]
+	headerSize := int(wavHeaderSize(format))
+	le := binary.LittleEndian
+	if got := int(le.Uint32(wav[4:8])); got != len(wav)-8 {
+		t.Errorf("RIFF size %d, want %d", got, len(wav)-8)
+	}
+	if got := int(le.Uint32(wav[headerSize-4:])); got != 200 {
+		t.Errorf("data size %d, want 200", got)
+	}
+
+	reader, err := NewWAVReader(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Format() != format || reader.DataSize() != 200 {
+		t.Errorf("read back %+v with %d bytes", reader.Format(), reader.DataSize())
+	}
+}
+
+func TestWAVReaderTruncatedData(t *testing.T) {
+	// 头中声明4个样本，实际只有3个完整样本和半个样本
+	file := wavFile(pcmFmt(1, 1, 8000, 16), []byte{0, 0x40, 0, 0xC0, 0, 0x20, 0, 0})
+	file = file[:len(file)-1]
+	reader, err := NewWAVReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]float32, 16)
+	n, err := reader.ReadSamples(buf)
+	if err != nil || n != 3 {
+		t.Fatalf("ReadSamples() = %d, %v, want 3 samples", n, err)
+	}
+	if _, err := reader.ReadSamples(buf); err != io.EOF {
+		t.Fatalf("ReadSamples() error = %v, want io.EOF", err)
+	}
+}
+
+// writeTestWAV 将audioData写入临时目录中的WAV文件
+func writeTestWAV(t *testing.T, audioData *AudioData) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "in.wav")
+	if err := NewAudioProcessor(nil).WriteWAV(path, audioData); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFilterAudioFileStreamsInBlocks(t *testing.T) {
+	// 超过fileBlockFrames的24位立体声文件，默认混合为单声道
+	frames := fileBlockFrames*2 + 1234
+	audioData := &AudioData{SampleRate: 48000, Channels: 2, BitDepth: 24}
+	for _, sample := range sineSamples(frames, 48000, 440) {
+		audioData.Samples = append(audioData.Samples, sample, sample/2)
+	}
+	input := writeTestWAV(t, audioData)
+
+	var kept, total int
+	filter, err := NewNoiseFilterWithDenoiser(NewFakeDenoiser(0.9, 0.1),
+		WithOutputPolicy(OutputSilence),
+		WithCrossfade(0),
+		WithMetrics(Metrics{OnAudio: func(m AudioMetrics) { kept, total = m.KeptFrames, m.Frames }}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filter.Destroy()
+
+	output := filepath.Join(t.TempDir(), "out.wav")
+	result, err := filter.FilterAudioFile(input, output, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFrames := (frames + 479) / 480
+	if result.ProcessedFrames != wantFrames || len(result.VoiceProbabilities) != wantFrames || total != wantFrames || kept != (wantFrames+1)/2 {
+		t.Errorf("processed %d frames (%d probs, metrics %d/%d), want %d", result.ProcessedFrames, len(result.VoiceProbabilities), kept, total, wantFrames)
+	}
+	if result.DenoisedAudio.Samples != nil || result.DenoisedAudio.BitDepth != 24 || result.DenoisedAudio.Channels != 1 {
+		t.Errorf("unexpected DenoisedAudio %+v", result.DenoisedAudio)
+	}
+	if want := audioData.Duration(); result.InputDuration != want {
+		t.Errorf("InputDuration = %v, want %v", result.InputDuration, want)
+	}
+
+	out, err := NewAudioProcessor(nil).ReadWAV(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.BitDepth != 24 || out.Channels != 1 || len(out.Samples) != frames {
+		t.Fatalf("output %d bit %d channels %d samples, want 24 bit mono %d samples", out.BitDepth, out.Channels, len(out.Samples), frames)
+	}
+	for i, sample := range out.Samples {
+		want := (audioData.Samples[i*2] + audioData.Samples[i*2+1]) / 2
+		if (i/480)%2 == 1 {
+			want = 0
+		}
+		if math.Abs(float64(sample-want)) > 1.0/8388608 {
+			t.Fatalf("sample %d = %f, want %f", i, sample, want)
+		}
+	}
+}
+
+func TestFilterAudioFileMatchesFilterAudio(t *testing.T) {
+	audioData := &AudioData{SampleRate: 48000, Channels: 2, BitDepth: 16}
+	for _, sample := range sineSamples(480*40, 48000, 300) {
+		audioData.Samples = append(audioData.Samples, sample, -sample/3)
+	}
+	input := writeTestWAV(t, audioData)
+	in, err := NewAudioProcessor(nil).ReadWAV(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newFilter := func() *NoiseFilter {
+		var created []*FakeDenoiser
+		filter, err := NewNoiseFilter(
+			WithDenoiserFactory(fakeFactory(&created, 0.9, 0.2)),
+			WithChannelMode(ChannelIndependent),
+			WithOutputPolicy(OutputAttenuate),
+			WithThreshold(0.5),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return filter
+	}
+
+	memFilter := newFilter()
+	defer memFilter.Destroy()
+	want, err := memFilter.FilterAudio(in, DefaultThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileFilter := newFilter()
+	defer fileFilter.Destroy()
+	output := filepath.Join(t.TempDir(), "out.wav")
+	result, err := fileFilter.FilterAudioFile(input, output, DefaultThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.ChannelVoiceProbabilities) != 2 || result.ChannelVoiceProbabilities[1][0] != 0.2 {
+		t.Errorf("unexpected channel voice probabilities %v", result.ChannelVoiceProbabilities)
+	}
+
+	got, err := NewAudioProcessor(nil).ReadWAV(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Channels != 2 || len(got.Samples) != len(want.DenoisedAudio.Samples) {
+		t.Fatalf("output %d channels %d samples, want 2 channels %d samples", got.Channels, len(got.Samples), len(want.DenoisedAudio.Samples))
+	}
+	for i, sample := range want.DenoisedAudio.Samples {
+		if math.Abs(float64(got.Samples[i]-sample)) > 1.0/32768 {
+			t.Fatalf("sample %d = %f, want %f", i, got.Samples[i], sample)
+		}
+	}
+}
+
+func TestFilterAudioFileOutputDropLength(t *testing.T) {
+	// OutputDrop保留所有帧时输出与输入等长，丢弃帧时只减去被丢弃的部分
+	for _, rate := range []int{22050, 44100} {
+		for _, probs := range [][]float32{{1}, {0.9, 0.1}} {
+			n := rate/3 + 17
+			input := writeTestWAV(t, &AudioData{Samples: sineSamples(n, rate, 300), SampleRate: rate, Channels: 1, BitDepth: 16})
+			filter, err := NewNoiseFilterWithDenoiser(NewFakeDenoiser(probs...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := filter.FilterAudio(&AudioData{Samples: sineSamples(n, rate, 300), SampleRate: rate, Channels: 1, BitDepth: 16}, 0.5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := filter.Reset(); err != nil {
+				t.Fatal(err)
+			}
+			output := filepath.Join(t.TempDir(), "out.wav")
+			if _, err := filter.FilterAudioFile(input, output, 0.5); err != nil {
+				t.Fatal(err)
+			}
+			filter.Destroy()
+
+			got, err := NewAudioProcessor(nil).ReadWAV(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(probs) == 1 && len(got.Samples) != n {
+				t.Errorf("%d Hz all frames kept: output %d samples, want %d", rate, len(got.Samples), n)
+			}
+			if len(got.Samples) != len(want.DenoisedAudio.Samples) {
+				t.Errorf("%d Hz probs %v: file output %d samples, FilterAudio %d", rate, probs, len(got.Samples), len(want.DenoisedAudio.Samples))
+			}
+		}
+	}
+}
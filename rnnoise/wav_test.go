@@ -0,0 +1,196 @@
+package rnnoise
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVRoundTrip(t *testing.T) {
+	cases := []struct {
+		name     string
+		bitDepth int
+		float    bool
+		channels int
+	}{
+		{"8-bit", 8, false, 1},
+		{"16-bit", 16, false, 2},
+		{"24-bit", 24, false, 2},
+		{"32-bit", 32, false, 1},
+		{"float32", 32, true, 2},
+		{"float64", 64, true, 1},
+		{"24-bit 6ch extensible", 24, false, 6},
+		{"float32 4ch extensible", 32, true, 4},
+	}
+
+	ap := NewAudioProcessor(nil)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// 整数格式使用可以精确表示的值k/2^(bitDepth-1)，往返后应逐位一致
+			span := 1 << 20
+			if !tc.float && tc.bitDepth < 21 {
+				span = 1 << uint(tc.bitDepth-1)
+			}
+			in := &AudioData{SampleRate: 22050, Channels: tc.channels, BitDepth: tc.bitDepth, Float: tc.float}
+			for i := 0; i < 301*tc.channels; i++ {
+				k := (i*7919)%(2*span) - span
+				in.Samples = append(in.Samples, float32(k)/float32(span))
+			}
+			in.Samples[0] = -1
+
+			path := filepath.Join(t.TempDir(), "roundtrip.wav")
+			if err := ap.WriteWAV(path, in); err != nil {
+				t.Fatal(err)
+			}
+			out, err := ap.ReadWAV(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out.SampleRate != in.SampleRate || out.Channels != in.Channels || out.BitDepth != in.BitDepth || out.Float != in.Float {
+				t.Fatalf("format = %d Hz %d ch %d bit float=%v, want %d Hz %d ch %d bit float=%v",
+					out.SampleRate, out.Channels, out.BitDepth, out.Float, in.SampleRate, in.Channels, in.BitDepth, in.Float)
+			}
+			if len(out.Samples) != len(in.Samples) {
+				t.Fatalf("read %d samples, want %d", len(out.Samples), len(in.Samples))
+			}
+			for i := range in.Samples {
+				if out.Samples[i] != in.Samples[i] {
+					t.Fatalf("sample %d = %g, want %g", i, out.Samples[i], in.Samples[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteWAVClipsAndRounds(t *testing.T) {
+	ap := NewAudioProcessor(nil)
+	path := filepath.Join(t.TempDir(), "clip.wav")
+	in := &AudioData{Samples: []float32{1.5, 1, -1, -2, 0.5 / 32768}, SampleRate: 8000, Channels: 1, BitDepth: 16}
+	if err := ap.WriteWAV(path, in); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := raw[len(raw)-10:]
+	want := []int16{32767, 32767, -32768, -32768, 1}
+	for i, w := range want {
+		if got := int16(binary.LittleEndian.Uint16(data[i*2:])); got != w {
+			t.Errorf("sample %d = %d, want %d", i, got, w)
+		}
+	}
+}
+
+// wavFile 构造WAV文件，extra中的块插入在fmt块和data块之间
+func wavFile(fmtBody []byte, data []byte, extra ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(0)) // 读取时不检查RIFF大小
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(fmtBody)))
+	buf.Write(fmtBody)
+	for _, chunk := range extra {
+		buf.Write(chunk)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func pcmFmt(tag uint16, channels, rate, bits int) []byte {
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	binary.Write(&buf, le, tag)
+	binary.Write(&buf, le, uint16(channels))
+	binary.Write(&buf, le, uint32(rate))
+	binary.Write(&buf, le, uint32(rate*channels*bits/8))
+	binary.Write(&buf, le, uint16(channels*bits/8))
+	binary.Write(&buf, le, uint16(bits))
+	return buf.Bytes()
+}
+
+func TestReadWAVFormats(t *testing.T) {
+	// WAVE_FORMAT_EXTENSIBLE：24位容器中的20位有效数据，子格式为PCM
+	extensible := append(pcmFmt(0xFFFE, 1, 48000, 24), 22, 0, 20, 0, 4, 0, 0, 0, 1, 0, 0, 0,
+		0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71)
+	// 奇数长度的LIST块后有一个填充字节
+	list := []byte{'L', 'I', 'S', 'T', 3, 0, 0, 0, 'a', 'b', 'c', 0}
+
+	cases := []struct {
+		name string
+		file []byte
+		want []float32
+	}{
+		{"8-bit unsigned", wavFile(pcmFmt(1, 1, 8000, 8), []byte{0x80, 0xFF, 0x00, 0xC0}), []float32{0, 127.0 / 128, -1, 0.5}},
+		{"24-bit full scale", wavFile(pcmFmt(1, 1, 8000, 24), []byte{0xFF, 0xFF, 0x7F, 0x00, 0x00, 0x80, 0x00, 0x00, 0x40}),
+			[]float32{8388607.0 / 8388608, -1, 0.5}},
+		{"32-bit", wavFile(pcmFmt(1, 1, 8000, 32), []byte{0x00, 0x00, 0x00, 0xC0}), []float32{-0.5}},
+		{"extensible", wavFile(extensible, []byte{0x00, 0x00, 0xC0}, list), []float32{-0.5}},
+	}
+
+	ap := NewAudioProcessor(nil)
+	for _, tc := range cases {
+		path := filepath.Join(t.TempDir(), "in.wav")
+		if err := os.WriteFile(path, tc.file, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		out, err := ap.ReadWAV(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(out.Samples) != len(tc.want) {
+			t.Fatalf("%s: read %v, want %v", tc.name, out.Samples, tc.want)
+		}
+		for i := range tc.want {
+			if out.Samples[i] != tc.want[i] {
+				t.Errorf("%s: sample %d = %g, want %g", tc.name, i, out.Samples[i], tc.want[i])
+			}
+		}
+	}
+}
+
+func TestReadWAVOversizedDataChunk(t *testing.T) {
+	// data块声明接近4GB，实际只有两个样本
+	file := wavFile(pcmFmt(1, 1, 8000, 16), []byte{0x00, 0x40, 0x00, 0xC0})
+	binary.LittleEndian.PutUint32(file[len(file)-8:], 0xFFFFFFF0)
+	path := filepath.Join(t.TempDir(), "in.wav")
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := NewAudioProcessor(nil).ReadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Samples) != 2 || out.Samples[0] != 0.5 || out.Samples[1] != -0.5 {
+		t.Errorf("read %v, want [0.5 -0.5]", out.Samples)
+	}
+	if cap(out.Samples) > len(file)/2 {
+		t.Errorf("preallocated %d samples for a %d-byte file", cap(out.Samples), len(file))
+	}
+}
+
+func TestReadWAVRejectsUnsupported(t *testing.T) {
+	ap := NewAudioProcessor(nil)
+	for name, file := range map[string][]byte{
+		"a-law":      wavFile(pcmFmt(6, 1, 8000, 8), []byte{0}),
+		"12-bit":     wavFile(pcmFmt(1, 1, 8000, 12), nil),
+		"not riff":   []byte("not a wav file at all"),
+		"no data":    wavFile(pcmFmt(1, 1, 8000, 16), nil)[:36],
+		"float16bit": wavFile(pcmFmt(3, 1, 8000, 16), []byte{0, 0}),
+	} {
+		path := filepath.Join(t.TempDir(), "in.wav")
+		if err := os.WriteFile(path, file, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ap.ReadWAV(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package rnnoise
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// yamlLine 去掉注释和缩进后的一行
+type yamlLine struct {
+	num    int // 行号（从1开始）
+	indent int
+	text   string
+}
+
+// yamlParser 解析流水线描述所需的YAML子集
+//
+// 支持块映射、块序列（包括"- key: value"形式的紧凑映射）、单行的流式序列和映射、
+// 普通/单引号/双引号标量、#注释以及文档开头的"---"。锚点、别名、标签、多行标量和
+// 多文档不在支持范围内，遇到时返回错误而不是猜测其含义
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// decodeYAML 将YAML文档解码为map[string]interface{}、[]interface{}和标量组成的值
+func decodeYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	if err := p.split(string(data)); err != nil {
+		return nil, err
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+	value, err := p.parseNode(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		return nil, fmt.Errorf("YAML第%d行: 缩进与上文不一致", line.num)
+	}
+	return value, nil
+}
+
+// split 按行拆分，去掉注释、空行和文档标记
+func (p *yamlParser) split(doc string) error {
+	for i, raw := range strings.Split(strings.ReplaceAll(doc, "\r\n", "\n"), "\n") {
+		num := i + 1
+		text := strings.TrimRight(stripYAMLComment(raw), " \t")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return fmt.Errorf("YAML第%d行: 不能用制表符缩进", num)
+		}
+		if trimmed == "---" || trimmed == "..." {
+			if len(p.lines) > 0 {
+				return fmt.Errorf("YAML第%d行: 不支持多文档", num)
+			}
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{num: num, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	return nil
+}
+
+// stripYAMLComment 去掉引号之外、位于行首或空白之后的#注释
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseNode 解析从当前行开始、缩进为indent的节点
+func (p *yamlParser) parseNode(indent int) (interface{}, error) {
+	line := p.lines[p.pos]
+	if line.indent != indent {
+		return nil, fmt.Errorf("YAML第%d行: 缩进与上文不一致", line.num)
+	}
+	if isYAMLSequenceItem(line.text) {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok, err := splitYAMLKey(line); err != nil {
+		return nil, err
+	} else if ok {
+		return p.parseMapping(indent)
+	}
+	p.pos++
+	return parseYAMLValue(line.text, line.num)
+}
+
+// parseSequence 解析缩进为indent的块序列
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && !isYAMLSequenceItem(line.text)) {
+			// 与键对齐的序列到下一个键结束，由上层处理
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("YAML第%d行: 缩进与上文不一致", line.num)
+		}
+
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if rest == "" {
+			// 元素在之后缩进更深的行中
+			p.pos++
+			item, err := p.parseChild(indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+		// "- key: value"或"- - item"：把元素的内容当作缩进更深的一行
+		childIndent := indent + len(line.text) - len(rest)
+		p.lines[p.pos] = yamlLine{num: line.num, indent: childIndent, text: rest}
+		item, err := p.parseNode(childIndent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// parseMapping 解析缩进为indent的块映射
+func (p *yamlParser) parseMapping(indent int) (map[string]interface{}, error) {
+	mapping := make(map[string]interface{})
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("YAML第%d行: 缩进与上文不一致", line.num)
+		}
+		key, rest, ok, err := splitYAMLKey(line)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("YAML第%d行: 应为\"键: 值\"", line.num)
+		}
+		if _, dup := mapping[key]; dup {
+			return nil, fmt.Errorf("YAML第%d行: 重复的键%q", line.num, key)
+		}
+		p.pos++
+
+		var value interface{}
+		if rest == "" {
+			value, err = p.parseChild(indent)
+		} else {
+			value, err = parseYAMLValue(rest, line.num)
+		}
+		if err != nil {
+			return nil, err
+		}
+		mapping[key] = value
+	}
+	return mapping, nil
+}
+
+// parseChild 解析值为空的键或序列元素之后的子节点，没有子节点时为null
+//
+// 子节点缩进比parent更深；映射中的序列也可以与键对齐
+func (p *yamlParser) parseChild(parent int) (interface{}, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > parent || (next.indent == parent && isYAMLSequenceItem(next.text)) {
+		return p.parseNode(next.indent)
+	}
+	return nil, nil
+}
+
+// isYAMLSequenceItem 是否为"- "开头的序列元素
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey 拆分"键: 值"，不是键值对时ok为false
+func splitYAMLKey(line yamlLine) (key, rest string, ok bool, err error) {
+	text := line.text
+	switch text[0] {
+	case '[', '{':
+		return "", "", false, nil
+	case '&', '*', '!', '|', '>', '?':
+		return "", "", false, fmt.Errorf("YAML第%d行: 不支持锚点、别名、标签、多行标量和复杂键", line.num)
+	}
+	end := 0
+	if text[0] == '"' || text[0] == '\'' {
+		if end = closingQuote(text); end < 0 {
+			return "", "", false, fmt.Errorf("YAML第%d行: 引号没有闭合", line.num)
+		}
+		end++
+		if end >= len(text) || text[end] != ':' {
+			return "", "", false, nil
+		}
+		if key, err = parseYAMLQuoted(text[:end], line.num); err != nil {
+			return "", "", false, err
+		}
+	} else {
+		for {
+			i := strings.IndexByte(text[end:], ':')
+			if i < 0 {
+				return "", "", false, nil
+			}
+			end += i
+			if end+1 == len(text) || text[end+1] == ' ' {
+				break
+			}
+			end++
+		}
+		key = strings.TrimRight(text[:end], " ")
+	}
+	rest = strings.TrimLeft(text[end+1:], " ")
+	return key, rest, true, nil
+}
+
+// parseYAMLValue 解析一行中的值：流式序列、流式映射或标量
+func parseYAMLValue(text string, num int) (interface{}, error) {
+	switch text[0] {
+	case '&', '*', '!':
+		return nil, fmt.Errorf("YAML第%d行: 不支持锚点、别名和标签", num)
+	case '|', '>':
+		return nil, fmt.Errorf("YAML第%d行: 不支持多行标量", num)
+	case '[', '{':
+		f := &yamlFlow{text: text, num: num}
+		value, err := f.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if f.skipSpaces(); f.pos < len(f.text) {
+			return nil, fmt.Errorf("YAML第%d行: 流式集合之后有多余的内容", num)
+		}
+		return value, nil
+	case '"', '\'':
+		if end := closingQuote(text); end != len(text)-1 {
+			return nil, fmt.Errorf("YAML第%d行: 引号没有闭合或之后有多余的内容", num)
+		}
+		return parseYAMLQuoted(text, num)
+	}
+	return parseYAMLScalar(text), nil
+}
+
+// yamlFlow 单行流式集合的解析状态
+type yamlFlow struct {
+	text string
+	pos  int
+	num  int
+}
+
+func (f *yamlFlow) skipSpaces() {
+	for f.pos < len(f.text) && f.text[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+// parseValue 解析流式集合中的一个值
+func (f *yamlFlow) parseValue() (interface{}, error) {
+	f.skipSpaces()
+	if f.pos >= len(f.text) {
+		return nil, fmt.Errorf("YAML第%d行: 流式集合没有闭合", f.num)
+	}
+	switch f.text[f.pos] {
+	case '[':
+		f.pos++
+		items := []interface{}{}
+		err := f.parseEntries(']', func() error {
+			item, err := f.parseValue()
+			items = append(items, item)
+			return err
+		})
+		return items, err
+	case '{':
+		f.pos++
+		mapping := make(map[string]interface{})
+		err := f.parseEntries('}', func() error {
+			key, err := f.parseKey()
+			if err != nil {
+				return err
+			}
+			if _, dup := mapping[key]; dup {
+				return fmt.Errorf("YAML第%d行: 重复的键%q", f.num, key)
+			}
+			mapping[key], err = f.parseValue()
+			return err
+		})
+		return mapping, err
+	case '"', '\'':
+		end := closingQuote(f.text[f.pos:])
+		if end < 0 {
+			return nil, fmt.Errorf("YAML第%d行: 引号没有闭合", f.num)
+		}
+		quoted := f.text[f.pos : f.pos+end+1]
+		f.pos += end + 1
+		return parseYAMLQuoted(quoted, f.num)
+	case '&', '*', '!', '|', '>':
+		return nil, fmt.Errorf("YAML第%d行: 不支持锚点、别名、标签和多行标量", f.num)
+	}
+	start := f.pos
+	for f.pos < len(f.text) && !strings.ContainsRune(",]}", rune(f.text[f.pos])) {
+		f.pos++
+	}
+	return parseYAMLScalar(strings.TrimRight(f.text[start:f.pos], " ")), nil
+}
+
+// parseEntries 解析以逗号分隔、以closing结束的元素
+func (f *yamlFlow) parseEntries(closing byte, entry func() error) error {
+	for first := true; ; first = false {
+		f.skipSpaces()
+		if f.pos < len(f.text) && f.text[f.pos] == closing {
+			f.pos++
+			return nil
+		}
+		if !first {
+			if f.pos >= len(f.text) || f.text[f.pos] != ',' {
+				return fmt.Errorf("YAML第%d行: 流式集合中缺少逗号或没有闭合", f.num)
+			}
+			f.pos++
+			f.skipSpaces()
+		}
+		if err := entry(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseKey 解析流式映射中"键:"部分
+func (f *yamlFlow) parseKey() (string, error) {
+	f.skipSpaces()
+	var key string
+	if f.pos < len(f.text) && (f.text[f.pos] == '"' || f.text[f.pos] == '\'') {
+		end := closingQuote(f.text[f.pos:])
+		if end < 0 {
+			return "", fmt.Errorf("YAML第%d行: 引号没有闭合", f.num)
+		}
+		var err error
+		if key, err = parseYAMLQuoted(f.text[f.pos:f.pos+end+1], f.num); err != nil {
+			return "", err
+		}
+		f.pos += end + 1
+		f.skipSpaces()
+	} else {
+		start := f.pos
+		for f.pos < len(f.text) && !strings.ContainsRune(":,]}", rune(f.text[f.pos])) {
+			f.pos++
+		}
+		key = strings.TrimRight(f.text[start:f.pos], " ")
+	}
+	if f.pos >= len(f.text) || f.text[f.pos] != ':' || key == "" {
+		return "", fmt.Errorf("YAML第%d行: 流式映射中应为\"键: 值\"", f.num)
+	}
+	f.pos++
+	return key, nil
+}
+
+// closingQuote 以引号开头的text中闭合引号的下标，没有闭合时为-1
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++ // 单引号中''表示一个单引号
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// parseYAMLQuoted 解析带引号的标量
+func parseYAMLQuoted(text string, num int) (string, error) {
+	if text[0] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	s, err := strconv.Unquote(text)
+	if err != nil {
+		return "", fmt.Errorf("YAML第%d行: 无效的双引号字符串 %s", num, text)
+	}
+	return s, nil
+}
+
+// parseYAMLScalar 按YAML 1.2核心模式解析普通标量：null、布尔值、整数、浮点数，其他为字符串
+func parseYAMLScalar(text string) interface{} {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	}
+	if n, err := strconv.ParseInt(text, 0, 64); err == nil && !strings.ContainsAny(text, "_") {
+		return n
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && !strings.ContainsAny(text, "_xXpP") {
+		return f
+	}
+	return text
+}
@@ -0,0 +1,61 @@
+package rnnoise
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want interface{}
+	}{
+		{"scalars", "a: 1\nb: -2.5\nc: true\nd: ~\ne: text with spaces\nf: 0x10\n",
+			map[string]interface{}{"a": int64(1), "b": -2.5, "c": true, "d": nil, "e": "text with spaces", "f": int64(16)}},
+		{"quoted", `a: 'it''s # not a comment'` + "\n" + `b: "tab\tand \"quote\""` + "\n" + `"c d": '1'`,
+			map[string]interface{}{"a": "it's # not a comment", "b": "tab\tand \"quote\"", "c d": "1"}},
+		{"comments and document marker", "---\n# 注释\na: x # 行尾注释\n\nb: y#z\n",
+			map[string]interface{}{"a": "x", "b": "y#z"}},
+		{"sequence aligned with key", "a:\n- 1\n- 2\nb: 3\n",
+			map[string]interface{}{"a": []interface{}{int64(1), int64(2)}, "b": int64(3)}},
+		{"nested", "a:\n  b:\n    - c: 1\n      d: [x, 'y', {e: 2}]\n    -\n      - 3\n  f:\n",
+			map[string]interface{}{"a": map[string]interface{}{
+				"b": []interface{}{
+					map[string]interface{}{"c": int64(1), "d": []interface{}{"x", "y", map[string]interface{}{"e": int64(2)}}},
+					[]interface{}{int64(3)},
+				},
+				"f": nil,
+			}}},
+		{"flow", "{a: [], b: {}, c: [1, [2]]}",
+			map[string]interface{}{"a": []interface{}{}, "b": map[string]interface{}{}, "c": []interface{}{int64(1), []interface{}{int64(2)}}}},
+		{"url value", "a: http://example.com\n", map[string]interface{}{"a": "http://example.com"}},
+		{"empty", "# 只有注释\n", nil},
+	}
+	for _, tt := range tests {
+		got, err := decodeYAML([]byte(tt.yaml))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, doc := range []string{
+		"a: [1, 2\n",
+		"a: {b 1}\n",
+		"a: 'open\n",
+		"a: *ref\n",
+		"a: !!str 1\n",
+		"a: >\n  folded\n",
+		"a: 1\n---\nb: 2\n",
+		"- a\nb: 1\n",
+		"a: 1\na: 2\n",
+	} {
+		if got, err := decodeYAML([]byte(doc)); err == nil {
+			t.Errorf("decodeYAML(%q) = %#v, want error", doc, got)
+		}
+	}
+}